@@ -0,0 +1,189 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// AdminClient queries the REST API of the server about the cluster and its
+// nodes, for routing and alerting, with the authentication of the connections
+// of a Connector. Some endpoints may require additional privileges, and
+// requests failing with HTTP 401 or 403 return an ErrQueryFailed with that
+// status code.
+type AdminClient struct {
+	conn *Conn
+}
+
+// NewAdminClient returns an AdminClient using the server and authentication
+// of the DSN.
+func NewAdminClient(ctx context.Context, dsn string) (*AdminClient, error) {
+	return (&Connector{dsn: dsn}).AdminClient(ctx)
+}
+
+// AdminClient returns an AdminClient using the server and authentication of
+// the connections of the Connector, including its TokenSource and
+// CredentialProvider.
+func (c *Connector) AdminClient(ctx context.Context) (*AdminClient, error) {
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminClient{conn: conn.(*Conn)}, nil
+}
+
+// Close releases the resources of the client.
+func (a *AdminClient) Close() error {
+	return a.conn.Close()
+}
+
+// NodeVersion is the version of a node.
+type NodeVersion struct {
+	Version string `json:"version"`
+}
+
+// ServerInfo is the information about the node answering requests, returned by /v1/info.
+type ServerInfo struct {
+	NodeVersion NodeVersion `json:"nodeVersion"`
+	Environment string      `json:"environment"`
+	Coordinator bool        `json:"coordinator"`
+	// Starting is true until the node is ready to run queries.
+	Starting bool `json:"starting"`
+	// Uptime is formatted like 3.25h.
+	Uptime string `json:"uptime"`
+}
+
+// NodeStatus is the status of the node answering requests, returned by /v1/status.
+type NodeStatus struct {
+	NodeID          string      `json:"nodeId"`
+	NodeVersion     NodeVersion `json:"nodeVersion"`
+	Environment     string      `json:"environment"`
+	Coordinator     bool        `json:"coordinator"`
+	Uptime          string      `json:"uptime"`
+	ExternalAddress string      `json:"externalAddress"`
+	InternalAddress string      `json:"internalAddress"`
+	Processors      int         `json:"processors"`
+	ProcessCPULoad  float64     `json:"processCpuLoad"`
+	SystemCPULoad   float64     `json:"systemCpuLoad"`
+	HeapUsed        int64       `json:"heapUsed"`
+	HeapAvailable   int64       `json:"heapAvailable"`
+	NonHeapUsed     int64       `json:"nonHeapUsed"`
+}
+
+// NodeStats are the statistics of the requests of the coordinator to a worker
+// node, returned by /v1/node.
+type NodeStats struct {
+	URI                string    `json:"uri"`
+	RecentRequests     float64   `json:"recentRequests"`
+	RecentFailures     float64   `json:"recentFailures"`
+	RecentSuccesses    float64   `json:"recentSuccesses"`
+	LastRequestTime    time.Time `json:"lastRequestTime"`
+	LastResponseTime   time.Time `json:"lastResponseTime"`
+	RecentFailureRatio float64   `json:"recentFailureRatio"`
+	// Age is how long the node is known, formatted like 3.25h.
+	Age string `json:"age"`
+}
+
+// QueryInfo is the state of a query, returned by /v1/query.
+type QueryInfo struct {
+	QueryID string `json:"queryId"`
+	// State is the state of the query, like QUEUED, RUNNING or FINISHED.
+	State string `json:"state"`
+	// ResourceGroupID is the path of the resource group of the query, like
+	// [global adhoc], or empty until it's selected.
+	ResourceGroupID []string       `json:"resourceGroupId"`
+	QueryStats      QueryInfoStats `json:"queryStats"`
+	// QueuePosition is the position of a queued query among the queued queries
+	// of its resource group, from 1, in the order they were created, or 0 if
+	// it's not queued. Resource groups not scheduling queries in this order
+	// may run them in another one.
+	QueuePosition int `json:"-"`
+}
+
+// QueryInfoStats are the statistics of a query in a QueryInfo.
+type QueryInfoStats struct {
+	CreateTime time.Time `json:"createTime"`
+	// QueuedTime and ElapsedTime are formatted like 1.50s.
+	QueuedTime  string `json:"queuedTime"`
+	ElapsedTime string `json:"elapsedTime"`
+}
+
+// Info returns the information about the node answering requests,
+// which is the coordinator, unless using a gateway.
+func (a *AdminClient) Info(ctx context.Context) (*ServerInfo, error) {
+	var info ServerInfo
+	if err := a.conn.getJSON(ctx, "/v1/info", &info); err != nil {
+		return nil, fmt.Errorf("trino: error getting server info: %w", err)
+	}
+	return &info, nil
+}
+
+// Status returns the status of the node answering requests, like its memory
+// and CPU usage.
+func (a *AdminClient) Status(ctx context.Context) (*NodeStatus, error) {
+	var status NodeStatus
+	if err := a.conn.getJSON(ctx, "/v1/status", &status); err != nil {
+		return nil, fmt.Errorf("trino: error getting node status: %w", err)
+	}
+	return &status, nil
+}
+
+// Nodes returns the worker nodes known by the coordinator.
+func (a *AdminClient) Nodes(ctx context.Context) ([]NodeStats, error) {
+	return a.nodes(ctx, "/v1/node")
+}
+
+// FailedNodes returns the worker nodes the coordinator failed to reach recently.
+func (a *AdminClient) FailedNodes(ctx context.Context) ([]NodeStats, error) {
+	return a.nodes(ctx, "/v1/node/failed")
+}
+
+func (a *AdminClient) nodes(ctx context.Context, path string) ([]NodeStats, error) {
+	var nodes []NodeStats
+	if err := a.conn.getJSON(ctx, path, &nodes); err != nil {
+		return nil, fmt.Errorf("trino: error getting nodes: %w", err)
+	}
+	return nodes, nil
+}
+
+// Query returns the state of a query, with its resource group and its position
+// in the queue, like to alert on queries queued for too long. Use the ID
+// reported to a ProgressUpdater, or by an ErrTrino.
+func (a *AdminClient) Query(ctx context.Context, queryID string) (*QueryInfo, error) {
+	var info QueryInfo
+	// pruned skips the plan and the stages of the query, which can be large
+	if err := a.conn.getJSON(ctx, "/v1/query/"+url.PathEscape(queryID)+"?pruned=true", &info); err != nil {
+		return nil, fmt.Errorf("trino: error getting query %s: %w", queryID, err)
+	}
+	if info.State != "QUEUED" {
+		return &info, nil
+	}
+	var queued []QueryInfo
+	if err := a.conn.getJSON(ctx, "/v1/query?state=QUEUED", &queued); err != nil {
+		return nil, fmt.Errorf("trino: error getting queued queries: %w", err)
+	}
+	info.QueuePosition = 1
+	for _, q := range queued {
+		if q.QueryID != info.QueryID && slices.Equal(q.ResourceGroupID, info.ResourceGroupID) &&
+			q.QueryStats.CreateTime.Before(info.QueryStats.CreateTime) {
+			info.QueuePosition++
+		}
+	}
+	return &info, nil
+}
@@ -0,0 +1,166 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(trinoUserHeader) != "admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/info":
+			w.Write([]byte(`{"nodeVersion":{"version":"450"},"environment":"prod","coordinator":true,"starting":false,"uptime":"3.25h"}`))
+		case "/v1/status":
+			w.Write([]byte(`{"nodeId":"coordinator-1","nodeVersion":{"version":"450"},"environment":"prod","coordinator":true,` +
+				`"uptime":"3.25h","externalAddress":"10.0.0.1","internalAddress":"10.0.0.1","processors":8,` +
+				`"processCpuLoad":0.25,"systemCpuLoad":0.5,"heapUsed":1024,"heapAvailable":4096,"nonHeapUsed":256}`))
+		case "/v1/node":
+			w.Write([]byte(`[{"uri":"http://10.0.0.2:8080","recentRequests":120.5,"recentFailures":0.0,"recentSuccesses":120.5,` +
+				`"lastRequestTime":"2024-01-02T03:04:05.123Z","lastResponseTime":"2024-01-02T03:04:05.124Z",` +
+				`"recentFailureRatio":0.0,"age":"3.20h","recentFailuresByType":{}}]`))
+		case "/v1/node/failed":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	ctx := context.Background()
+
+	client, err := NewAdminClient(ctx, "http://admin@"+strings.TrimPrefix(ts.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+
+	info, err := client.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ServerInfo{NodeVersion: NodeVersion{Version: "450"}, Environment: "prod", Coordinator: true, Uptime: "3.25h"}, info)
+
+	status, err := client.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "coordinator-1", status.NodeID)
+	assert.Equal(t, 8, status.Processors)
+	assert.Equal(t, int64(4096), status.HeapAvailable)
+
+	nodes, err := client.Nodes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []NodeStats{{
+		URI:              "http://10.0.0.2:8080",
+		RecentRequests:   120.5,
+		RecentSuccesses:  120.5,
+		LastRequestTime:  time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC),
+		LastResponseTime: time.Date(2024, 1, 2, 3, 4, 5, 124000000, time.UTC),
+		Age:              "3.20h",
+	}}, nodes)
+
+	_, err = client.FailedNodes(ctx)
+	var qf *ErrQueryFailed
+	require.True(t, errors.As(err, &qf), err)
+	assert.Equal(t, http.StatusForbidden, qf.StatusCode)
+}
+
+func TestAdminClientQuery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/query/queued-query":
+			assert.Equal(t, "true", r.URL.Query().Get("pruned"))
+			w.Write([]byte(`{"queryId":"queued-query","state":"QUEUED","resourceGroupId":["global","adhoc"],` +
+				`"queryStats":{"createTime":"2024-01-02T03:04:05.000Z","queuedTime":"1.50m","elapsedTime":"1.50m"}}`))
+		case "/v1/query/running-query":
+			w.Write([]byte(`{"queryId":"running-query","state":"RUNNING","resourceGroupId":["global","etl"],` +
+				`"queryStats":{"createTime":"2024-01-02T03:04:00.000Z","queuedTime":"10.00ms","elapsedTime":"2.00m"}}`))
+		case "/v1/query":
+			assert.Equal(t, "QUEUED", r.URL.Query().Get("state"))
+			w.Write([]byte(`[
+				{"queryId":"older-query","state":"QUEUED","resourceGroupId":["global","adhoc"],"queryStats":{"createTime":"2024-01-02T03:04:00.000Z"}},
+				{"queryId":"other-group","state":"QUEUED","resourceGroupId":["global","etl"],"queryStats":{"createTime":"2024-01-02T03:04:00.000Z"}},
+				{"queryId":"queued-query","state":"QUEUED","resourceGroupId":["global","adhoc"],"queryStats":{"createTime":"2024-01-02T03:04:05.000Z"}},
+				{"queryId":"newer-query","state":"QUEUED","resourceGroupId":["global","adhoc"],"queryStats":{"createTime":"2024-01-02T03:04:10.000Z"}}
+			]`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	ctx := context.Background()
+
+	client, err := NewAdminClient(ctx, ts.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+
+	info, err := client.Query(ctx, "queued-query")
+	require.NoError(t, err)
+	assert.Equal(t, &QueryInfo{
+		QueryID:         "queued-query",
+		State:           "QUEUED",
+		ResourceGroupID: []string{"global", "adhoc"},
+		QueryStats: QueryInfoStats{
+			CreateTime:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			QueuedTime:  "1.50m",
+			ElapsedTime: "1.50m",
+		},
+		QueuePosition: 2,
+	}, info)
+
+	info, err = client.Query(ctx, "running-query")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"global", "etl"}, info.ResourceGroupID)
+	assert.Zero(t, info.QueuePosition)
+}
+
+func TestAdminClientConnector(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(authorizationHeader) != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"nodeVersion":{"version":"450"},"environment":"prod","coordinator":true}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	connector, err := NewConnector(&Config{ServerURI: ts.URL, TokenSource: staticTokenSource("token")})
+	require.NoError(t, err)
+	client, err := connector.AdminClient(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+	info, err := client.Info(context.Background())
+	require.NoError(t, err, "authenticated with the token source of the connector")
+	assert.Equal(t, "450", info.NodeVersion.Version)
+}
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
@@ -0,0 +1,298 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+)
+
+// Benchmarks of decoding and converting results, reading them from a local
+// fake server, unlike BenchmarkQuery. Compare runs with benchstat to spot
+// regressions, like in the allocations per row:
+//
+//	go test -run '^$' -bench 'Decode|Convert|Rows' -benchmem -count 10 ./trino
+
+// benchmarkColumns are the columns of the results used by benchmarks, with common types.
+const benchmarkColumns = `[
+	{"name":"id","type":"bigint","typeSignature":{"rawType":"bigint","arguments":[]}},
+	{"name":"name","type":"varchar","typeSignature":{"rawType":"varchar","arguments":[{"kind":"LONG","value":2147483647}]}},
+	{"name":"score","type":"double","typeSignature":{"rawType":"double","arguments":[]}},
+	{"name":"price","type":"decimal(18,2)","typeSignature":{"rawType":"decimal","arguments":[{"kind":"LONG","value":18},{"kind":"LONG","value":2}]}},
+	{"name":"created","type":"timestamp(3)","typeSignature":{"rawType":"timestamp","arguments":[{"kind":"LONG","value":3}]}},
+	{"name":"tags","type":"array(varchar)","typeSignature":{"rawType":"array","arguments":[{"kind":"TYPE","value":{"rawType":"varchar","arguments":[{"kind":"LONG","value":2147483647}]}}]}},
+	{"name":"attributes","type":"map(varchar, bigint)","typeSignature":{"rawType":"map","arguments":[{"kind":"TYPE","value":{"rawType":"varchar","arguments":[{"kind":"LONG","value":2147483647}]}},{"kind":"TYPE","value":{"rawType":"bigint","arguments":[]}}]}}
+]`
+
+// benchmarkRow returns the JSON encoding of the i-th row of the benchmark results.
+func benchmarkRow(i int) string {
+	if i%10 == 9 {
+		return `[` + strconv.Itoa(i) + `,null,null,null,null,null,null]`
+	}
+	return fmt.Sprintf(`[%d,"name %d",%d.5,"%d.25","2024-01-02 03:04:05.%03d",["a","b%d"],{"k":%d}]`, i, i, i, i, i%1000, i, i)
+}
+
+// benchmarkPage returns the JSON encoding of a response with the given rows.
+func benchmarkPage(first, rows int, nextURI string) []byte {
+	data := make([]string, rows)
+	for i := range data {
+		data[i] = benchmarkRow(first + i)
+	}
+	next := ""
+	if nextURI != "" {
+		next = fmt.Sprintf(`"nextUri":%q,`, nextURI)
+	}
+	return []byte(fmt.Sprintf(`{"id":"bench",%s"columns":%s,"data":[%s],"stats":{"state":"RUNNING"}}`,
+		next, benchmarkColumns, strings.Join(data, ",")))
+}
+
+func BenchmarkDecodeQueryResponse(b *testing.B) {
+	page := benchmarkPage(0, 1000, "")
+	for _, bm := range []struct {
+		name   string
+		decode func(d *json.Decoder, qresp *queryResponse) error
+	}{
+		{"json", func(d *json.Decoder, qresp *queryResponse) error { return d.Decode(qresp) }},
+		{"raw", protocol.DecodeRawQueryResponse},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(page)))
+			for n := 0; n < b.N; n++ {
+				var qresp queryResponse
+				d := json.NewDecoder(bytes.NewReader(page))
+				d.UseNumber()
+				if err := bm.decode(d, &qresp); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkConvertValue(b *testing.B) {
+	var qresp queryResponse
+	d := json.NewDecoder(bytes.NewReader(benchmarkPage(0, 1, "")))
+	d.UseNumber()
+	require.NoError(b, d.Decode(&qresp))
+	for i, column := range qresp.Columns {
+		require.NoError(b, protocol.UnmarshalArguments(&column.TypeSignature))
+		converter, err := newTypeConverter(column.Type, column.TypeSignature)
+		require.NoError(b, err)
+		value := qresp.Data[0][i]
+		b.Run(column.Type, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := converter.ConvertValue(value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newBenchmarkServer returns a server returning the benchmark results in pages of pageSize rows,
+// compressing the responses with gzip if requested by the client.
+func newBenchmarkServer(b *testing.B, pages, pageSize int) *httptest.Server {
+	var ts *httptest.Server
+	var once sync.Once
+	var encoded [][]byte
+	var compressed [][]byte
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			for i := 0; i < pages; i++ {
+				next := ""
+				if i < pages-1 {
+					next = fmt.Sprintf("%s/v1/statement/bench/%d", ts.URL, i+1)
+				}
+				page := benchmarkPage(i*pageSize, pageSize, next)
+				var buf bytes.Buffer
+				zw := gzip.NewWriter(&buf)
+				zw.Write(page)
+				zw.Close()
+				encoded = append(encoded, page)
+				compressed = append(compressed, buf.Bytes())
+			}
+		})
+		if r.Method == http.MethodPost {
+			fmt.Fprintf(w, `{"id":"bench","nextUri":"%s/v1/statement/bench/0","stats":{"state":"QUEUED"}}`, ts.URL)
+			return
+		}
+		if r.Method != http.MethodGet {
+			return
+		}
+		i, err := strconv.Atoi(r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:])
+		if err != nil || i >= pages {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed[i])
+			return
+		}
+		w.Write(encoded[i])
+	}))
+	b.Cleanup(ts.Close)
+	return ts
+}
+
+// readBenchmarkRows reads all the rows of the benchmark results, returning their number.
+// Raw rows are scanned into sql.RawBytes.
+func readBenchmarkRows(ctx context.Context, db *sql.DB, raw bool) (int, error) {
+	if raw {
+		ctx = WithRawJSON(ctx)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT * FROM bench")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var (
+		id         int64
+		name       sql.NullString
+		score      sql.NullFloat64
+		price      sql.NullString
+		created    sql.NullTime
+		tags       NullSliceString
+		attributes NullMap
+	)
+	dest := []interface{}{&id, &name, &score, &price, &created, &tags, &attributes}
+	if raw {
+		for i := range dest {
+			dest[i] = new(sql.RawBytes)
+		}
+	}
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// reportPerRow reports the time and allocations per row read, besides the ones per operation.
+func reportPerRow(b *testing.B, rows int, before *runtime.MemStats) {
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	total := float64(rows) * float64(b.N)
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/total, "ns/row")
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/total, "allocs/row")
+}
+
+func BenchmarkRows(b *testing.B) {
+	const pages, pageSize = 10, 1000
+	ts := newBenchmarkServer(b, pages, pageSize)
+	for _, bm := range []struct {
+		name string
+		gzip bool
+		raw  bool
+	}{
+		{"json", false, false},
+		{"json+gzip", true, false},
+		{"raw", false, true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			// the default transport requests compressed responses, unless disabled
+			client := &http.Client{Transport: &http.Transport{DisableCompression: !bm.gzip}}
+			clientName := "bench_" + strings.ReplaceAll(bm.name, "+", "_")
+			require.NoError(b, RegisterCustomClient(clientName, client))
+			b.Cleanup(func() { DeregisterCustomClient(clientName) })
+			db, err := sql.Open("trino", ts.URL+"?custom_client="+clientName)
+			require.NoError(b, err)
+			b.Cleanup(func() { db.Close() })
+
+			b.ReportAllocs()
+			var before runtime.MemStats
+			runtime.ReadMemStats(&before)
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				read, err := readBenchmarkRows(context.Background(), db, bm.raw)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if read != pages*pageSize {
+					b.Fatalf("read %d rows, expected %d", read, pages*pageSize)
+				}
+			}
+			b.StopTimer()
+			reportPerRow(b, pages*pageSize, &before)
+		})
+	}
+}
+
+func BenchmarkRowsConcurrent(b *testing.B) {
+	const pages, pageSize = 4, 1000
+	ts := newBenchmarkServer(b, pages, pageSize)
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(b, err)
+	b.Cleanup(func() { db.Close() })
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			db.SetMaxIdleConns(workers)
+			b.ReportAllocs()
+			var before runtime.MemStats
+			runtime.ReadMemStats(&before)
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			queries := make(chan struct{})
+			errs := make(chan error, workers)
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range queries {
+						if _, err := readBenchmarkRows(context.Background(), db, false); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}()
+			}
+			for n := 0; n < b.N; n++ {
+				select {
+				case queries <- struct{}{}:
+				case err := <-errs:
+					b.Fatal(err)
+				}
+			}
+			close(queries)
+			wg.Wait()
+			b.StopTimer()
+			select {
+			case err := <-errs:
+				b.Fatal(err)
+			default:
+			}
+			reportPerRow(b, pages*pageSize, &before)
+		})
+	}
+}
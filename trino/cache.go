@@ -0,0 +1,300 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResultCache caches the results of queries on the client, for applications
+// running the same queries repeatedly, like dashboards. Set it in
+// Config.ResultCache to enable it for all the connections of a Connector.
+//
+// Only the results of queries that read data, like SELECT, WITH, VALUES, SHOW
+// and DESCRIBE, are cached, and only once all of them were read. Results are
+// keyed by the query, with whitespace normalized, its arguments, and the
+// session state of the connection, like the user, catalog, schema and session
+// properties. Entries expire after a TTL, and the least recently used ones are
+// evicted when the cache is full.
+//
+// Use WithoutResultCache to run a query without using the cache.
+type ResultCache struct {
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	bytes   int64
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type resultCacheEntry struct {
+	key     string
+	queryID string
+	// columns are the encoded columns, decoded again for every query,
+	// since their type signatures are modified when reading the results.
+	columns []byte
+	pages   []cachedPage
+	bytes   int64
+	expires time.Time
+}
+
+type cachedPage struct {
+	data        []queryData
+	updateCount int64
+}
+
+// ResultCacheStats are the statistics of a ResultCache.
+type ResultCacheStats struct {
+	// Hits is the number of queries which results were read from the cache.
+	Hits int64
+	// Misses is the number of cacheable queries sent to the server.
+	Misses int64
+	// Entries is the number of cached results.
+	Entries int
+	// Bytes is the size of the cached results, as returned by the server.
+	Bytes int64
+}
+
+// HitRate returns the ratio of cacheable queries which results were read from the cache.
+func (s ResultCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// NewResultCache returns a ResultCache keeping results for ttl, up to a total
+// of maxBytes of results, measured by the size of the responses of the server.
+// Results larger than maxBytes are not cached.
+func NewResultCache(ttl time.Duration, maxBytes int64) *ResultCache {
+	return &ResultCache{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *ResultCache) Stats() ResultCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ResultCacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: len(c.entries),
+		Bytes:   c.bytes,
+	}
+}
+
+// Clear removes all the cached results.
+func (c *ResultCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+func (c *ResultCache) get(key string) (*resultCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*resultCacheEntry)
+		if c.now().Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			c.hits.Add(1)
+			return entry, true
+		}
+		c.remove(elem)
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+func (c *ResultCache) put(entry *resultCacheEntry) {
+	if entry.bytes > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[entry.key]; ok {
+		c.remove(elem)
+	}
+	entry.expires = c.now().Add(c.ttl)
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.bytes += entry.bytes
+	for c.bytes > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *ResultCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*resultCacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.bytes
+}
+
+// WithoutResultCache returns a context executing queries without reading
+// or storing their results in the ResultCache of the connection.
+func WithoutResultCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noResultCacheContextKey, true)
+}
+
+var cacheableQueryRegexp = regexp.MustCompile(`(?is)^(?:\s|--[^\n]*(?:\n|$)|/\*.*?\*/|\()*(?:SELECT|WITH|VALUES|TABLE|SHOW|DESCRIBE)\b`)
+
+// isCacheableQuery reports whether the query only reads data, so its results can be cached.
+func isCacheableQuery(query string) bool {
+	return cacheableQueryRegexp.MatchString(query)
+}
+
+// normalizeQuery replaces runs of whitespace outside of quoted strings and identifiers with a single space.
+func normalizeQuery(query string) string {
+	var b strings.Builder
+	var quote rune
+	space := false
+	for _, r := range strings.TrimSpace(query) {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\v':
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// resultCacheKey returns the key of the results of a query sent with the given headers,
+// which include the session state of the connection.
+func (c *Conn) resultCacheKey(query string, hs http.Header) string {
+	headers := make(http.Header)
+	for k, v := range c.httpHeaders {
+		headers[k] = v
+	}
+	for k, v := range hs {
+		headers[k] = v
+	}
+	// client info and tags identify the caller, and don't change the results
+	delete(headers, trinoClientInfoHeader)
+	delete(headers, trinoClientTagsHeader)
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(normalizeQuery(query))
+	if c.auth != nil {
+		b.WriteString("\n" + c.auth.Username())
+	}
+	if c.authorizationUser != "" {
+		b.WriteString("\nauthorization user: " + c.authorizationUser)
+	}
+	for _, k := range names {
+		for _, v := range headers[k] {
+			b.WriteString("\n" + k + ": " + v)
+		}
+	}
+	return b.String()
+}
+
+// resultRecorder collects the results of a query, to store them in a ResultCache
+// once all of them were read.
+type resultRecorder struct {
+	cache *ResultCache
+	entry *resultCacheEntry
+}
+
+func (r *resultRecorder) record(qresp *queryResponse) {
+	if r.entry == nil {
+		return
+	}
+	r.entry.queryID = qresp.ID
+	if r.entry.columns == nil && len(qresp.Columns) != 0 {
+		columns, err := json.Marshal(qresp.Columns)
+		if err != nil {
+			r.entry = nil
+			return
+		}
+		r.entry.columns = columns
+	}
+	r.entry.bytes += qresp.Bytes
+	if r.entry.bytes > r.cache.maxBytes {
+		// too large to be cached
+		r.entry = nil
+		return
+	}
+	r.entry.pages = append(r.entry.pages, cachedPage{data: qresp.Data, updateCount: qresp.UpdateCount})
+}
+
+func (r *resultRecorder) store() {
+	if r.entry == nil {
+		return
+	}
+	r.cache.put(r.entry)
+	r.entry = nil
+}
+
+// fetchCached reads the next page of results from the cache.
+func (qr *driverRows) fetchCached() error {
+	for qr.cachedPage < len(qr.cached.pages) {
+		page := qr.cached.pages[qr.cachedPage]
+		qr.cachedPage++
+		qresp := queryResponse{ID: qr.queryID, Data: page.data, UpdateCount: page.updateCount}
+		if qr.columns == nil && qr.cached.columns != nil {
+			if err := json.Unmarshal(qr.cached.columns, &qresp.Columns); err != nil {
+				return err
+			}
+		}
+		if err := qr.initColumns(&qresp); err != nil {
+			return err
+		}
+		qr.rowindex = 0
+		qr.data = qresp.Data
+		qr.setRowsAffected(qresp.UpdateCount)
+		if len(qr.data) != 0 {
+			return nil
+		}
+	}
+	return io.EOF
+}
@@ -0,0 +1,191 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultCache(t *testing.T) {
+	var posts atomic.Int64
+	paged := newPagedTestServer(t, 3, nil)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts.Add(1)
+		}
+		paged.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	cache := NewResultCache(time.Minute, 1<<20)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	connector, err := NewConnector(&Config{
+		ServerURI: ts.URL,
+		// results are fetched directly from the paged server
+		AllowedHosts: []string{strings.TrimPrefix(paged.URL, "http://")},
+		ResultCache:  cache,
+	})
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	query := func(ctx context.Context, q string, args ...interface{}) []int {
+		rows, err := db.QueryContext(ctx, q, args...)
+		require.NoError(t, err)
+		defer rows.Close()
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"_col0"}, columns)
+		var values []int
+		for rows.Next() {
+			var v int
+			require.NoError(t, rows.Scan(&v))
+			values = append(values, v)
+		}
+		require.NoError(t, rows.Err())
+		return values
+	}
+	ctx := context.Background()
+
+	assert.Equal(t, []int{1, 2, 3}, query(ctx, "SELECT 1"))
+	assert.Equal(t, []int{1, 2, 3}, query(ctx, "  SELECT\n\t1 "))
+	assert.Equal(t, int64(1), posts.Load())
+	stats := cache.Stats()
+	assert.Equal(t, ResultCacheStats{Hits: 1, Misses: 1, Entries: 1, Bytes: stats.Bytes}, stats)
+	assert.Greater(t, stats.Bytes, int64(0))
+	assert.Equal(t, 0.5, stats.HitRate())
+
+	t.Run("different arguments", func(t *testing.T) {
+		before := posts.Load()
+		query(ctx, "SELECT ?", 1)
+		query(ctx, "SELECT ?", 2)
+		query(ctx, "SELECT ?", 1)
+		assert.Equal(t, before+2, posts.Load())
+	})
+
+	t.Run("different session", func(t *testing.T) {
+		before := posts.Load()
+		query(WithUser(ctx, "alice"), "SELECT 1")
+		assert.Equal(t, before+1, posts.Load())
+	})
+
+	t.Run("without result cache", func(t *testing.T) {
+		before := posts.Load()
+		query(WithoutResultCache(ctx), "SELECT 1")
+		assert.Equal(t, before+1, posts.Load())
+	})
+
+	t.Run("not cacheable", func(t *testing.T) {
+		before := posts.Load()
+		query(ctx, "INSERT INTO t VALUES (1)")
+		query(ctx, "INSERT INTO t VALUES (1)")
+		assert.Equal(t, before+2, posts.Load())
+	})
+
+	t.Run("partially read", func(t *testing.T) {
+		before := posts.Load()
+		for i := 0; i < 2; i++ {
+			rows, err := db.Query("SELECT 2")
+			require.NoError(t, err)
+			require.True(t, rows.Next())
+			require.NoError(t, rows.Close())
+		}
+		assert.Equal(t, before+2, posts.Load())
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		before := posts.Load()
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, []int{1, 2, 3}, query(ctx, "SELECT 1"))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, before, posts.Load())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		before := posts.Load()
+		now = now.Add(time.Minute)
+		query(ctx, "SELECT 1")
+		query(ctx, "SELECT 1")
+		assert.Equal(t, before+1, posts.Load())
+	})
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	cache := NewResultCache(time.Minute, 100)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.put(&resultCacheEntry{key: key, bytes: 40})
+	}
+	_, ok := cache.get("a")
+	assert.False(t, ok, "the least recently used entry is evicted")
+	_, ok = cache.get("b")
+	assert.True(t, ok)
+
+	cache.put(&resultCacheEntry{key: "d", bytes: 40})
+	_, ok = cache.get("c")
+	assert.False(t, ok)
+	_, ok = cache.get("b")
+	assert.True(t, ok)
+
+	cache.put(&resultCacheEntry{key: "e", bytes: 101})
+	_, ok = cache.get("e")
+	assert.False(t, ok, "entries larger than the cache are not stored")
+	assert.Equal(t, int64(80), cache.Stats().Bytes)
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestIsCacheableQuery(t *testing.T) {
+	for query, want := range map[string]bool{
+		"SELECT 1":                             true,
+		"  select 1":                           true,
+		"WITH t AS (SELECT 1) SELECT * FROM t": true,
+		"(SELECT 1) UNION (SELECT 2)":          true,
+		"-- comment\nSELECT 1":                 true,
+		"/* comment */ VALUES 1":               true,
+		"SHOW TABLES":                          true,
+		"DESCRIBE t":                           true,
+		"INSERT INTO t SELECT 1":               false,
+		"SELECTED":                             false,
+		"SET SESSION a = 1":                    false,
+		"-- SELECT\nDROP TABLE t":              false,
+	} {
+		assert.Equal(t, want, isCacheableQuery(query), query)
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	assert.Equal(t, "SELECT 'a  b', \"c\n d\" FROM t", normalizeQuery("\n SELECT  'a  b',\n\t\"c\n d\"   FROM t\n"))
+}
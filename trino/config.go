@@ -0,0 +1,365 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config is a configuration that can be encoded to a DSN string.
+//
+// The optional fields after ResubmitStatements, like TokenSource or
+// ResultCache, can't be encoded in the DSN, and require using NewConnector.
+type Config struct {
+	ServerURI                 string            // URI of the Trino server, e.g. http://user@localhost:8080
+	Source                    string            // Source of the connection (optional)
+	Catalog                   string            // Catalog (optional)
+	Schema                    string            // Schema (optional)
+	SessionProperties         map[string]string // Session properties (optional)
+	ExtraCredentials          map[string]string // Extra credentials (optional)
+	CustomClientName          string            // Custom client name (optional)
+	KerberosEnabled           string            // KerberosEnabled (optional, default is false)
+	KerberosKeytabPath        string            // Kerberos Keytab Path (optional)
+	KerberosPrincipal         string            // Kerberos Principal used to authenticate to KDC (optional)
+	KerberosRemoteServiceName string            // Trino coordinator Kerberos service name (optional)
+	KerberosRealm             string            // The Kerberos Realm (optional)
+	KerberosConfigPath        string            // The krb5 config path (optional)
+	SSLCertPath               string            // The SSL cert path for TLS verification, read again when it changes (optional)
+	SSLCert                   string            // The SSL cert for TLS verification (optional)
+	AccessToken               string            // An access token (JWT) for authentication (optional)
+	AccessTokenPath           string            // Path of a file containing an access token for authentication, read again when it changes (optional)
+	ExplicitPrepare           string            // Send statements with parameters in the prepared statement header, instead of using EXECUTE IMMEDIATE (optional, default is true)
+	CompressRequestBody       string            // Compress request bodies with gzip, if supported by the server or a proxy (optional, default is false)
+	OriginalUser              string            // The original user, when authenticating as a service principal on behalf of another user (optional)
+	AllowedHosts              []string          // Hosts allowed in URIs returned by the server, in addition to the hosts of ServerURI, or "*" to allow any host (optional)
+	HostSelection             string            // How to pick one of multiple comma-separated hosts in ServerURI, "failover" or "round_robin" (optional, default is failover)
+	DiscoverServerVersion     string            // Get the server version from /v1/info when opening connections (optional, default is false)
+	ServerVersion             string            // The server version, instead of discovering it, e.g. when a gateway blocks /v1/info (optional)
+	SubNanosecondTime         string            // How to handle time and timestamp digits beyond nanoseconds, "truncate", "error" or "preserve" (optional, default is truncate)
+	StrictTime                string            // Reject leap seconds and 24:00:00 in time and timestamp values, instead of normalizing them (optional, default is false)
+	FullTypeNames             string            // Include type parameters in column database type names, like DECIMAL(38,10) (optional, default is false)
+	RealAsFloat32             string            // Return REAL values as float32 instead of float64 (optional, default is false)
+	PointerScanTypes          string            // Report pointers, like *string, instead of sql.NullString and other sql.Null types as column scan types (optional, default is false)
+	StrictStringLength        string            // Validate the length of CHAR(N) and VARCHAR(N) values (optional, default is false)
+	MaxPreparedStatements     string            // Maximum number of statements prepared with PREPARE kept by a connection, evicting the oldest ones, or 0 for no limit (optional, default is 100)
+	MaxHeaderSize             string            // Maximum total size of the headers of a request, in bytes, or 0 for no limit (optional, default is 0)
+	MaxHeaderValueSize        string            // Maximum size of a single header value, in bytes, splitting lists of values across repeated headers, or 0 for no limit (optional, default is 0)
+	UserAgentSuffix           string            // Appended to the User-Agent header, like the name and version of the application (optional)
+	HTTPTimeout               string            // Timeout of each HTTP request to the server, like 30s, retrying the ones polling for results (optional, default is no timeout)
+	PrefetchPages             string            // Number of result pages fetched ahead of the one being read, or 0 to only fetch them when needed (optional, default is 1)
+	CookieJar                 string            // Keep the cookies set by the server, like a gateway routing the queries of a connection to the same coordinator (optional, default is false)
+	RedirectHosts             []string          // Hosts to which redirects keep the authentication of the request, or "*" for any host (optional)
+	ValidateLiterals          string            // Check that the SQL literals of query arguments are literals, before folding them into the statement (optional, default is false)
+	MaxScannedBytes           string            // Cancel queries once they read more bytes from the tables than this, or 0 for no limit (optional, default is 0)
+	ConnectionMaxAge          string            // Close the idle HTTP connections to the server at this interval, like 5m, so host names are resolved again (optional, default is never)
+	Dial                      string            // Address to connect to instead of the host of the server URI, like unix:///var/run/trino.sock, tcp://localhost:15001 or srv://_trino._tcp.example.com (optional)
+	ExecutionTimeDeadline     string            // Limit queries setting the query_max_execution_time session property to slightly more than it, instead of DefaultQueryTimeout (optional, default is true)
+	Priorities                []string          // Priorities queries may set with WithPriority, like interactive and batch (optional, default is any)
+	ExecSelect                string            // What Exec does with queries returning rows, like SELECT, "cancel" them after the first page, return an "error", or "read" all their rows (optional, default is cancel)
+	ResubmitStatements        string            // Submit queries again when the request submitting them fails without a response, unless system.runtime.queries shows they were received (optional, default is false)
+
+	// RateLimitCallback is called whenever Trino, or a gateway in front of it,
+	// responds with HTTP 429 Too Many Requests, before the request is retried.
+	RateLimitCallback func(RateLimitInfo)
+
+	// TokenSource provides access tokens for the Authorization header, instead of AccessToken.
+	TokenSource TokenSource
+
+	// CredentialProvider provides the user and password for HTTP Basic authentication
+	// when opening connections, instead of the user and password in ServerURI.
+	CredentialProvider CredentialProvider
+
+	// ResultCache caches the results of queries, shared by all the connections of the Connector.
+	ResultCache *ResultCache
+
+	// QueryRewriter inspects, and can modify or reject, every query before it's sent to the server.
+	QueryRewriter QueryRewriter
+
+	// SessionPolicy restricts the session properties queries may set.
+	SessionPolicy *SessionPolicy
+
+	// QueryTags adds the service name, hostname, a correlation ID and the calling code
+	// to the client info and tags of every query.
+	QueryTags *QueryTags
+
+	// StatementLogger receives a summary of every statement once it's finished.
+	StatementLogger StatementLogger
+
+	// ColumnConverters convert the values of the columns they match, instead of
+	// the driver. The first converter matching a column is used.
+	ColumnConverters []ColumnConverter
+
+	// SessionChangeCallback is called whenever the session state of a connection
+	// changes, from the headers of a response, like after USE, SET SESSION or PREPARE.
+	SessionChangeCallback func(SessionChange)
+}
+
+// CredentialProvider provides the user and password for HTTP Basic authentication,
+// for example by prompting for them, or reading them from a secrets store.
+type CredentialProvider interface {
+	// Credentials returns the user and password. It's called every time a new
+	// connection is opened.
+	Credentials(ctx context.Context) (user, password string, err error)
+}
+
+// CredentialProviderFunc is an adapter allowing to use a function as a CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context) (user, password string, err error)
+
+// Credentials implements the CredentialProvider interface.
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// QueryRewriter inspects queries before they're sent to the server, for example
+// to add comments identifying the application, enforce a LIMIT, or block some
+// statements. It's called with the query as passed to the database/sql methods,
+// before arguments are bound.
+type QueryRewriter interface {
+	// RewriteQuery returns the query to send, or an error to reject it,
+	// which is returned by the database/sql method executing the query.
+	RewriteQuery(ctx context.Context, query string) (string, error)
+}
+
+// QueryRewriterFunc is an adapter allowing to use a function as a QueryRewriter.
+type QueryRewriterFunc func(ctx context.Context, query string) (string, error)
+
+// RewriteQuery implements the QueryRewriter interface.
+func (f QueryRewriterFunc) RewriteQuery(ctx context.Context, query string) (string, error) {
+	return f(ctx, query)
+}
+
+// FormatDSN returns a DSN string from the configuration.
+func (c *Config) FormatDSN() (string, error) {
+	serverURI, hosts := splitHosts(c.ServerURI)
+	serverURL, err := url.Parse(serverURI)
+	if err != nil {
+		return "", err
+	}
+	switch c.HostSelection {
+	case "", hostSelectionFailover, hostSelectionRoundRobin:
+	default:
+		return "", fmt.Errorf("trino: client configuration error, unsupported host selection %q", c.HostSelection)
+	}
+	switch c.SubNanosecondTime {
+	case "", subNanosecondTimeTruncate, subNanosecondTimeError, subNanosecondTimePreserve:
+	default:
+		return "", fmt.Errorf("trino: client configuration error, unsupported sub-nanosecond time handling %q", c.SubNanosecondTime)
+	}
+	switch c.ExecSelect {
+	case "", execSelectCancel, execSelectError, execSelectRead:
+	default:
+		return "", fmt.Errorf("trino: client configuration error, unsupported exec select handling %q", c.ExecSelect)
+	}
+	var sessionkv []string
+	if c.SessionProperties != nil {
+		for k, v := range c.SessionProperties {
+			sessionkv = append(sessionkv, k+"="+v)
+		}
+	}
+	var credkv []string
+	if c.ExtraCredentials != nil {
+		for k, v := range c.ExtraCredentials {
+			credkv = append(credkv, k+"="+v)
+		}
+	}
+	source := c.Source
+	if source == "" {
+		source = "trino-go-client"
+	}
+	query := make(url.Values)
+	query.Add("source", source)
+
+	KerberosEnabled, _ := strconv.ParseBool(c.KerberosEnabled)
+	isSSL := serverURL.Scheme == "https"
+
+	if c.CustomClientName != "" {
+		if c.SSLCert != "" || c.SSLCertPath != "" {
+			return "", fmt.Errorf("trino: client configuration error, a custom client cannot be specific together with a custom SSL certificate")
+		}
+	}
+	if c.SSLCertPath != "" {
+		if !isSSL {
+			return "", fmt.Errorf("trino: client configuration error, SSL must be enabled to specify a custom SSL certificate file")
+		}
+		if c.SSLCert != "" {
+			return "", fmt.Errorf("trino: client configuration error, a custom SSL certificate file cannot be specified together with a certificate string")
+		}
+		query.Add(sslCertPathConfig, c.SSLCertPath)
+	}
+
+	if c.SSLCert != "" {
+		if !isSSL {
+			return "", fmt.Errorf("trino: client configuration error, SSL must be enabled to specify a custom SSL certificate")
+		}
+		if c.SSLCertPath != "" {
+			return "", fmt.Errorf("trino: client configuration error, a custom SSL certificate string cannot be specified together with a certificate file")
+		}
+		query.Add(sslCertConfig, c.SSLCert)
+	}
+
+	if c.AccessToken != "" && c.AccessTokenPath != "" {
+		return "", fmt.Errorf("trino: client configuration error, an access token cannot be specified together with an access token file")
+	}
+
+	if KerberosEnabled {
+		if !isSSL {
+			return "", fmt.Errorf("trino: client configuration error, SSL must be enabled for secure env")
+		}
+		query.Add(kerberosEnabledConfig, "true")
+		query.Add(kerberosKeytabPathConfig, c.KerberosKeytabPath)
+		query.Add(kerberosPrincipalConfig, c.KerberosPrincipal)
+		query.Add(kerberosRealmConfig, c.KerberosRealm)
+		query.Add(kerberosConfigPathConfig, c.KerberosConfigPath)
+		remoteServiceName := c.KerberosRemoteServiceName
+		if remoteServiceName == "" {
+			remoteServiceName = "trino"
+		}
+		query.Add(kerberosRemoteServiceNameConfig, remoteServiceName)
+	}
+
+	// ensure consistent order of items
+	sort.Strings(sessionkv)
+	sort.Strings(credkv)
+
+	for k, v := range map[string]string{
+		"catalog":                   c.Catalog,
+		"schema":                    c.Schema,
+		"session_properties":        strings.Join(sessionkv, ","),
+		"extra_credentials":         strings.Join(credkv, ","),
+		"custom_client":             c.CustomClientName,
+		accessTokenConfig:           c.AccessToken,
+		accessTokenPathConfig:       c.AccessTokenPath,
+		explicitPrepareConfig:       c.ExplicitPrepare,
+		compressRequestBodyConfig:   c.CompressRequestBody,
+		originalUserConfig:          c.OriginalUser,
+		allowedHostsConfig:          strings.Join(c.AllowedHosts, ","),
+		hostSelectionConfig:         c.HostSelection,
+		discoverServerVersionConfig: c.DiscoverServerVersion,
+		serverVersionConfig:         c.ServerVersion,
+		subNanosecondTimeConfig:     c.SubNanosecondTime,
+		strictTimeConfig:            c.StrictTime,
+		fullTypeNamesConfig:         c.FullTypeNames,
+		realAsFloat32Config:         c.RealAsFloat32,
+		pointerScanTypesConfig:      c.PointerScanTypes,
+		strictStringLengthConfig:    c.StrictStringLength,
+		maxPreparedStatementsConfig: c.MaxPreparedStatements,
+		maxHeaderSizeConfig:         c.MaxHeaderSize,
+		maxHeaderValueSizeConfig:    c.MaxHeaderValueSize,
+		userAgentSuffixConfig:       c.UserAgentSuffix,
+		httpTimeoutConfig:           c.HTTPTimeout,
+		prefetchPagesConfig:         c.PrefetchPages,
+		cookieJarConfig:             c.CookieJar,
+		redirectHostsConfig:         strings.Join(c.RedirectHosts, ","),
+		validateLiteralsConfig:      c.ValidateLiterals,
+		maxScannedBytesConfig:       c.MaxScannedBytes,
+		connectionMaxAgeConfig:      c.ConnectionMaxAge,
+		dialConfig:                  c.Dial,
+		executionTimeDeadlineConfig: c.ExecutionTimeDeadline,
+		prioritiesConfig:            strings.Join(c.Priorities, ","),
+		execSelectConfig:            c.ExecSelect,
+		resubmitStatementsConfig:    c.ResubmitStatements,
+	} {
+		if v != "" {
+			query[k] = []string{v}
+		}
+	}
+	serverURL.RawQuery = query.Encode()
+	serverURL.Host = strings.Join(hosts, ",")
+	return serverURL.String(), nil
+}
+
+// splitHosts returns the DSN with only the first of its comma-separated hosts,
+// so that it can be parsed as a URL, and all the hosts.
+func splitHosts(dsn string) (string, []string) {
+	i := strings.Index(dsn, "://")
+	if i == -1 {
+		return dsn, nil
+	}
+	prefix, rest := dsn[:i+len("://")], dsn[i+len("://"):]
+	end := strings.IndexAny(rest, "/?#")
+	if end == -1 {
+		end = len(rest)
+	}
+	authority, suffix := rest[:end], rest[end:]
+	var userinfo string
+	if at := strings.LastIndex(authority, "@"); at != -1 {
+		userinfo, authority = authority[:at+1], authority[at+1:]
+	}
+	hosts := strings.Split(authority, ",")
+	return prefix + userinfo + hosts[0] + suffix, hosts
+}
+
+// Redacted returns a DSN string from the configuration, like FormatDSN,
+// with the password, access token and extra credentials values masked.
+func (c *Config) Redacted() (string, error) {
+	dsn, err := c.FormatDSN()
+	if err != nil {
+		return "", err
+	}
+	return redactDSN(dsn), nil
+}
+
+// String returns the redacted DSN, so that logging the configuration doesn't leak credentials.
+func (c Config) String() string {
+	dsn, err := c.Redacted()
+	if err != nil {
+		return "invalid trino config: " + redactDSN(err.Error())
+	}
+	return dsn
+}
+
+const redactedValue = "xxxxx"
+
+var (
+	dsnPasswordRegexp    = regexp.MustCompile(`(//[^/@:]*:)[^/@]*@`)
+	dsnAccessTokenRegexp = regexp.MustCompile(`(` + accessTokenConfig + `=)[^&"\s]*`)
+)
+
+// redactDSN masks credentials in a DSN. DSNs that can't be parsed are masked on a best-effort basis.
+func redactDSN(dsn string) string {
+	dsn, hosts := splitHosts(dsn)
+	u, err := url.Parse(dsn)
+	if err != nil {
+		dsn = dsnPasswordRegexp.ReplaceAllString(dsn, "${1}"+redactedValue+"@")
+		return dsnAccessTokenRegexp.ReplaceAllString(dsn, "${1}"+redactedValue)
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+	query := u.Query()
+	if query.Get(accessTokenConfig) != "" {
+		query.Set(accessTokenConfig, redactedValue)
+	}
+	if v := query.Get("extra_credentials"); v != "" {
+		creds := strings.Split(v, ",")
+		for i, cred := range creds {
+			if name, _, ok := strings.Cut(cred, "="); ok {
+				creds[i] = name + "=" + redactedValue
+			}
+		}
+		query.Set("extra_credentials", strings.Join(creds, ","))
+	}
+	u.RawQuery = query.Encode()
+	if len(hosts) > 1 {
+		u.Host = strings.Join(hosts, ",")
+	}
+	return u.String()
+}
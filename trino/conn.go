@@ -0,0 +1,637 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino/internal/auth"
+	"gopkg.in/jcmturner/gokrb5.v6/client"
+	"gopkg.in/jcmturner/gokrb5.v6/config"
+	"gopkg.in/jcmturner/gokrb5.v6/keytab"
+)
+
+// Conn is a Trino connection.
+type Conn struct {
+	baseURL string
+	// baseURLs are the base URLs of all the hosts from the DSN, starting with baseURL.
+	baseURLs                  []string
+	roundRobin                bool
+	auth                      *url.Userinfo
+	httpClient                http.Client
+	httpHeaders               http.Header
+	kerberosClient            client.Client
+	kerberosEnabled           bool
+	kerberosRemoteServiceName string
+	progressUpdater           ProgressUpdater
+	progressUpdaterPeriod     queryProgressCallbackPeriod
+	// progress delivers the progress updates of all queries on this connection.
+	progress          *progressDispatcher
+	rateLimitCallback func(RateLimitInfo)
+	resultCache       *ResultCache
+	queryRewriter     QueryRewriter
+	sessionPolicy     *SessionPolicy
+	queryTagger       *queryTagger
+	statementLogger   StatementLogger
+	columnConverters  []ColumnConverter
+	// sessionChangeCallback is called when a response changes the session state.
+	sessionChangeCallback func(SessionChange)
+	// queries tracks the queries in progress, when the connection was created by a Connector.
+	queries            *queryTracker
+	tokenSource        TokenSource
+	accessToken        string
+	useExplicitPrepare bool
+	// autoExplicitPrepare is set when explicitPrepare is not in the DSN,
+	// to use EXECUTE IMMEDIATE if the server supports it.
+	autoExplicitPrepare bool
+	compressRequestBody bool
+	// allowedHosts are the host patterns allowed in URIs returned by the server,
+	// or nil if any host is allowed.
+	allowedHosts []string
+	// redirectHosts are the host patterns of redirect targets to which the
+	// authentication of requests is sent again.
+	redirectHosts         []string
+	discoverServerVersion bool
+	serverVersion         string
+	subNanosecondTime     string
+	strictTime            bool
+	fullTypeNames         bool
+	realAsFloat32         bool
+	pointerScanTypes      bool
+	strictStringLength    bool
+	// maxPreparedStatements is the maximum number of statements prepared with PREPARE,
+	// sent in the prepared statement header, or 0 for no limit.
+	maxPreparedStatements int
+	// maxHeaderSize and maxHeaderValueSize are the maximum sizes of the headers
+	// of a request, and of a single header value, or 0 for no limit.
+	maxHeaderSize      int
+	maxHeaderValueSize int
+	// httpTimeout is the timeout of each request, or 0 for no timeout.
+	httpTimeout time.Duration
+	// prefetchPages is the number of result pages fetched ahead of the one being read.
+	prefetchPages int
+	// validateLiterals checks the literals of query arguments with ValidateLiteral.
+	validateLiterals bool
+	// maxScannedBytes is the budget of physical input bytes of queries, or 0 for no limit.
+	maxScannedBytes int64
+	// connectionMaxAge is the interval at which idle HTTP connections are closed, or 0 for never.
+	// recycledAt and recycledGeneration are the time and the generation of RecycleConnections
+	// at which they were last closed.
+	connectionMaxAge   time.Duration
+	recycledAt         time.Time
+	recycledGeneration int64
+	// executionTimeDeadline limits queries with query_max_execution_time to slightly more than it.
+	executionTimeDeadline bool
+	// priorities are the priorities allowed with WithPriority, or nil if any is allowed.
+	priorities []string
+	// execSelect is what Exec does with queries returning rows.
+	execSelect string
+	// resubmitStatements tags queries with an idempotency key, to submit them again
+	// if the request submitting them fails without a response.
+	resubmitStatements bool
+	// authorizationUser is the user set by SET SESSION AUTHORIZATION, sent instead of the
+	// user of the connection, which is sent as the original user.
+	authorizationUser string
+}
+
+var (
+	_ driver.Conn               = &Conn{}
+	_ driver.ConnPrepareContext = &Conn{}
+	_ driver.SessionResetter    = &Conn{}
+)
+
+func newConn(dsn string) (*Conn, error) {
+	dsn, hosts := splitHosts(dsn)
+	serverURL, err := url.Parse(dsn)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactDSN(urlErr.URL)
+		}
+		return nil, fmt.Errorf("trino: malformed dsn: %w", err)
+	}
+
+	query := serverURL.Query()
+
+	kerberosEnabled, _ := strconv.ParseBool(query.Get(kerberosEnabledConfig))
+
+	useExplicitPrepare := true
+	autoExplicitPrepare := query.Get(explicitPrepareConfig) == ""
+	if v := query.Get(explicitPrepareConfig); v != "" {
+		useExplicitPrepare, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("trino: invalid %s value: %w", explicitPrepareConfig, err)
+		}
+	}
+	compressRequestBody, _ := strconv.ParseBool(query.Get(compressRequestBodyConfig))
+	discoverServerVersion, _ := strconv.ParseBool(query.Get(discoverServerVersionConfig))
+	strictTime, _ := strconv.ParseBool(query.Get(strictTimeConfig))
+	fullTypeNames, _ := strconv.ParseBool(query.Get(fullTypeNamesConfig))
+	realAsFloat32, _ := strconv.ParseBool(query.Get(realAsFloat32Config))
+	pointerScanTypes, _ := strconv.ParseBool(query.Get(pointerScanTypesConfig))
+	strictStringLength, _ := strconv.ParseBool(query.Get(strictStringLengthConfig))
+	cookieJar, _ := strconv.ParseBool(query.Get(cookieJarConfig))
+	validateLiterals, _ := strconv.ParseBool(query.Get(validateLiteralsConfig))
+	resubmitStatements, _ := strconv.ParseBool(query.Get(resubmitStatementsConfig))
+
+	maxPreparedStatements := defaultMaxPreparedStatements
+	if v := query.Get(maxPreparedStatementsConfig); v != "" {
+		maxPreparedStatements, err = strconv.Atoi(v)
+		if err != nil || maxPreparedStatements < 0 {
+			return nil, fmt.Errorf("trino: invalid %s value: %q", maxPreparedStatementsConfig, v)
+		}
+	}
+	var maxHeaderSize, maxHeaderValueSize int
+	for name, size := range map[string]*int{
+		maxHeaderSizeConfig:      &maxHeaderSize,
+		maxHeaderValueSizeConfig: &maxHeaderValueSize,
+	} {
+		if v := query.Get(name); v != "" {
+			*size, err = strconv.Atoi(v)
+			if err != nil || *size < 0 {
+				return nil, fmt.Errorf("trino: invalid %s value: %q", name, v)
+			}
+		}
+	}
+
+	var httpTimeout time.Duration
+	if v := query.Get(httpTimeoutConfig); v != "" {
+		httpTimeout, err = time.ParseDuration(v)
+		if err != nil || httpTimeout < 0 {
+			return nil, fmt.Errorf("trino: invalid %s value: %q", httpTimeoutConfig, v)
+		}
+	}
+
+	var maxScannedBytes int64
+	if v := query.Get(maxScannedBytesConfig); v != "" {
+		maxScannedBytes, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || maxScannedBytes < 0 {
+			return nil, fmt.Errorf("trino: invalid %s value: %q", maxScannedBytesConfig, v)
+		}
+	}
+
+	executionTimeDeadline := true
+	if v := query.Get(executionTimeDeadlineConfig); v != "" {
+		executionTimeDeadline, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("trino: invalid %s value: %w", executionTimeDeadlineConfig, err)
+		}
+	}
+
+	var connectionMaxAge time.Duration
+	if v := query.Get(connectionMaxAgeConfig); v != "" {
+		connectionMaxAge, err = time.ParseDuration(v)
+		if err != nil || connectionMaxAge < 0 {
+			return nil, fmt.Errorf("trino: invalid %s value: %q", connectionMaxAgeConfig, v)
+		}
+	}
+
+	prefetchPages := defaultPrefetchPages
+	if v := query.Get(prefetchPagesConfig); v != "" {
+		prefetchPages, err = strconv.Atoi(v)
+		if err != nil || prefetchPages < 0 {
+			return nil, fmt.Errorf("trino: invalid %s value: %q", prefetchPagesConfig, v)
+		}
+	}
+
+	var roundRobin bool
+	switch v := query.Get(hostSelectionConfig); v {
+	case "", hostSelectionFailover:
+	case hostSelectionRoundRobin:
+		roundRobin = true
+	default:
+		return nil, fmt.Errorf("trino: invalid %s value: %q", hostSelectionConfig, v)
+	}
+
+	subNanosecondTime := query.Get(subNanosecondTimeConfig)
+	switch subNanosecondTime {
+	case "", subNanosecondTimeTruncate, subNanosecondTimeError, subNanosecondTimePreserve:
+	default:
+		return nil, fmt.Errorf("trino: invalid %s value: %q", subNanosecondTimeConfig, subNanosecondTime)
+	}
+
+	execSelect := query.Get(execSelectConfig)
+	switch execSelect {
+	case "":
+		execSelect = execSelectCancel
+	case execSelectCancel, execSelectError, execSelectRead:
+	default:
+		return nil, fmt.Errorf("trino: invalid %s value: %q", execSelectConfig, execSelect)
+	}
+
+	baseURLs := []string{serverURL.Scheme + "://" + serverURL.Host}
+	for _, host := range hosts[min(1, len(hosts)):] {
+		if host == "" {
+			return nil, fmt.Errorf("trino: malformed dsn: empty host")
+		}
+		baseURLs = append(baseURLs, serverURL.Scheme+"://"+host)
+	}
+
+	var kerberosClient client.Client
+
+	if kerberosEnabled {
+		kt, err := keytab.Load(query.Get(kerberosKeytabPathConfig))
+		if err != nil {
+			return nil, fmt.Errorf("trino: Error loading Keytab: %w", err)
+		}
+
+		kerberosClient = client.NewClientWithKeytab(query.Get(kerberosPrincipalConfig), query.Get(kerberosRealmConfig), kt)
+		conf, err := config.Load(query.Get(kerberosConfigPathConfig))
+		if err != nil {
+			return nil, fmt.Errorf("trino: Error loading krb config: %w", err)
+		}
+
+		kerberosClient.WithConfig(conf)
+
+		loginErr := kerberosClient.Login()
+		if loginErr != nil {
+			return nil, fmt.Errorf("trino: Error login to KDC: %v", loginErr)
+		}
+	}
+
+	dialContext, err := newDialContext(query.Get(dialConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	var httpClient = http.DefaultClient
+	if clientKey := query.Get("custom_client"); clientKey != "" {
+		if dialContext != nil {
+			return nil, fmt.Errorf("trino: %s can't be used with a custom client", dialConfig)
+		}
+		httpClient = getCustomClient(clientKey)
+		if httpClient == nil {
+			return nil, fmt.Errorf("trino: custom client not registered: %q", clientKey)
+		}
+	} else if serverURL.Scheme == "https" {
+
+		cert := []byte(query.Get(sslCertConfig))
+
+		if certPath := query.Get(sslCertPathConfig); certPath != "" {
+			transport, err := auth.NewCertReloadingTransport(certPath, dialContext)
+			if err != nil {
+				return nil, fmt.Errorf("trino: Error loading SSL Cert File: %w", err)
+			}
+			httpClient = &http.Client{
+				Transport: transport,
+			}
+		} else if len(cert) != 0 {
+			certPool := x509.NewCertPool()
+			certPool.AppendCertsFromPEM(cert)
+
+			httpClient = &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						RootCAs: certPool,
+					},
+					DialContext: dialContext,
+				},
+			}
+		}
+	}
+	if dialContext != nil && httpClient == http.DefaultClient {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialContext
+		httpClient = &http.Client{Transport: transport}
+	}
+
+	c := &Conn{
+		baseURL:                   baseURLs[0],
+		baseURLs:                  baseURLs,
+		roundRobin:                roundRobin,
+		httpClient:                *httpClient,
+		httpHeaders:               make(http.Header),
+		kerberosClient:            kerberosClient,
+		kerberosEnabled:           kerberosEnabled,
+		kerberosRemoteServiceName: query.Get(kerberosRemoteServiceNameConfig),
+		useExplicitPrepare:        useExplicitPrepare,
+		autoExplicitPrepare:       autoExplicitPrepare,
+		compressRequestBody:       compressRequestBody,
+		accessToken:               query.Get(accessTokenConfig),
+		discoverServerVersion:     discoverServerVersion,
+		serverVersion:             query.Get(serverVersionConfig),
+		subNanosecondTime:         subNanosecondTime,
+		strictTime:                strictTime,
+		fullTypeNames:             fullTypeNames,
+		realAsFloat32:             realAsFloat32,
+		pointerScanTypes:          pointerScanTypes,
+		strictStringLength:        strictStringLength,
+		maxPreparedStatements:     maxPreparedStatements,
+		maxHeaderSize:             maxHeaderSize,
+		maxHeaderValueSize:        maxHeaderValueSize,
+		httpTimeout:               httpTimeout,
+		prefetchPages:             prefetchPages,
+		validateLiterals:          validateLiterals,
+		maxScannedBytes:           maxScannedBytes,
+		connectionMaxAge:          connectionMaxAge,
+		recycledAt:                time.Now(),
+		recycledGeneration:        recycleGeneration.Load(),
+		executionTimeDeadline:     executionTimeDeadline,
+		execSelect:                execSelect,
+		resubmitStatements:        resubmitStatements,
+	}
+
+	if path := query.Get(accessTokenPathConfig); path != "" {
+		c.tokenSource = auth.NewFileTokenSource(path)
+	}
+
+	// every connection gets its own jar, so that the cookies of a gateway pin
+	// its queries to a coordinator, unless a custom client already has one
+	if cookieJar && c.httpClient.Jar == nil {
+		// cookiejar.New never fails without options
+		c.httpClient.Jar, _ = cookiejar.New(nil)
+	}
+
+	if v := query.Get(redirectHostsConfig); v != "" {
+		c.redirectHosts = strings.Split(v, ",")
+		c.httpClient.CheckRedirect = c.redirectPolicy(c.httpClient.CheckRedirect)
+	}
+
+	if v := query.Get(prioritiesConfig); v != "" {
+		c.priorities = strings.Split(v, ",")
+	}
+
+	if v := query.Get(allowedHostsConfig); v != "*" {
+		c.allowedHosts = []string{serverURL.Host}
+		for _, u := range baseURLs[1:] {
+			c.allowedHosts = append(c.allowedHosts, strings.TrimPrefix(u, serverURL.Scheme+"://"))
+		}
+		if v != "" {
+			c.allowedHosts = append(c.allowedHosts, strings.Split(v, ",")...)
+		}
+	}
+
+	var user string
+	if serverURL.User != nil {
+		user = serverURL.User.Username()
+		pass, _ := serverURL.User.Password()
+		if pass != "" && serverURL.Scheme == "https" {
+			c.auth = serverURL.User
+		}
+	}
+
+	for k, v := range map[string]string{
+		trinoUserHeader:            user,
+		trinoOriginalUserHeader:    query.Get(originalUserConfig),
+		trinoSourceHeader:          query.Get("source"),
+		trinoCatalogHeader:         query.Get("catalog"),
+		trinoSchemaHeader:          query.Get("schema"),
+		trinoSessionHeader:         query.Get("session_properties"),
+		trinoExtraCredentialHeader: query.Get("extra_credentials"),
+		authorizationHeader:        getAuthorization(query.Get(accessTokenConfig)),
+		userAgentHeader:            userAgent(query.Get(userAgentSuffixConfig)),
+	} {
+		if v != "" {
+			c.httpHeaders.Add(k, v)
+		}
+	}
+
+	return c, nil
+}
+
+func getAuthorization(token string) string {
+	if token == "" {
+		return ""
+	}
+	return fmt.Sprintf("Bearer %s", token)
+}
+
+// registry for custom http clients
+var customClientRegistry = struct {
+	sync.RWMutex
+	Index map[string]http.Client
+}{
+	Index: make(map[string]http.Client),
+}
+
+// RegisterCustomClient associates a client to a key in the driver's registry.
+//
+// Register your custom client in the driver, then refer to it by name in the DSN, on the call to sql.Open:
+//
+//	foobarClient := &http.Client{
+//		Transport: &http.Transport{
+//			Proxy: http.ProxyFromEnvironment,
+//			DialContext: (&net.Dialer{
+//				Timeout:   30 * time.Second,
+//				KeepAlive: 30 * time.Second,
+//				DualStack: true,
+//			}).DialContext,
+//			MaxIdleConns:          100,
+//			IdleConnTimeout:       90 * time.Second,
+//			TLSHandshakeTimeout:   10 * time.Second,
+//			ExpectContinueTimeout: 1 * time.Second,
+//			TLSClientConfig:       &tls.Config{
+//			// your config here...
+//			},
+//		},
+//	}
+//	trino.RegisterCustomClient("foobar", foobarClient)
+//	db, err := sql.Open("trino", "https://user@localhost:8080?custom_client=foobar")
+func RegisterCustomClient(key string, client *http.Client) error {
+	if _, err := strconv.ParseBool(key); err == nil {
+		return fmt.Errorf("trino: custom client key %q is reserved", key)
+	}
+	customClientRegistry.Lock()
+	customClientRegistry.Index[key] = *client
+	customClientRegistry.Unlock()
+	return nil
+}
+
+// DeregisterCustomClient removes the client associated to the key.
+func DeregisterCustomClient(key string) {
+	customClientRegistry.Lock()
+	delete(customClientRegistry.Index, key)
+	customClientRegistry.Unlock()
+}
+
+func getCustomClient(key string) *http.Client {
+	customClientRegistry.RLock()
+	defer customClientRegistry.RUnlock()
+	if client, ok := customClientRegistry.Index[key]; ok {
+		return &client
+	}
+	return nil
+}
+
+// Begin implements the driver.Conn interface.
+func (c *Conn) Begin() (driver.Tx, error) {
+	return nil, &UnsupportedOperationError{
+		Operation:   "transactions",
+		Alternative: "queries run in auto-commit mode, use a single statement, like INSERT INTO ... SELECT, to change data atomically",
+	}
+}
+
+// Prepare implements the driver.Conn interface.
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+// PrepareContext implements the driver.ConnPrepareContext interface.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	st := &driverStmt{conn: c, query: query}
+	if m := executeRegexp.FindStringSubmatch(stripComments(query)); m != nil && c.hasPreparedStatement(m[1]) {
+		st.preparedName = m[1]
+	}
+	return st, nil
+}
+
+// executeRegexp matches the EXECUTE statements of a statement prepared with PREPARE.
+var executeRegexp = regexp.MustCompile(`(?is)^EXECUTE\s+([a-z_][a-z0-9_]*)(?:\s+USING\s.*)?$`)
+
+// ResetSession implements the driver.SessionResetter interface. It's called before
+// reusing a connection from the pool, and removes the statements prepared with PREPARE,
+// and the user set by SET SESSION AUTHORIZATION, so they're not used by the queries
+// of the next user of the connection.
+func (c *Conn) ResetSession(ctx context.Context) error {
+	c.httpHeaders.Del(preparedStatementHeader)
+	c.authorizationUser = ""
+	return nil
+}
+
+// addPreparedStatement keeps a statement prepared with PREPARE, as name=statement,
+// replacing one with the same name, and evicting the oldest ones above maxPreparedStatements.
+// It reports whether the statement was kept, unlike the one prepared by the driver.
+func (c *Conn) addPreparedStatement(v string) bool {
+	name, _, _ := strings.Cut(v, "=")
+	if name == preparedStatementName {
+		// the statement prepared by the driver is sent with every query using it
+		return false
+	}
+	c.removePreparedStatement(name)
+	c.httpHeaders.Add(preparedStatementHeader, v)
+	values := c.httpHeaders.Values(preparedStatementHeader)
+	if c.maxPreparedStatements > 0 && len(values) > c.maxPreparedStatements {
+		c.httpHeaders[http.CanonicalHeaderKey(preparedStatementHeader)] = values[len(values)-c.maxPreparedStatements:]
+	}
+	return true
+}
+
+// hasPreparedStatement reports whether the connection keeps the statement
+// prepared with PREPARE as name.
+func (c *Conn) hasPreparedStatement(name string) bool {
+	for _, v := range c.httpHeaders.Values(preparedStatementHeader) {
+		if prepared, _, _ := strings.Cut(v, "="); strings.EqualFold(prepared, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Conn) removePreparedStatement(name string) {
+	values := c.httpHeaders.Values(preparedStatementHeader)
+	c.httpHeaders.Del(preparedStatementHeader)
+	for _, v := range values {
+		if !strings.HasPrefix(v, name+"=") {
+			c.httpHeaders.Add(preparedStatementHeader, v)
+		}
+	}
+}
+
+// ServerVersion returns the version of the Trino server, as set in the DSN
+// or discovered when opening the connection, or an empty string if it's unknown.
+//
+// Use sql.Conn.Raw to access it:
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		version = driverConn.(*trino.Conn).ServerVersion()
+//		return nil
+//	})
+func (c *Conn) ServerVersion() string {
+	return c.serverVersion
+}
+
+// minExecuteImmediateVersion is the first Trino version supporting EXECUTE IMMEDIATE.
+const minExecuteImmediateVersion = 431
+
+// serverVersionNumber returns the numeric part of the server version,
+// like 431 for 431-SNAPSHOT, or 0 if it's unknown.
+func (c *Conn) serverVersionNumber() int {
+	digits := c.serverVersion
+	if i := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); i != -1 {
+		digits = digits[:i]
+	}
+	n, _ := strconv.Atoi(digits)
+	return n
+}
+
+// supportsExecuteImmediate returns false if the server is known to be older
+// than the first version supporting EXECUTE IMMEDIATE.
+func (c *Conn) supportsExecuteImmediate() bool {
+	v := c.serverVersionNumber()
+	return v == 0 || v >= minExecuteImmediateVersion
+}
+
+// fetchServerVersion gets the server version from the /v1/info endpoint.
+func (c *Conn) fetchServerVersion(ctx context.Context) error {
+	var info ServerInfo
+	if err := c.getJSON(ctx, "/v1/info", &info); err != nil {
+		return fmt.Errorf("trino: error getting server version: %w", err)
+	}
+	c.serverVersion = info.NodeVersion.Version
+	return nil
+}
+
+// getJSON decodes the JSON response of a GET request to an endpoint of the REST API,
+// like /v1/info, failing over to the next host if one doesn't respond.
+func (c *Conn) getJSON(ctx context.Context, path string, v interface{}) error {
+	var cancel context.CancelFunc = func() {}
+	if _, ok := ctx.Deadline(); !ok {
+		ctx, cancel = context.WithTimeout(ctx, DefaultQueryTimeout)
+	}
+	defer cancel()
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i, baseURL := range c.baseURLs {
+		var req *http.Request
+		req, err = c.newRequest(ctx, "GET", baseURL+path, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp, err = c.roundTrip(ctx, req)
+		if err == nil {
+			break
+		}
+		if i == len(c.baseURLs)-1 || !isConnectionError(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Close implements the driver.Conn interface.
+func (c *Conn) Close() error {
+	if c.progress != nil {
+		c.progress.close()
+		c.progress = nil
+	}
+	return nil
+}
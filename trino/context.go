@@ -0,0 +1,150 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	columnMetadataContextKey
+	columnsContextKey
+	noResultCacheContextKey
+	correlationIDContextKey
+	execProgressContextKey
+	rawJSONContextKey
+	statementObserverContextKey
+	sessionPropertiesContextKey
+	scannedBytesBudgetContextKey
+	priorityContextKey
+	columnsFirstContextKey
+)
+
+// WithUser returns a context executing queries as the given user, instead of
+// the user of the connection. It has the same effect as passing an X-Trino-User
+// NamedArg to the query.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// WithScannedBytesBudget returns a context cancelling queries once they read
+// more bytes from the tables than budget, failing with ErrBudgetExceeded,
+// instead of the max_scanned_bytes parameter of the DSN. A budget of 0 disables it.
+func WithScannedBytesBudget(ctx context.Context, budget int64) context.Context {
+	return context.WithValue(ctx, scannedBytesBudgetContextKey, budget)
+}
+
+// WithColumnMetadata returns a context calling fn with the metadata of the columns
+// of queries, as soon as it's returned by the server.
+func WithColumnMetadata(ctx context.Context, fn func([]ColumnMetadata)) context.Context {
+	return context.WithValue(ctx, columnMetadataContextKey, fn)
+}
+
+// WithColumns returns a context limiting the conversion of query results to the
+// columns with the given names. Other columns are returned as nil, which avoids
+// the cost of converting values that are not used, in queries returning many
+// columns. Scan them into a sql.RawBytes or an *interface{}.
+//
+// It doesn't change the query, so the server still returns all the columns.
+func WithColumns(ctx context.Context, names ...string) context.Context {
+	return context.WithValue(ctx, columnsContextKey, names)
+}
+
+// WithColumnsFirst returns a context making QueryContext return as soon as the
+// server reports the columns of queries, before their first rows, so that
+// Columns and ColumnTypes don't wait for them, like to show the schema of the
+// results in a UI while the query runs. Next then waits for the rows.
+func WithColumnsFirst(ctx context.Context) context.Context {
+	return context.WithValue(ctx, columnsFirstContextKey, true)
+}
+
+// WithExecProgress returns a context calling fn with the progress of statements
+// executed using Exec, like large INSERT, UPDATE, DELETE or MERGE statements,
+// every time the server reports it, while Exec waits for them to finish.
+// fn is called from the goroutine calling Exec, so it delays reading the next
+// status of the statement until it returns.
+func WithExecProgress(ctx context.Context, fn func(ExecProgress)) context.Context {
+	return context.WithValue(ctx, execProgressContextKey, fn)
+}
+
+// ExecProgress is the progress of a statement executed using Exec, passed to the
+// function set with WithExecProgress.
+type ExecProgress struct {
+	// QueryID is the ID of the query.
+	QueryID string
+	// State is the state of the query, like QUEUED, RUNNING or FINISHED.
+	State string
+	// QueuedTime is how long the query waited in the queue of its resource group,
+	// and ElapsedTime how long it ran since it was created, including QueuedTime.
+	QueuedTime  time.Duration
+	ElapsedTime time.Duration
+	// ProcessedRows and ProcessedBytes are the rows and bytes read by the query so far.
+	ProcessedRows  int64
+	ProcessedBytes int64
+	// PhysicalWrittenBytes is the number of bytes written by the query so far.
+	PhysicalWrittenBytes int64
+	// ProgressPercentage is the estimated progress of the query, from 0 to 100.
+	ProgressPercentage float32
+	// RowsAffected is the number of rows affected by the statement, once reported by the server.
+	RowsAffected int64
+}
+
+func newExecProgress(queryID string, stats stmtStats, rowsAffected int64) ExecProgress {
+	return ExecProgress{
+		QueryID:              queryID,
+		State:                stats.State,
+		QueuedTime:           time.Duration(stats.QueuedTimeMillis) * time.Millisecond,
+		ElapsedTime:          time.Duration(stats.ElapsedTimeMillis) * time.Millisecond,
+		ProcessedRows:        stats.ProcessedRows,
+		ProcessedBytes:       stats.ProcessedBytes,
+		PhysicalWrittenBytes: stats.PhysicalWrittenBytes,
+		ProgressPercentage:   stats.ProgressPercentage,
+		RowsAffected:         rowsAffected,
+	}
+}
+
+// WithRawJSON returns a context returning the values of queries as they were encoded
+// by the server, in JSON, without converting them, for applications that only
+// serialize them again. Scan them into a json.RawMessage, a []byte or a sql.RawBytes.
+// NULL values are returned as null. The results of these queries are not cached.
+func WithRawJSON(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawJSONContextKey, true)
+}
+
+// WithStatementObserver returns a context calling fn with every statement submitted
+// to the server, right before sending it, for audit logging or debugging how
+// arguments were serialized. The statement includes the arguments folded into
+// EXECUTE or EXECUTE IMMEDIATE, and isn't called for results read from the
+// ResultCache. fn is called again if the statement is resubmitted to another host.
+func WithStatementObserver(ctx context.Context, fn func(SubmittedStatement)) context.Context {
+	return context.WithValue(ctx, statementObserverContextKey, fn)
+}
+
+// SubmittedStatement is a statement submitted to the server, passed to the function
+// set with WithStatementObserver.
+type SubmittedStatement struct {
+	// URL is the URL the statement is submitted to.
+	URL string
+	// Query is the text of the statement, before compressing it.
+	Query string
+	// Header are the headers of the request, like the session and the prepared
+	// statements. The Authorization header is removed, to not leak credentials.
+	Header http.Header
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// ColumnConverter converts the values of the columns it matches, instead of
+// the driver, for example to return DECIMAL amounts as int64 cents:
+//
+//	trino.ColumnConverter{
+//		Type: "decimal",
+//		Name: "*_amount",
+//		Convert: func(column trino.ColumnMetadata, v interface{}) (driver.Value, error) {
+//			return parseCents(v.(string))
+//		},
+//		ScanType: reflect.TypeOf(int64(0)),
+//	}
+type ColumnConverter struct {
+	// Type matches the columns of a type, case insensitively, with or without
+	// its parameters, like "decimal" or "decimal(10,2)", or any type if empty.
+	Type string
+	// Name matches the columns by name, case insensitively, with a pattern
+	// of path.Match, like "*_cents", or any name if empty.
+	Name string
+	// Convert converts the values of the matched columns, as decoded from the
+	// JSON response: a string, a json.Number, a bool, a []interface{} for
+	// arrays and rows, or a map[string]interface{} for maps. It's not called
+	// for NULL values, which are returned as nil. Its result is passed to
+	// Scan like the values converted by the driver.
+	Convert func(column ColumnMetadata, v interface{}) (driver.Value, error)
+	// ScanType is returned by ColumnTypeScanType for the matched columns
+	// (optional, default is the scan type of the driver).
+	ScanType reflect.Type
+}
+
+// validateColumnConverters returns an error if a converter has no Convert
+// function, or an invalid name pattern.
+func validateColumnConverters(converters []ColumnConverter) error {
+	for i, converter := range converters {
+		if converter.Convert == nil {
+			return fmt.Errorf("trino: column converter %d has no Convert function", i)
+		}
+		if _, err := path.Match(converter.Name, ""); err != nil {
+			return fmt.Errorf("trino: invalid name pattern %q of column converter %d: %w", converter.Name, i, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the converter applies to the column.
+func (cc *ColumnConverter) matches(column ColumnMetadata, rawType string) bool {
+	if cc.Type != "" && !strings.EqualFold(cc.Type, rawType) && !strings.EqualFold(cc.Type, column.Type) {
+		return false
+	}
+	if cc.Name == "" {
+		return true
+	}
+	matched, err := path.Match(strings.ToLower(cc.Name), strings.ToLower(column.Name))
+	return err == nil && matched
+}
+
+// setColumnConverter makes the first of the converters matching the column
+// convert its values.
+func (c *typeConverter) setColumnConverter(converters []ColumnConverter, name string) {
+	column := ColumnMetadata{Name: name, Type: c.typeName, TypeSignature: c.signature}
+	for i := range converters {
+		cc := &converters[i]
+		if !cc.matches(column, c.parsedType[0]) {
+			continue
+		}
+		c.convert = func(v interface{}) (driver.Value, error) {
+			if v == nil {
+				return nil, nil
+			}
+			return cc.Convert(column, v)
+		}
+		if cc.ScanType != nil {
+			c.scanType = cc.ScanType
+		}
+		return
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+func TestColumnConverters(t *testing.T) {
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{
+		Columns: []trinomock.Column{
+			{Name: "total_amount", Type: "decimal(10,2)"},
+			{Name: "discount_amount", Type: "decimal(10,2)"},
+			{Name: "Code", Type: "varchar"},
+			{Name: "rate", Type: "decimal(10,2)"},
+		},
+		Rows: [][]interface{}{{"12.34", nil, "abc", "0.50"}},
+	})
+
+	var columns []ColumnMetadata
+	connector, err := NewConnector(&Config{
+		ServerURI: server.DSN(),
+		ColumnConverters: []ColumnConverter{
+			{
+				Type: "DECIMAL",
+				Name: "*_AMOUNT",
+				Convert: func(column ColumnMetadata, v interface{}) (driver.Value, error) {
+					columns = append(columns, column)
+					units, cents, _ := strings.Cut(v.(string), ".")
+					var amount int64
+					_, err := fmt.Sscan(units+cents, &amount)
+					return amount, err
+				},
+				ScanType: reflect.TypeOf(int64(0)),
+			},
+			{
+				Name: "code",
+				Convert: func(column ColumnMetadata, v interface{}) (driver.Value, error) {
+					return strings.ToUpper(v.(string)), nil
+				},
+			},
+			{
+				Type: "varchar",
+				Convert: func(column ColumnMetadata, v interface{}) (driver.Value, error) {
+					return nil, fmt.Errorf("only the first converter matching a column is used")
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	types, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	assert.Equal(t, reflect.TypeOf(int64(0)), types[0].ScanType())
+	assert.Equal(t, reflect.TypeOf(sql.NullString{}), types[2].ScanType())
+	assert.Equal(t, reflect.TypeOf(sql.NullString{}), types[3].ScanType())
+	require.True(t, rows.Next())
+	var (
+		total    int64
+		discount sql.NullInt64
+		code     string
+		rate     string
+	)
+	require.NoError(t, rows.Scan(&total, &discount, &code, &rate))
+	require.NoError(t, rows.Close())
+	assert.Equal(t, int64(1234), total)
+	assert.False(t, discount.Valid)
+	assert.Equal(t, "ABC", code)
+	assert.Equal(t, "0.50", rate)
+	require.Len(t, columns, 1)
+	assert.Equal(t, "total_amount", columns[0].Name)
+	assert.Equal(t, "decimal(10,2)", columns[0].Type)
+
+	for _, converters := range [][]ColumnConverter{
+		{{Type: "decimal"}},
+		{{Name: "[", Convert: func(ColumnMetadata, interface{}) (driver.Value, error) { return nil, nil }}},
+	} {
+		_, err = NewConnector(&Config{ServerURI: server.DSN(), ColumnConverters: converters})
+		assert.Error(t, err)
+	}
+}
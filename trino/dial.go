@@ -0,0 +1,94 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// lookupSRV resolves the SRV records of the dial parameter, and is replaced
+// in tests.
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// newDialContext returns the function dialing the address of the dial
+// parameter, instead of the host of the server URI, which the requests still
+// use for the Host header and TLS, or nil if it's empty:
+//
+//   - unix:///path/to/socket dials a Unix domain socket, like the one of a
+//     sidecar proxy.
+//   - tcp://host:port dials another TCP address.
+//   - srv://name resolves the SRV records of name, like _trino._tcp.example.com,
+//     for every connection, and dials their targets in order of priority.
+func newDialContext(target string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if target == "" {
+		return nil, nil
+	}
+	invalid := fmt.Errorf("trino: invalid %s value: %q", dialConfig, target)
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, invalid
+	}
+	var dialer net.Dialer
+	switch u.Scheme {
+	case "unix":
+		path := u.Host + u.Path
+		if path == "" {
+			return nil, invalid
+		}
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}, nil
+	case "tcp":
+		if u.Port() == "" || u.Path != "" {
+			return nil, invalid
+		}
+		address := u.Host
+		return func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		}, nil
+	case "srv":
+		if u.Host == "" || u.Path != "" {
+			return nil, invalid
+		}
+		name := u.Host
+		return func(ctx context.Context, network, _ string) (net.Conn, error) {
+			// the records are sorted by priority, and randomized by weight
+			_, records, err := lookupSRV(ctx, "", "", name)
+			if err != nil {
+				return nil, fmt.Errorf("trino: resolving %s: %w", name, err)
+			}
+			var errs []error
+			for _, record := range records {
+				address := net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))
+				conn, err := dialer.DialContext(ctx, network, address)
+				if err == nil {
+					return conn, nil
+				}
+				errs = append(errs, err)
+			}
+			if len(errs) == 0 {
+				return nil, fmt.Errorf("trino: no SRV records for %s", name)
+			}
+			return nil, errors.Join(errs...)
+		}, nil
+	}
+	return nil, invalid
+}
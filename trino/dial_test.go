@@ -0,0 +1,145 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDialServer returns a server answering queries with the Host header of
+// their requests, and with next URIs on the same host.
+func newDialServer(t *testing.T, listener net.Listener) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodPost:
+			w.Write([]byte(`{"id":"fake-query","nextUri":"http://` + r.Host + `/v1/statement/fake-query/1"}`))
+			return
+		}
+		w.Write([]byte(`{"id":"fake-query","columns":[{"name":"host","type":"varchar","typeSignature":{"rawType":"varchar","arguments":[]}}],"data":[["` + r.Host + `"]]}`))
+	}))
+	if listener != nil {
+		ts.Listener.Close()
+		ts.Listener = listener
+	}
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func queryHost(t *testing.T, dsn string) string {
+	db, err := sql.Open("trino", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	var host string
+	require.NoError(t, db.QueryRow("SELECT 1").Scan(&host))
+	return host
+}
+
+func TestDialUnix(t *testing.T) {
+	// the paths of Unix sockets are limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "trino")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	socket := filepath.Join(dir, "trino.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	newDialServer(t, listener)
+
+	dsn, err := (&Config{ServerURI: "http://trino.invalid:8080", Dial: "unix://" + socket}).FormatDSN()
+	require.NoError(t, err)
+	assert.Equal(t, "trino.invalid:8080", queryHost(t, dsn))
+}
+
+func TestDialTCP(t *testing.T) {
+	ts := newDialServer(t, nil)
+	address := ts.Listener.Addr().String()
+	assert.Equal(t, "trino.invalid:8080", queryHost(t, "http://trino.invalid:8080?dial="+url.QueryEscape("tcp://"+address)))
+}
+
+func TestDialSRV(t *testing.T) {
+	ts := newDialServer(t, nil)
+	host, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	var names []string
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		names = append(names, name)
+		if name == "_missing._tcp.example.com" {
+			return "", nil, errors.New("no such host")
+		}
+		return name, []*net.SRV{
+			// refused, as the server is on another port
+			{Target: host + ".", Port: uint16(portNumber + 1), Priority: 1},
+			{Target: host + ".", Port: uint16(portNumber), Priority: 2},
+		}, nil
+	}
+	t.Cleanup(func() {
+		lookupSRV = net.DefaultResolver.LookupSRV
+	})
+
+	assert.Equal(t, "trino.invalid:8080", queryHost(t, "http://trino.invalid:8080?dial=srv://_trino._tcp.example.com"))
+	assert.Equal(t, []string{"_trino._tcp.example.com"}, names)
+
+	db, err := sql.Open("trino", "http://trino.invalid:8080?dial=srv://_missing._tcp.example.com")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	_, err = db.Exec("SELECT 1")
+	assert.ErrorContains(t, err, "no such host")
+}
+
+func TestDialInvalid(t *testing.T) {
+	for _, dial := range []string{
+		"localhost:8080",
+		"unix://",
+		"tcp://localhost",
+		"tcp://localhost:8080/path",
+		"srv://",
+		"http://localhost:8080",
+	} {
+		_, err := newConn("http://localhost:8080?dial=" + url.QueryEscape(dial))
+		assert.ErrorContains(t, err, "invalid dial value", dial)
+	}
+
+	RegisterCustomClient("dial", &http.Client{})
+	t.Cleanup(func() {
+		DeregisterCustomClient("dial")
+	})
+	_, err := newConn("http://localhost:8080?custom_client=dial&dial=tcp://localhost:8080")
+	assert.Error(t, err)
+}
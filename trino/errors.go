@@ -0,0 +1,116 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"fmt"
+
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+)
+
+// UnsupportedOperationError is returned when using a database/sql feature that isn't
+// supported by Trino or by the driver. It describes the missing capability, and what to
+// use instead. It matches ErrOperationNotSupported when using errors.Is.
+type UnsupportedOperationError struct {
+	// Operation is the unsupported operation or feature.
+	Operation string
+	// Alternative describes what to use instead, if anything.
+	Alternative string
+}
+
+// Error implements the error interface.
+func (e *UnsupportedOperationError) Error() string {
+	msg := ErrOperationNotSupported.Error() + ": " + e.Operation
+	if e.Alternative != "" {
+		msg += "; " + e.Alternative
+	}
+	return msg
+}
+
+// Is reports whether the target is ErrOperationNotSupported.
+func (e *UnsupportedOperationError) Is(target error) bool {
+	return target == ErrOperationNotSupported
+}
+
+// ErrQueryFailed indicates that a query to Trino failed.
+type ErrQueryFailed = protocol.ErrQueryFailed
+
+// ErrTrino is an error reported by the server for a query. Errors returned
+// for failed queries wrap it, so it can be retrieved using errors.As.
+type ErrTrino = protocol.ErrTrino
+
+// ErrorLocation is the location of an error in the text of a query.
+type ErrorLocation = protocol.ErrorLocation
+
+// FailureInfo describes the failure of a query, and its causes.
+type FailureInfo = protocol.FailureInfo
+
+// ErrorInfo identifies the error code of a failure.
+type ErrorInfo = protocol.ErrorInfo
+
+// errQueryCancelled is returned for queries cancelled by the user. It matches ErrQueryCancelled
+// when using errors.Is, and wraps the ErrTrino reported by the server.
+type errQueryCancelled struct {
+	reason *ErrTrino
+}
+
+func (e *errQueryCancelled) Error() string {
+	return ErrQueryCancelled.Error()
+}
+
+func (e *errQueryCancelled) Unwrap() []error {
+	return []error{ErrQueryCancelled, e.reason}
+}
+
+// ConversionError is returned when a value returned by the server
+// cannot be converted to a Go value.
+type ConversionError struct {
+	// Column is the name of the column.
+	Column string
+	// Type is the Trino type of the column.
+	Type string
+	// Row is the zero-based offset of the row in the query results.
+	Row int64
+	// Value is the value as decoded from the server response.
+	Value interface{}
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("trino: cannot convert value %v of column %q (%s) in row %d: %v", e.Value, e.Column, e.Type, e.Row, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// handleResponseError returns the error reported by the server for a query, if any,
+// which can always be retrieved as a *ErrTrino using errors.As.
+func handleResponseError(status int, queryID string, respErr ErrTrino) error {
+	respErr.QueryID = queryID
+	switch respErr.ErrorName {
+	case "":
+		return nil
+	case "USER_CANCELLED":
+		return &errQueryCancelled{reason: &respErr}
+	default:
+		return &ErrQueryFailed{
+			StatusCode: status,
+			Reason:     &respErr,
+		}
+	}
+}
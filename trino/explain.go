@@ -0,0 +1,562 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ExplainFormat is the format of a query plan returned by EXPLAIN.
+type ExplainFormat string
+
+const (
+	// ExplainText is the default format, a tree of plan nodes for every fragment.
+	ExplainText ExplainFormat = "TEXT"
+	// ExplainJSON is a JSON document with the plan nodes of every fragment.
+	ExplainJSON ExplainFormat = "JSON"
+	// ExplainGraphviz is a graph in the DOT language, which is not parsed.
+	ExplainGraphviz ExplainFormat = "GRAPHVIZ"
+)
+
+// QueryPlan is the distributed plan of a query.
+type QueryPlan struct {
+	// Text is the plan, as returned by the server.
+	Text string
+	// Fragments are the stages of the plan, ordered by ID.
+	// It's empty for the GRAPHVIZ format.
+	Fragments []PlanFragment
+}
+
+// PlanFragment is a fragment of a distributed query plan, executed by one stage of the query.
+type PlanFragment struct {
+	// ID is the ID of the fragment, which is the ID of the stage executing it.
+	ID string
+	// Partitioning is how the data processed by the fragment is partitioned, like SOURCE or HASH.
+	// It's only included in the TEXT format.
+	Partitioning string
+	// Root is the root node of the fragment.
+	Root *PlanNode
+
+	// CPUTime, ScheduledTime, InputRows and OutputRows are the statistics of the stage,
+	// only set by ExplainAnalyze.
+	CPUTime       time.Duration
+	ScheduledTime time.Duration
+	InputRows     int64
+	OutputRows    int64
+}
+
+// PlanNode is a node of a query plan.
+type PlanNode struct {
+	// ID is the ID of the node. It's only included in the JSON format.
+	ID string `json:"id"`
+	// Name is the type of the node, like TableScan or Aggregate.
+	Name string `json:"name"`
+	// Descriptor are the properties of the node, like the table of a TableScan.
+	Descriptor map[string]string `json:"descriptor"`
+	// Outputs are the symbols returned by the node.
+	Outputs []PlanSymbol `json:"outputs"`
+	// Details are the other properties of the node, like the expressions it computes,
+	// and the statistics of its execution from ExplainAnalyze.
+	Details []string `json:"details"`
+	// Estimates are the estimated statistics of the output of the node, with unknown values set to NaN.
+	Estimates []PlanEstimate `json:"estimates"`
+	// Children are the nodes which this node reads from.
+	Children []*PlanNode `json:"children"`
+}
+
+// PlanSymbol is a symbol returned by a plan node.
+type PlanSymbol struct {
+	Symbol string `json:"symbol"`
+	Type   string `json:"type"`
+}
+
+// PlanEstimate are the estimated statistics of the output of a plan node.
+type PlanEstimate struct {
+	OutputRowCount    float64
+	OutputSizeInBytes float64
+	CPUCost           float64
+	MemoryCost        float64
+	NetworkCost       float64
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, for estimates
+// which can be NaN, encoded as a string.
+func (e *PlanEstimate) UnmarshalJSON(b []byte) error {
+	var v struct {
+		OutputRowCount    estimateValue `json:"outputRowCount"`
+		OutputSizeInBytes estimateValue `json:"outputSizeInBytes"`
+		CPUCost           estimateValue `json:"cpuCost"`
+		MemoryCost        estimateValue `json:"memoryCost"`
+		NetworkCost       estimateValue `json:"networkCost"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = PlanEstimate{
+		OutputRowCount:    float64(v.OutputRowCount),
+		OutputSizeInBytes: float64(v.OutputSizeInBytes),
+		CPUCost:           float64(v.CPUCost),
+		MemoryCost:        float64(v.MemoryCost),
+		NetworkCost:       float64(v.NetworkCost),
+	}
+	return nil
+}
+
+type estimateValue float64
+
+func (v *estimateValue) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("trino: invalid estimate %q", s)
+		}
+		*v = estimateValue(f)
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*v = estimateValue(f)
+	return nil
+}
+
+// ExplainPlan returns the distributed plan of a query, without executing it,
+// for example to check it before running it. The query is wrapped in
+// EXPLAIN (FORMAT format), and args are passed as its arguments.
+func ExplainPlan(ctx context.Context, db *sql.DB, query string, format ExplainFormat, args ...interface{}) (*QueryPlan, error) {
+	switch format {
+	case ExplainText, ExplainJSON, ExplainGraphviz:
+	default:
+		return nil, fmt.Errorf("trino: unsupported explain format %q", format)
+	}
+	text, err := queryPlan(ctx, db, "EXPLAIN (FORMAT "+string(format)+") "+query, args)
+	if err != nil {
+		return nil, err
+	}
+	plan := &QueryPlan{Text: text}
+	switch format {
+	case ExplainText:
+		plan.Fragments = parseTextPlan(text)
+	case ExplainJSON:
+		plan.Fragments, err = parseJSONPlan(text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// ExplainAnalyze executes a query, and returns its distributed plan with the
+// statistics of its execution. The query is wrapped in EXPLAIN ANALYZE, and
+// args are passed as its arguments. Its results are discarded by the server.
+func ExplainAnalyze(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*QueryPlan, error) {
+	text, err := queryPlan(ctx, db, "EXPLAIN ANALYZE "+query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryPlan{Text: text, Fragments: parseTextPlan(text)}, nil
+}
+
+// RowEstimate is the estimated output of a query, and data it reads, from the
+// statistics of its tables. Unknown estimates are NaN.
+type RowEstimate struct {
+	// Rows and Bytes are the estimated number of rows and size of the output of the query.
+	Rows  float64
+	Bytes float64
+	// ScannedBytes is the estimated size of the data read from all the tables.
+	ScannedBytes float64
+	// Tables are the estimates of the data read from each table, after filtering
+	// its partitions with the constraints of the query.
+	Tables []TableEstimate
+}
+
+// TableEstimate is the estimated data read from a table by a query.
+type TableEstimate struct {
+	Catalog string
+	Schema  string
+	Table   string
+	Rows    float64
+	Bytes   float64
+}
+
+// EstimateRows returns the estimated number of rows of a query, and of bytes
+// it reads, without executing it, for example to reject expensive queries
+// before running them. It uses EXPLAIN (TYPE IO), and args are passed as its
+// arguments. The estimates are only as good as the statistics of the tables,
+// which ANALYZE collects, and are NaN when the server has none.
+func EstimateRows(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*RowEstimate, error) {
+	text, err := queryPlan(ctx, db, "EXPLAIN (TYPE IO, FORMAT JSON) "+query, args)
+	if err != nil {
+		return nil, err
+	}
+	return parseIOPlan(text)
+}
+
+func parseIOPlan(text string) (*RowEstimate, error) {
+	var plan struct {
+		InputTableColumnInfos []struct {
+			Table struct {
+				Catalog     string `json:"catalog"`
+				SchemaTable struct {
+					Schema string `json:"schema"`
+					Table  string `json:"table"`
+				} `json:"schemaTable"`
+			} `json:"table"`
+			Estimate PlanEstimate `json:"estimate"`
+		} `json:"inputTableColumnInfos"`
+		Estimate PlanEstimate `json:"estimate"`
+	}
+	if err := json.Unmarshal([]byte(text), &plan); err != nil {
+		return nil, fmt.Errorf("trino: invalid IO plan: %w", err)
+	}
+	estimate := &RowEstimate{
+		Rows:  plan.Estimate.OutputRowCount,
+		Bytes: plan.Estimate.OutputSizeInBytes,
+	}
+	for _, info := range plan.InputTableColumnInfos {
+		estimate.Tables = append(estimate.Tables, TableEstimate{
+			Catalog: info.Table.Catalog,
+			Schema:  info.Table.SchemaTable.Schema,
+			Table:   info.Table.SchemaTable.Table,
+			Rows:    info.Estimate.OutputRowCount,
+			Bytes:   info.Estimate.OutputSizeInBytes,
+		})
+		// NaN, if the size of any table is unknown
+		estimate.ScannedBytes += info.Estimate.OutputSizeInBytes
+	}
+	return estimate, nil
+}
+
+// validatePrefix is prepended to queries by ValidateQuery, on their first line.
+const validatePrefix = "EXPLAIN (TYPE VALIDATE) "
+
+// QueryValidationError is returned by ValidateQuery for queries rejected by
+// the server, like with a syntax error, or referencing a missing table.
+type QueryValidationError struct {
+	// Location is the position of the error in the validated query, with line
+	// and column numbers starting at 1, or zero if the server didn't report it.
+	Location ErrorLocation
+	// Err is the error reported by the server, like SYNTAX_ERROR or TABLE_NOT_FOUND.
+	Err *ErrTrino
+}
+
+// Error implements the error interface.
+func (e *QueryValidationError) Error() string {
+	if e.Location.LineNumber == 0 {
+		return "trino: invalid query: " + e.Err.Message
+	}
+	return fmt.Sprintf("trino: invalid query at line %d, column %d: %s", e.Location.LineNumber, e.Location.ColumnNumber, e.Err.Message)
+}
+
+// Unwrap returns the error reported by the server.
+func (e *QueryValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateQuery checks the syntax and the semantics of a query, like whether
+// its tables and columns exist and its expressions have valid types, without
+// executing it, for example to report errors in an editor. It uses
+// EXPLAIN (TYPE VALIDATE), and args are passed as its arguments. It returns a
+// *QueryValidationError with the location of the error in the query if the
+// server rejected it, or another error if it couldn't validate it.
+func ValidateQuery(ctx context.Context, db *sql.DB, query string, args ...interface{}) error {
+	var valid bool
+	err := db.QueryRowContext(ctx, validatePrefix+query, args...).Scan(&valid)
+	if err == nil {
+		if !valid {
+			return fmt.Errorf("trino: query %q was not validated", query)
+		}
+		return nil
+	}
+	var trinoErr *ErrTrino
+	if !errors.As(err, &trinoErr) || trinoErr.ErrorType != "USER_ERROR" {
+		return err
+	}
+	location := trinoErr.ErrorLocation
+	if location.LineNumber == 1 {
+		// errors in the prefix, if any, are reported at the start of the query
+		location.ColumnNumber = max(location.ColumnNumber-len(validatePrefix), 1)
+	}
+	return &QueryValidationError{Location: location, Err: trinoErr}
+}
+
+func queryPlan(ctx context.Context, db *sql.DB, query string, args []interface{}) (string, error) {
+	var text string
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+func parseJSONPlan(text string) ([]PlanFragment, error) {
+	var nodes map[string]*PlanNode
+	if err := json.Unmarshal([]byte(text), &nodes); err != nil {
+		return nil, fmt.Errorf("trino: invalid JSON plan: %w", err)
+	}
+	fragments := make([]PlanFragment, 0, len(nodes))
+	for id, node := range nodes {
+		fragments = append(fragments, PlanFragment{ID: id, Root: node})
+	}
+	sortFragments(fragments)
+	return fragments, nil
+}
+
+func sortFragments(fragments []PlanFragment) {
+	sort.Slice(fragments, func(i, j int) bool {
+		a, errA := strconv.Atoi(fragments[i].ID)
+		b, errB := strconv.Atoi(fragments[j].ID)
+		if errA != nil || errB != nil {
+			return fragments[i].ID < fragments[j].ID
+		}
+		return a < b
+	})
+}
+
+var (
+	fragmentHeaderRegexp = regexp.MustCompile(`^Fragment (\d+) \[(.*)\]$`)
+	planNodeRegexp       = regexp.MustCompile(`^([A-Z][A-Za-z]*)(?:\[(.*)\])?$`)
+	planEstimateRegexp   = regexp.MustCompile(`\{rows: (\S+) \(([^)]*)\), cpu: ([^,]+), memory: ([^,]+), network: ([^}]+)\}`)
+	stageTimesRegexp     = regexp.MustCompile(`^CPU: (\S+), Scheduled: (\S+),`)
+	stageInputRegexp     = regexp.MustCompile(`Input: ([\d.]+[KMBT]?) rows?\b`)
+	stageOutputRegexp    = regexp.MustCompile(`Output: ([\d.]+[KMBT]?) rows?\b`)
+)
+
+// parseTextPlan parses the fragments of a plan in the TEXT format. Nodes are
+// recognized by their position in the tree, and lines following a node are
+// its properties, until the next node.
+func parseTextPlan(text string) []PlanFragment {
+	type stackEntry struct {
+		column int
+		node   *PlanNode
+	}
+	var (
+		fragments []PlanFragment
+		fragment  *PlanFragment
+		stack     []stackEntry
+		current   *PlanNode
+	)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if m := fragmentHeaderRegexp.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			fragments = append(fragments, PlanFragment{ID: m[1], Partitioning: m[2]})
+			fragment = &fragments[len(fragments)-1]
+			stack, current = nil, nil
+			continue
+		}
+		if fragment == nil || strings.TrimSpace(line) == "" {
+			continue
+		}
+		column, content, branch := splitTreePrefix(line)
+		if m := planNodeRegexp.FindStringSubmatch(content); m != nil && (branch || fragment.Root == nil) {
+			node := &PlanNode{Name: m[1], Descriptor: parseDescriptor(m[2])}
+			for len(stack) > 0 && stack[len(stack)-1].column >= column {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				if fragment.Root != nil {
+					// not part of the tree of this fragment
+					continue
+				}
+				fragment.Root = node
+			} else {
+				parent := stack[len(stack)-1].node
+				parent.Children = append(parent.Children, node)
+			}
+			stack = append(stack, stackEntry{column: column, node: node})
+			current = node
+			continue
+		}
+		if current == nil {
+			parseStageStats(fragment, content)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(content, "Layout: "):
+			current.Outputs = parseLayout(strings.TrimPrefix(content, "Layout: "))
+		case strings.HasPrefix(content, "Estimates: "):
+			for _, m := range planEstimateRegexp.FindAllStringSubmatch(content, -1) {
+				current.Estimates = append(current.Estimates, PlanEstimate{
+					OutputRowCount:    parseEstimate(m[1]),
+					OutputSizeInBytes: parseEstimate(m[2]),
+					CPUCost:           parseEstimate(m[3]),
+					MemoryCost:        parseEstimate(m[4]),
+					NetworkCost:       parseEstimate(m[5]),
+				})
+			}
+		default:
+			current.Details = append(current.Details, content)
+		}
+	}
+	sortFragments(fragments)
+	return fragments
+}
+
+// splitTreePrefix returns the column at which the content of a line of a plan tree starts,
+// after the indentation and the lines of the tree, and if it's a branch of the tree.
+func splitTreePrefix(line string) (int, string, bool) {
+	column := 0
+	for len(line) > 0 {
+		r, size := utf8.DecodeRuneInString(line)
+		if r != ' ' && r != '│' {
+			break
+		}
+		line = line[size:]
+		column++
+	}
+	for _, marker := range []string{"└─ ", "├─ "} {
+		if strings.HasPrefix(line, marker) {
+			return column + utf8.RuneCountInString(marker), line[len(marker):], true
+		}
+	}
+	return column, line, false
+}
+
+func parseStageStats(fragment *PlanFragment, content string) {
+	if m := stageTimesRegexp.FindStringSubmatch(content); m != nil {
+		fragment.CPUTime = parsePlanDuration(m[1])
+		fragment.ScheduledTime = parsePlanDuration(m[2])
+	}
+	if m := stageInputRegexp.FindStringSubmatch(content); m != nil {
+		fragment.InputRows = parsePlanCount(m[1])
+	}
+	if m := stageOutputRegexp.FindStringSubmatch(content); m != nil {
+		fragment.OutputRows = parsePlanCount(m[1])
+	}
+}
+
+// splitTopLevel splits s on commas which are not nested in brackets or parentheses.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+func parseDescriptor(s string) map[string]string {
+	descriptor := make(map[string]string)
+	for _, part := range splitTopLevel(s) {
+		key, value, _ := strings.Cut(part, " = ")
+		descriptor[key] = value
+	}
+	return descriptor
+}
+
+func parseLayout(s string) []PlanSymbol {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	var symbols []PlanSymbol
+	for _, part := range splitTopLevel(s) {
+		symbol, typ, _ := strings.Cut(part, ":")
+		symbols = append(symbols, PlanSymbol{Symbol: symbol, Type: typ})
+	}
+	return symbols
+}
+
+var dataSizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"PB", 1 << 50},
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"kB", 1 << 10},
+	{"B", 1},
+}
+
+// parseEstimate parses an estimate, which can be a number or a data size, or NaN if it's unknown.
+func parseEstimate(s string) float64 {
+	s = strings.TrimSpace(s)
+	factor := 1.0
+	for _, unit := range dataSizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, factor = strings.TrimSuffix(s, unit.suffix), unit.factor
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f * factor
+}
+
+var durationUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"ns", time.Nanosecond},
+	{"us", time.Microsecond},
+	{"ms", time.Millisecond},
+	{"s", time.Second},
+	{"m", time.Minute},
+	{"h", time.Hour},
+	{"d", 24 * time.Hour},
+}
+
+// parsePlanDuration parses a duration formatted by Trino, like 1.23ms, or returns 0.
+func parsePlanDuration(s string) time.Duration {
+	for _, unit := range durationUnits {
+		if v, ok := strings.CutSuffix(s, unit.suffix); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			return time.Duration(f * float64(unit.unit))
+		}
+	}
+	return 0
+}
+
+// parsePlanCount parses a number of rows, which can have a K, M, B or T suffix for large numbers.
+func parsePlanCount(s string) int64 {
+	factor := 1.0
+	if i := strings.IndexAny(s, "KMBT"); i != -1 {
+		factor = map[byte]float64{'K': 1e3, 'M': 1e6, 'B': 1e9, 'T': 1e12}[s[i]]
+		s = s[:i]
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(f * factor))
+}
@@ -0,0 +1,377 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+const textPlan = `Fragment 0 [SINGLE]
+    Output layout: [regionkey, count]
+    Output partitioning: SINGLE []
+    Output[columnNames = [regionkey, _col1]]
+    │   Layout: [regionkey:bigint, count:bigint]
+    │   Estimates: {rows: 5 (90B), cpu: 1230, memory: 0B, network: 90B}
+    │   _col1 := count
+    └─ RemoteSource[sourceFragmentIds = [1]]
+           Layout: [regionkey:bigint, count:bigint]
+
+Fragment 1 [HASH]
+    Output layout: [regionkey, count]
+    Output partitioning: SINGLE []
+    Aggregate[type = FINAL, keys = [regionkey]]
+    │   Layout: [regionkey:bigint, count:bigint]
+    │   Estimates: {rows: ? (?), cpu: ?, memory: 1.5kB, network: 0B}
+    │   count := count("count_0")
+    ├─ LocalExchange[partitioning = HASH, arguments = ["regionkey"]]
+    │      Layout: [regionkey:bigint, count_0:bigint]
+    │      Estimates: {rows: 25 (450B), cpu: 675, memory: 0B, network: 0B}
+    │   └─ RemoteSource[sourceFragmentIds = [2]]
+    │          Layout: [regionkey:bigint, count_0:bigint]
+    └─ Values[]
+           Layout: [price:decimal(10,2)]
+           Estimates: {rows: 1 (9B), cpu: 0, memory: 0B, network: 0B}/{rows: 2 (18B), cpu: 0, memory: 0B, network: 0B}
+
+Fragment 2 [SOURCE]
+    Output layout: [regionkey, count_0]
+    Output partitioning: HASH [regionkey]
+    TableScan[table = tpch:tiny:nation]
+        Layout: [regionkey:bigint]
+        Estimates: {rows: 25 (225B), cpu: 225, memory: 0B, network: 0B}
+        regionkey := tpch:regionkey
+`
+
+const jsonPlan = `{
+   "1" : {
+      "id" : "4",
+      "name" : "TableScan",
+      "descriptor" : {
+         "table" : "tpch:tiny:nation"
+      },
+      "outputs" : [ {
+         "symbol" : "regionkey",
+         "type" : "bigint"
+      } ],
+      "details" : [ "regionkey := tpch:regionkey" ],
+      "estimates" : [ {
+         "outputRowCount" : 25.0,
+         "outputSizeInBytes" : 225.0,
+         "cpuCost" : 225.0,
+         "memoryCost" : 0.0,
+         "networkCost" : "NaN"
+      } ],
+      "children" : [ ]
+   },
+   "0" : {
+      "id" : "9",
+      "name" : "Output",
+      "descriptor" : {
+         "columnNames" : "[regionkey]"
+      },
+      "outputs" : [ {
+         "symbol" : "regionkey",
+         "type" : "bigint"
+      } ],
+      "details" : [ ],
+      "estimates" : [ ],
+      "children" : [ {
+         "id" : "10",
+         "name" : "RemoteSource",
+         "descriptor" : {
+            "sourceFragmentIds" : "[1]"
+         },
+         "outputs" : [ ],
+         "details" : [ ],
+         "estimates" : [ ],
+         "children" : [ ]
+      } ]
+   }
+}`
+
+const analyzePlan = `Queued: 332.72us, Analysis: 23.01ms, Planning: 41.64ms, Execution: 208.69ms
+Fragment 1 [SOURCE]
+    CPU: 2.45ms, Scheduled: 3.43ms, Blocked 0.00ns (Input: 0.00ns, Output: 0.00ns), Input: 25 rows (225B); per task: avg.: 25.00 std.dev.: 0.00, Output: 1.5K rows (9B)
+    Output layout: [count_0]
+    Output partitioning: SINGLE []
+    TableScan[table = tpch:tiny:nation]
+        Layout: [regionkey:bigint]
+        Estimates: {rows: 25 (225B), cpu: 225, memory: 0B, network: 0B}
+        CPU: 1.00ms (40.82%), Scheduled: 1.20ms (35.00%), Blocked: 0.00ns (?%), Output: 25 rows (225B)
+        Input avg.: 25.00 rows, Input std.dev.: 0.00%
+`
+
+func TestParseTextPlan(t *testing.T) {
+	fragments := parseTextPlan(textPlan)
+	require.Len(t, fragments, 3)
+
+	assert.Equal(t, "0", fragments[0].ID)
+	assert.Equal(t, "SINGLE", fragments[0].Partitioning)
+	output := fragments[0].Root
+	require.NotNil(t, output)
+	assert.Equal(t, "Output", output.Name)
+	assert.Equal(t, map[string]string{"columnNames": "[regionkey, _col1]"}, output.Descriptor)
+	assert.Equal(t, []PlanSymbol{{"regionkey", "bigint"}, {"count", "bigint"}}, output.Outputs)
+	assert.Equal(t, []string{"_col1 := count"}, output.Details)
+	assert.Equal(t, []PlanEstimate{{OutputRowCount: 5, OutputSizeInBytes: 90, CPUCost: 1230, MemoryCost: 0, NetworkCost: 90}}, output.Estimates)
+	require.Len(t, output.Children, 1)
+	assert.Equal(t, "RemoteSource", output.Children[0].Name)
+	assert.Equal(t, map[string]string{"sourceFragmentIds": "[1]"}, output.Children[0].Descriptor)
+
+	aggregate := fragments[1].Root
+	require.NotNil(t, aggregate)
+	assert.Equal(t, "HASH", fragments[1].Partitioning)
+	assert.Equal(t, "Aggregate", aggregate.Name)
+	assert.Equal(t, map[string]string{"type": "FINAL", "keys": "[regionkey]"}, aggregate.Descriptor)
+	require.Len(t, aggregate.Estimates, 1)
+	assert.True(t, math.IsNaN(aggregate.Estimates[0].OutputRowCount))
+	assert.Equal(t, 1.5*1024, aggregate.Estimates[0].MemoryCost)
+	require.Len(t, aggregate.Children, 2)
+	exchange := aggregate.Children[0]
+	assert.Equal(t, "LocalExchange", exchange.Name)
+	assert.Equal(t, 25.0, exchange.Estimates[0].OutputRowCount)
+	require.Len(t, exchange.Children, 1)
+	assert.Equal(t, "RemoteSource", exchange.Children[0].Name)
+	values := aggregate.Children[1]
+	assert.Equal(t, "Values", values.Name)
+	assert.Empty(t, values.Children)
+	assert.Equal(t, []PlanSymbol{{"price", "decimal(10,2)"}}, values.Outputs)
+	require.Len(t, values.Estimates, 2)
+	assert.Equal(t, 18.0, values.Estimates[1].OutputSizeInBytes)
+
+	scan := fragments[2].Root
+	require.NotNil(t, scan)
+	assert.Equal(t, "TableScan", scan.Name)
+	assert.Equal(t, "tpch:tiny:nation", scan.Descriptor["table"])
+	assert.Equal(t, []string{"regionkey := tpch:regionkey"}, scan.Details)
+}
+
+func TestParseAnalyzePlan(t *testing.T) {
+	fragments := parseTextPlan(analyzePlan)
+	require.Len(t, fragments, 1)
+	f := fragments[0]
+	assert.Equal(t, 2450*time.Microsecond, f.CPUTime)
+	assert.Equal(t, 3430*time.Microsecond, f.ScheduledTime)
+	assert.Equal(t, int64(25), f.InputRows)
+	assert.Equal(t, int64(1500), f.OutputRows)
+	require.NotNil(t, f.Root)
+	assert.Equal(t, "TableScan", f.Root.Name)
+	assert.Len(t, f.Root.Details, 2)
+}
+
+func TestParseJSONPlan(t *testing.T) {
+	fragments, err := parseJSONPlan(jsonPlan)
+	require.NoError(t, err)
+	require.Len(t, fragments, 2)
+	assert.Equal(t, "0", fragments[0].ID)
+	assert.Equal(t, "Output", fragments[0].Root.Name)
+	require.Len(t, fragments[0].Root.Children, 1)
+	assert.Equal(t, "RemoteSource", fragments[0].Root.Children[0].Name)
+
+	scan := fragments[1].Root
+	assert.Equal(t, "4", scan.ID)
+	assert.Equal(t, []PlanSymbol{{"regionkey", "bigint"}}, scan.Outputs)
+	require.Len(t, scan.Estimates, 1)
+	assert.Equal(t, 25.0, scan.Estimates[0].OutputRowCount)
+	assert.True(t, math.IsNaN(scan.Estimates[0].NetworkCost))
+
+	_, err = parseJSONPlan("not json")
+	assert.Error(t, err)
+}
+
+// planResponse returns the response of an EXPLAIN statement returning plan.
+func planResponse(plan string) trinomock.Response {
+	return trinomock.Response{
+		Columns: []trinomock.Column{{Name: "Query Plan", Type: "varchar"}},
+		Rows:    [][]interface{}{{plan}},
+	}
+}
+
+func TestExplainPlan(t *testing.T) {
+	server := newMockServer(t)
+	server.Handle("EXPLAIN (FORMAT JSON) SELECT 1", planResponse(jsonPlan))
+	server.HandleMatch(anyStatement, planResponse(textPlan))
+
+	db := openTestDB(t, server.DSN())
+	ctx := context.Background()
+
+	plan, err := ExplainPlan(ctx, db, "SELECT 1", ExplainText)
+	require.NoError(t, err)
+	assert.Equal(t, textPlan, plan.Text)
+	assert.Len(t, plan.Fragments, 3)
+
+	plan, err = ExplainPlan(ctx, db, "SELECT 1", ExplainJSON)
+	require.NoError(t, err)
+	assert.Len(t, plan.Fragments, 2)
+
+	plan, err = ExplainPlan(ctx, db, "SELECT 1", ExplainGraphviz)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Fragments)
+
+	plan, err = ExplainAnalyze(ctx, db, "SELECT 1")
+	require.NoError(t, err)
+	assert.Len(t, plan.Fragments, 3)
+
+	assert.Equal(t, []string{
+		"EXPLAIN (FORMAT TEXT) SELECT 1",
+		"EXPLAIN (FORMAT JSON) SELECT 1",
+		"EXPLAIN (FORMAT GRAPHVIZ) SELECT 1",
+		"EXPLAIN ANALYZE SELECT 1",
+	}, submittedBodies(server))
+
+	_, err = ExplainPlan(ctx, db, "SELECT 1", "XML")
+	assert.Error(t, err)
+}
+
+func TestValidateQuery(t *testing.T) {
+	server := newMockServer(t)
+	server.HandleMatch(func(statement string) bool { return strings.Contains(statement, "missing") }, trinomock.Response{
+		// the location of "missing" in the validated statement
+		Error: &trinomock.Error{
+			Message:      "line 1:39: Table 'memory.default.missing' does not exist",
+			ErrorName:    "TABLE_NOT_FOUND",
+			LineNumber:   1,
+			ColumnNumber: 39,
+		},
+	})
+	server.HandleMatch(func(statement string) bool { return strings.Contains(statement, "\n") }, trinomock.Response{
+		Error: &trinomock.Error{
+			Message:      "line 2:8: Column 'b' cannot be resolved",
+			ErrorName:    "COLUMN_NOT_FOUND",
+			LineNumber:   2,
+			ColumnNumber: 8,
+		},
+	})
+	server.HandleMatch(func(statement string) bool { return strings.Contains(statement, "broken") }, trinomock.Response{
+		Error: &trinomock.Error{Message: "broken", ErrorName: "GENERIC_INTERNAL_ERROR", ErrorType: "INTERNAL_ERROR"},
+	})
+	server.HandleMatch(anyStatement, trinomock.Response{
+		Columns: []trinomock.Column{{Name: "Valid", Type: "boolean"}},
+		Rows:    [][]interface{}{{true}},
+	})
+
+	db := openTestDB(t, server.DSN())
+	ctx := context.Background()
+
+	require.NoError(t, ValidateQuery(ctx, db, "SELECT 1"))
+	assert.Equal(t, []string{"EXPLAIN (TYPE VALIDATE) SELECT 1"}, submittedBodies(server))
+
+	err := ValidateQuery(ctx, db, "SELECT * FROM missing")
+	var validationErr *QueryValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, ErrorLocation{LineNumber: 1, ColumnNumber: 15}, validationErr.Location)
+	assert.Equal(t, "TABLE_NOT_FOUND", validationErr.Err.ErrorName)
+	assert.EqualError(t, err, "trino: invalid query at line 1, column 15: line 1:39: Table 'memory.default.missing' does not exist")
+	var trinoErr *ErrTrino
+	assert.ErrorAs(t, err, &trinoErr)
+
+	err = ValidateQuery(ctx, db, "SELECT a,\n       b FROM t")
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, ErrorLocation{LineNumber: 2, ColumnNumber: 8}, validationErr.Location)
+
+	err = ValidateQuery(ctx, db, "SELECT broken")
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &validationErr), "only user errors are validation errors")
+}
+
+const ioPlan = `{
+  "inputTableColumnInfos" : [ {
+    "table" : {
+      "catalog" : "hive",
+      "schemaTable" : {
+        "schema" : "tpch",
+        "table" : "orders"
+      }
+    },
+    "constraint" : {
+      "none" : false,
+      "columnConstraints" : [ ]
+    },
+    "estimate" : {
+      "outputRowCount" : 15000.0,
+      "outputSizeInBytes" : 1597500.0,
+      "cpuCost" : 1597500.0,
+      "maxMemory" : 0.0,
+      "networkCost" : 0.0
+    }
+  }, {
+    "table" : {
+      "catalog" : "hive",
+      "schemaTable" : {
+        "schema" : "tpch",
+        "table" : "customer"
+      }
+    },
+    "estimate" : {
+      "outputRowCount" : 1500.0,
+      "outputSizeInBytes" : 2500.0,
+      "cpuCost" : "NaN",
+      "maxMemory" : 0.0,
+      "networkCost" : 0.0
+    }
+  } ],
+  "estimate" : {
+    "outputRowCount" : 1500.0,
+    "outputSizeInBytes" : 27000.0,
+    "cpuCost" : 1600000.0,
+    "maxMemory" : 13500.0,
+    "networkCost" : 27000.0
+  }
+}`
+
+func TestEstimateRows(t *testing.T) {
+	server := newMockServer(t)
+	server.HandleMatch(func(statement string) bool { return strings.Contains(statement, "unknown") }, planResponse(
+		`{"inputTableColumnInfos":[{"table":{"catalog":"memory","schemaTable":{"schema":"default","table":"unknown"}},"estimate":{"outputRowCount":"NaN","outputSizeInBytes":"NaN"}}],"estimate":{"outputRowCount":"NaN","outputSizeInBytes":"NaN"}}`))
+	server.HandleMatch(anyStatement, planResponse(ioPlan))
+
+	db := openTestDB(t, server.DSN()+"?explicitPrepare=false")
+	ctx := context.Background()
+
+	estimate, err := EstimateRows(ctx, db, "SELECT * FROM orders JOIN customer USING (custkey) WHERE orderstatus = ?", "F")
+	require.NoError(t, err)
+	assert.Equal(t, &RowEstimate{
+		Rows:         1500,
+		Bytes:        27000,
+		ScannedBytes: 1600000,
+		Tables: []TableEstimate{
+			{Catalog: "hive", Schema: "tpch", Table: "orders", Rows: 15000, Bytes: 1597500},
+			{Catalog: "hive", Schema: "tpch", Table: "customer", Rows: 1500, Bytes: 2500},
+		},
+	}, estimate)
+
+	estimate, err = EstimateRows(ctx, db, "SELECT * FROM unknown")
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(estimate.Rows))
+	assert.True(t, math.IsNaN(estimate.ScannedBytes))
+	require.Len(t, estimate.Tables, 1)
+	assert.Equal(t, "unknown", estimate.Tables[0].Table)
+
+	assert.Equal(t, []string{
+		"EXECUTE IMMEDIATE 'EXPLAIN (TYPE IO, FORMAT JSON) SELECT * FROM orders JOIN customer USING (custkey) WHERE orderstatus = ?' USING 'F'",
+		"EXPLAIN (TYPE IO, FORMAT JSON) SELECT * FROM unknown",
+	}, submittedBodies(server))
+
+	_, err = parseIOPlan("not json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+)
+
+// Fuzz targets checking malformed server responses can't panic the driver, and
+// query arguments can't escape their literals.
+// Run them with:
+//
+//	go test -short -run '^$' -fuzz FuzzTypeSignature -fuzztime 1m ./trino
+
+// fuzzColumns are seeds of the column fuzz targets.
+var fuzzColumns = []string{
+	`{"name":"a","type":"bigint","typeSignature":{"rawType":"bigint","arguments":[]}}`,
+	`{"name":"a","type":"varchar(10)","typeSignature":{"rawType":"varchar","arguments":[{"kind":"LONG","value":10}]}}`,
+	`{"name":"a","type":"decimal(10,2)","typeSignature":{"rawType":"decimal","arguments":[{"kind":"LONG","value":10},{"kind":"LONG","value":2}]}}`,
+	`{"name":"a","type":"timestamp(3) with time zone","typeSignature":{"rawType":"timestamp with time zone","arguments":[{"kind":"LONG","value":3}]}}`,
+	`{"name":"a","type":"array(array(double))","typeSignature":{"rawType":"array","arguments":[{"kind":"TYPE","value":{"rawType":"array","arguments":[{"kind":"TYPE","value":{"rawType":"double","arguments":[]}}]}}]}}`,
+	`{"name":"a","type":"map(varchar, bigint)","typeSignature":{"rawType":"map","arguments":[{"kind":"TYPE","value":{"rawType":"varchar","arguments":[]}},{"kind":"TYPE","value":{"rawType":"bigint","arguments":[]}}]}}`,
+	`{"name":"a","type":"row(x integer)","typeSignature":{"rawType":"row","arguments":[{"kind":"NAMED_TYPE","value":{"fieldName":{"name":"x"},"typeSignature":{"rawType":"integer","arguments":[]}}}]}}`,
+	`{"name":"a","type":"array","typeSignature":{"rawType":"array","arguments":[]}}`,
+}
+
+// decodeFuzzColumn decodes a column, and returns its converter, if it's valid.
+func decodeFuzzColumn(data []byte) (*typeConverter, bool) {
+	var column queryColumn
+	if err := json.Unmarshal(data, &column); err != nil {
+		return nil, false
+	}
+	if err := protocol.UnmarshalArguments(&column.TypeSignature); err != nil {
+		return nil, false
+	}
+	converter, err := newTypeConverter(column.Type, column.TypeSignature)
+	if err != nil {
+		return nil, false
+	}
+	return converter, true
+}
+
+func FuzzTypeSignature(f *testing.F) {
+	for _, column := range fuzzColumns {
+		f.Add([]byte(column))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeFuzzColumn(data)
+	})
+}
+
+func FuzzConvertValue(f *testing.F) {
+	for _, column := range fuzzColumns {
+		for _, value := range []string{`null`, `1`, `"1.5"`, `"2024-01-02 03:04:05.123 UTC"`, `[[1.5, null]]`, `{"k":1}`, `[1]`, `"NaN"`} {
+			f.Add([]byte(column), []byte(value))
+		}
+	}
+	f.Fuzz(func(t *testing.T, column, value []byte) {
+		converter, ok := decodeFuzzColumn(column)
+		if !ok {
+			return
+		}
+		d := json.NewDecoder(bytes.NewReader(value))
+		d.UseNumber()
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			return
+		}
+		converter.ConvertValue(v)
+	})
+}
+
+func FuzzScanNullTime(f *testing.F) {
+	for _, s := range []string{
+		"2024-01-02",
+		"03:04:05.123456789",
+		"03:04:05+01:00",
+		"2024-01-02 03:04:05.123 UTC",
+		"2024-01-02 03:04:05 America/New_York",
+		"2024-01-02 23:59:60",
+		"2024-01-02 24:00:00",
+		"-2024-01-02 03:04:05 -05:00",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		scanNullTime(s)
+	})
+}
+
+func FuzzResponseHeaders(f *testing.F) {
+	for _, v := range []string{"120", "Wed, 21 Oct 2015 07:28:00 GMT", "stmt=SELECT+1", "=", "a=b=c"} {
+		f.Add(v)
+	}
+	now := time.Now()
+	f.Fuzz(func(t *testing.T, v string) {
+		parseRetryAfter(v, now)
+		c := &Conn{httpHeaders: make(http.Header), maxPreparedStatements: 2}
+		c.addPreparedStatement(v)
+		c.addPreparedStatement(v + "2")
+		c.removePreparedStatement(v)
+	})
+}
+
+func FuzzSerialString(f *testing.F) {
+	for _, s := range hostileStrings {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, v := range []interface{}{s, []string{s, s}} {
+			literal, err := Serial(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateLiteral(literal); err != nil {
+				t.Fatal(err)
+			}
+		}
+		literal, _ := Serial(s)
+		if unquoteString(literal) != s {
+			t.Fatalf("%q serialized as %q", s, literal)
+		}
+		if literal, err := Serial(Numeric(s)); err == nil && ValidateLiteral(literal) != nil {
+			t.Fatalf("Numeric %q serialized as %q", s, literal)
+		}
+		ValidateLiteral(s)
+	})
+}
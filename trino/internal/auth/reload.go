@@ -0,0 +1,139 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadingFile caches the content of a file, and reads it again when its
+// modification time or size changes, like when Kubernetes rotates a secret.
+type reloadingFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	content []byte
+}
+
+// read returns the content of the file, and whether it changed since the previous call.
+func (f *reloadingFile) read() ([]byte, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, false, err
+	}
+	if f.content != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.content, false, nil
+	}
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, false, err
+	}
+	f.modTime, f.size, f.content = info.ModTime(), info.Size(), content
+	return content, true, nil
+}
+
+// FileTokenSource is a TokenSource reading the access token from a file.
+type FileTokenSource struct {
+	file reloadingFile
+}
+
+var _ TokenSource = &FileTokenSource{}
+
+// NewFileTokenSource returns a TokenSource reading the access token from the
+// file at path, again when it changes.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{file: reloadingFile{path: path}}
+}
+
+// Token implements the TokenSource interface.
+func (s *FileTokenSource) Token(ctx context.Context) (string, error) {
+	content, _, err := s.file.read()
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", errors.New("trino: access token file " + s.file.path + " is empty")
+	}
+	return token, nil
+}
+
+// CertReloadingTransport is an http.RoundTripper trusting the CA certificates from a file,
+// and creating a new transport when the file changes.
+type CertReloadingTransport struct {
+	file reloadingFile
+	// dialContext dials the server, or is nil to dial the host of the request.
+	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	mu        sync.Mutex
+	transport *http.Transport
+}
+
+var _ http.RoundTripper = &CertReloadingTransport{}
+
+// NewCertReloadingTransport returns a transport trusting the CA certificates
+// from the file at path, dialing the server with dialContext if it's not nil.
+func NewCertReloadingTransport(path string, dialContext func(ctx context.Context, network, addr string) (net.Conn, error)) (*CertReloadingTransport, error) {
+	t := &CertReloadingTransport{file: reloadingFile{path: path}, dialContext: dialContext}
+	if _, err := t.currentTransport(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *CertReloadingTransport) currentTransport() (*http.Transport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cert, changed, err := t.file.read()
+	if err != nil {
+		return nil, err
+	}
+	if changed || t.transport == nil {
+		if t.transport != nil {
+			t.transport.CloseIdleConnections()
+		}
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM(cert)
+		t.transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs: certPool,
+			},
+			DialContext: t.dialContext,
+		}
+	}
+	return t.transport, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *CertReloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.currentTransport()
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
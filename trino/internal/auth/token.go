@@ -0,0 +1,155 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth provides the access tokens and certificates authenticating the
+// requests of the driver. The trino package has aliases for those it exposes,
+// like TokenSource.
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+)
+
+// ErrTokenExpired indicates that the access token, or the one returned by a TokenSource, is an expired JWT.
+var ErrTokenExpired = errors.New("trino: access token expired")
+
+// tokenExpiryDelta is how long before their expiration cached tokens are refreshed.
+const tokenExpiryDelta = 10 * time.Second
+
+// TokenSource provides access tokens sent in the Authorization header of every request.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx context.Context) (string, error)
+}
+
+// ClientCredentials is a TokenSource obtaining access tokens using the OAuth 2.0
+// client credentials grant, supported by identity providers like Azure AD (Microsoft Entra ID).
+// Tokens are cached, and refreshed shortly before they expire.
+type ClientCredentials struct {
+	TokenURL     string   // URL of the token endpoint
+	ClientID     string   // Client ID
+	ClientSecret string   // Client secret
+	Scopes       []string // Requested scopes (optional)
+
+	// HTTPClient used to request tokens. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+var _ TokenSource = &ClientCredentials{}
+
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	TokenType   string      `json:"token_type"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+// Token implements the TokenSource interface.
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Add(tokenExpiryDelta).Before(c.expiry) {
+		return c.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+	}
+	if len(c.Scopes) != 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("trino: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("trino: error requesting access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("trino: error requesting access token: %w", protocol.NewErrQueryFailedFromResponse(resp))
+	}
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", fmt.Errorf("trino: error decoding access token: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", errors.New("trino: token endpoint returned an empty access token")
+	}
+	c.token = tr.AccessToken
+	c.expiry = time.Time{}
+	if expiresIn, err := tr.ExpiresIn.Int64(); err == nil {
+		c.expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	} else if expiry, ok := jwtExpiry(tr.AccessToken); ok {
+		c.expiry = expiry
+	}
+	return c.token, nil
+}
+
+// jwtExpiry returns the expiration time from the exp claim of a JWT,
+// without verifying its signature. It returns false if token is not a JWT,
+// or has no exp claim.
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, false
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(exp)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
+
+// CheckTokenExpiry returns ErrTokenExpired if token is a JWT that has expired.
+func CheckTokenExpiry(token string) error {
+	if expiry, ok := jwtExpiry(token); ok && !time.Now().Before(expiry) {
+		return fmt.Errorf("%w at %s", ErrTokenExpired, expiry.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrQueryFailed indicates that a query to Trino failed.
+type ErrQueryFailed struct {
+	StatusCode int
+	Reason     error
+}
+
+// Error implements the error interface.
+func (e *ErrQueryFailed) Error() string {
+	return fmt.Sprintf("trino: query failed (%d %s): %q",
+		e.StatusCode, http.StatusText(e.StatusCode), e.Reason)
+}
+
+// Unwrap implements the unwrap interface.
+func (e *ErrQueryFailed) Unwrap() error {
+	return e.Reason
+}
+
+// NewErrQueryFailedFromResponse returns the error of a failed request, with its
+// status code, and the reason from the body of the response, which it closes.
+func NewErrQueryFailedFromResponse(resp *http.Response) *ErrQueryFailed {
+	const maxBytes = 8 * 1024
+	defer resp.Body.Close()
+	qf := &ErrQueryFailed{StatusCode: resp.StatusCode}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		qf.Reason = err
+		return qf
+	}
+	// some failures, like an unknown query, are reported in a JSON body
+	var qresp QueryResponse
+	if err := json.Unmarshal(b, &qresp); err == nil && qresp.Error.ErrorName != "" {
+		qresp.Error.QueryID = qresp.ID
+		qf.Reason = &qresp.Error
+		return qf
+	}
+	reason := string(b)
+	if resp.ContentLength > maxBytes {
+		reason += "..."
+	}
+	qf.Reason = errors.New(reason)
+	return qf
+}
+
+// ErrTrino is an error reported by the server for a query. Errors returned
+// for failed queries wrap it, so it can be retrieved using errors.As.
+type ErrTrino struct {
+	// QueryID is the ID of the failed query, if it was created.
+	QueryID       string        `json:"-"`
+	Message       string        `json:"message"`
+	SqlState      string        `json:"sqlState"`
+	ErrorCode     int           `json:"errorCode"`
+	ErrorName     string        `json:"errorName"`
+	ErrorType     string        `json:"errorType"`
+	ErrorLocation ErrorLocation `json:"errorLocation"`
+	FailureInfo   FailureInfo   `json:"failureInfo"`
+}
+
+func (i ErrTrino) Error() string {
+	return i.ErrorType + ": " + i.Message
+}
+
+// ErrorLocation is the location of an error in the text of a query.
+type ErrorLocation struct {
+	LineNumber   int `json:"lineNumber"`
+	ColumnNumber int `json:"columnNumber"`
+}
+
+// FailureInfo describes the failure of a query, and its causes.
+type FailureInfo struct {
+	Type          string        `json:"type"`
+	Message       string        `json:"message"`
+	Cause         *FailureInfo  `json:"cause"`
+	Suppressed    []FailureInfo `json:"suppressed"`
+	Stack         []string      `json:"stack"`
+	ErrorInfo     ErrorInfo     `json:"errorInfo"`
+	ErrorLocation ErrorLocation `json:"errorLocation"`
+}
+
+// ErrorInfo identifies the error code of a failure.
+type ErrorInfo struct {
+	Code int    `json:"code"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func (i ErrorInfo) Error() string {
+	return fmt.Sprintf("%s: %s (%d)", i.Type, i.Name, i.Code)
+}
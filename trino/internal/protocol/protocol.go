@@ -0,0 +1,199 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protocol defines the JSON messages of the Trino client REST API,
+// exchanged by the driver with the server. The trino package has aliases for
+// those it exposes, like ErrTrino.
+package protocol
+
+import "encoding/json"
+
+// StmtResponse is the response to the request submitting a statement.
+type StmtResponse struct {
+	ID          string    `json:"id"`
+	InfoURI     string    `json:"infoUri"`
+	NextURI     string    `json:"nextUri"`
+	Stats       StmtStats `json:"stats"`
+	Error       ErrTrino  `json:"error"`
+	UpdateType  string    `json:"updateType"`
+	UpdateCount int64     `json:"updateCount"`
+}
+
+// StmtStats are the statistics of a query, in every response.
+type StmtStats struct {
+	State                string    `json:"state"`
+	Queued               bool      `json:"queued"`
+	Scheduled            bool      `json:"scheduled"`
+	Nodes                int       `json:"nodes"`
+	TotalSplits          int       `json:"totalSplits"`
+	QueuesSplits         int       `json:"queuedSplits"`
+	RunningSplits        int       `json:"runningSplits"`
+	CompletedSplits      int       `json:"completedSplits"`
+	UserTimeMillis       int       `json:"userTimeMillis"`
+	CPUTimeMillis        int64     `json:"cpuTimeMillis"`
+	WallTimeMillis       int64     `json:"wallTimeMillis"`
+	QueuedTimeMillis     int64     `json:"queuedTimeMillis"`
+	ElapsedTimeMillis    int64     `json:"elapsedTimeMillis"`
+	ProcessedRows        int64     `json:"processedRows"`
+	ProcessedBytes       int64     `json:"processedBytes"`
+	PhysicalInputBytes   int64     `json:"physicalInputBytes"`
+	PhysicalWrittenBytes int64     `json:"physicalWrittenBytes"`
+	PeakMemoryBytes      int64     `json:"peakMemoryBytes"`
+	SpilledBytes         int64     `json:"spilledBytes"`
+	RootStage            StmtStage `json:"rootStage"`
+	ProgressPercentage   float32   `json:"progressPercentage"`
+	RunningPercentage    float32   `json:"runningPercentage"`
+}
+
+// StmtStage are the statistics of a stage of a query.
+type StmtStage struct {
+	StageID         string      `json:"stageId"`
+	State           string      `json:"state"`
+	Done            bool        `json:"done"`
+	Nodes           int         `json:"nodes"`
+	TotalSplits     int         `json:"totalSplits"`
+	QueuedSplits    int         `json:"queuedSplits"`
+	RunningSplits   int         `json:"runningSplits"`
+	CompletedSplits int         `json:"completedSplits"`
+	UserTimeMillis  int         `json:"userTimeMillis"`
+	CPUTimeMillis   int         `json:"cpuTimeMillis"`
+	WallTimeMillis  int         `json:"wallTimeMillis"`
+	ProcessedRows   int         `json:"processedRows"`
+	ProcessedBytes  int         `json:"processedBytes"`
+	SubStages       []StmtStage `json:"subStages"`
+}
+
+// QueryResponse is a response with the next page of the results of a query.
+type QueryResponse struct {
+	ID               string        `json:"id"`
+	InfoURI          string        `json:"infoUri"`
+	PartialCancelURI string        `json:"partialCancelUri"`
+	NextURI          string        `json:"nextUri"`
+	Columns          []QueryColumn `json:"columns"`
+	Data             []QueryData   `json:"data"`
+	Stats            StmtStats     `json:"stats"`
+	Error            ErrTrino      `json:"error"`
+	UpdateType       string        `json:"updateType"`
+	UpdateCount      int64         `json:"updateCount"`
+
+	// Bytes is the size of the encoded response.
+	Bytes int64 `json:"-"`
+}
+
+// rawQueryResponse is a QueryResponse with values that are not decoded, used with trino.WithRawJSON.
+type rawQueryResponse struct {
+	QueryResponse
+	Data [][]json.RawMessage `json:"data"`
+}
+
+// DecodeRawQueryResponse decodes a QueryResponse, keeping its values as raw JSON, in []byte.
+func DecodeRawQueryResponse(d *json.Decoder, qresp *QueryResponse) error {
+	var raw rawQueryResponse
+	if err := d.Decode(&raw); err != nil {
+		return err
+	}
+	*qresp = raw.QueryResponse
+	if raw.Data == nil {
+		return nil
+	}
+	qresp.Data = make([]QueryData, len(raw.Data))
+	for i, row := range raw.Data {
+		qresp.Data[i] = make(QueryData, len(row))
+		for j, value := range row {
+			qresp.Data[i][j] = []byte(value)
+		}
+	}
+	return nil
+}
+
+// QueryColumn is a column of the results of a query.
+type QueryColumn struct {
+	Name          string        `json:"name"`
+	Type          string        `json:"type"`
+	TypeSignature TypeSignature `json:"TypeSignature"`
+}
+
+// QueryData is a row of the results of a query.
+type QueryData []interface{}
+
+// NamedTypeSignature is the type of a row field.
+type NamedTypeSignature struct {
+	FieldName     RowFieldName  `json:"fieldName"`
+	TypeSignature TypeSignature `json:"TypeSignature"`
+}
+
+// RowFieldName is the name of a row field.
+type RowFieldName struct {
+	Name string `json:"name"`
+}
+
+// TypeSignature is the type of a column, with its parameters.
+type TypeSignature struct {
+	RawType   string         `json:"rawType"`
+	Arguments []TypeArgument `json:"arguments"`
+}
+
+// TypeKind is the kind of a type argument.
+type TypeKind string
+
+// Kinds of type arguments.
+const (
+	KindType      = TypeKind("TYPE")
+	KindNamedType = TypeKind("NAMED_TYPE")
+	KindLong      = TypeKind("LONG")
+	KindVariable  = TypeKind("VARIABLE")
+)
+
+// TypeArgument is a parameter of a type.
+type TypeArgument struct {
+	// Kind determines if the TypeSignature, NamedTypeSignature, or Long field has a value
+	Kind  TypeKind        `json:"kind"`
+	Value json.RawMessage `json:"value"`
+	// TypeSignature decoded from Value when Kind is TYPE
+	TypeSignature TypeSignature `json:"-"`
+	// NamedTypeSignature decoded from Value when Kind is NAMED_TYPE
+	NamedTypeSignature NamedTypeSignature `json:"-"`
+	// Long decoded from Value when Kind is LONG
+	Long int64 `json:"-"`
+}
+
+// UnmarshalArguments decodes the values of the arguments of signature, and of
+// their own arguments.
+func UnmarshalArguments(signature *TypeSignature) error {
+	for i, argument := range signature.Arguments {
+		var payload interface{}
+		switch argument.Kind {
+		case KindType:
+			payload = &(signature.Arguments[i].TypeSignature)
+		case KindNamedType:
+			payload = &(signature.Arguments[i].NamedTypeSignature)
+		case KindLong:
+			payload = &(signature.Arguments[i].Long)
+		}
+		err := json.Unmarshal(argument.Value, payload)
+		if err != nil {
+			return err
+		}
+		switch argument.Kind {
+		case KindType:
+			err = UnmarshalArguments(&(signature.Arguments[i].TypeSignature))
+		case KindNamedType:
+			err = UnmarshalArguments(&(signature.Arguments[i].NamedTypeSignature.TypeSignature))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains code that was borrowed from prestgo, mainly some
+// data type definitions.
+//
+// See https://github.com/avct/prestgo for copyright information.
+//
+// The MIT License (MIT)
+//
+// Copyright (c) 2015 Avocet Systems Ltd.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package types converts the values of Trino types returned by the server,
+// like times and floating point numbers, and the arrays of them. The Null types
+// of the trino package scan values with it.
+package types
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInexactNumber indicates that a number returned by the server cannot be represented exactly by the Go type it's converted to.
+var ErrInexactNumber = errors.New("trino: number cannot be represented exactly")
+
+// ValidateMap returns an error if v is neither nil nor a map value.
+func ValidateMap(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		return fmt.Errorf("cannot convert %v (%T) to map", v, v)
+	}
+	return nil
+}
+
+// ValidateSlice returns an error if v is neither nil nor an array value.
+func ValidateSlice(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	if _, ok := v.([]interface{}); !ok {
+		return fmt.Errorf("cannot convert %v (%T) to slice", v, v)
+	}
+	return nil
+}
+
+// ScanNullBool converts a boolean value, which is invalid for null values.
+func ScanNullBool(v interface{}) (sql.NullBool, error) {
+	if v == nil {
+		return sql.NullBool{}, nil
+	}
+	vv, ok := v.(bool)
+	if !ok {
+		return sql.NullBool{},
+			fmt.Errorf("cannot convert %v (%T) to bool", v, v)
+	}
+	return sql.NullBool{Valid: true, Bool: vv}, nil
+}
+
+// ScanNullString converts a varchar or char value, which is invalid for null values.
+func ScanNullString(v interface{}) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	vv, ok := v.(string)
+	if !ok {
+		return sql.NullString{},
+			fmt.Errorf("cannot convert %v (%T) to string", v, v)
+	}
+	return sql.NullString{Valid: true, String: vv}, nil
+}
+
+// ScanNullInt64 converts an integer value, which is invalid for null values.
+func ScanNullInt64(v interface{}) (sql.NullInt64, error) {
+	if v == nil {
+		return sql.NullInt64{}, nil
+	}
+	vNumber, ok := v.(json.Number)
+	if !ok {
+		return sql.NullInt64{},
+			fmt.Errorf("cannot convert %v (%T) to int64", v, v)
+	}
+	vv, err := vNumber.Int64()
+	if err != nil {
+		return sql.NullInt64{},
+			fmt.Errorf("cannot convert %v (%T) to int64: %w", v, v, err)
+	}
+	return sql.NullInt64{Valid: true, Int64: vv}, nil
+}
+
+// ScanNullFloat64 converts a double value, including NaN and Infinity, which is invalid for null values.
+func ScanNullFloat64(v interface{}) (sql.NullFloat64, error) {
+	if v == nil {
+		return sql.NullFloat64{}, nil
+	}
+	vNumber, ok := v.(json.Number)
+	if ok {
+		vFloat, err := vNumber.Float64()
+		if err != nil {
+			return sql.NullFloat64{}, fmt.Errorf("cannot convert %v (%T) to float64: %w", vNumber, vNumber, err)
+		}
+		return sql.NullFloat64{Valid: true, Float64: vFloat}, nil
+	}
+	switch v {
+	case "NaN":
+		return sql.NullFloat64{Valid: true, Float64: math.NaN()}, nil
+	case "Infinity":
+		return sql.NullFloat64{Valid: true, Float64: math.Inf(+1)}, nil
+	case "-Infinity":
+		return sql.NullFloat64{Valid: true, Float64: math.Inf(-1)}, nil
+	default:
+		vString, ok := v.(string)
+		if !ok {
+			return sql.NullFloat64{}, fmt.Errorf("cannot convert %v (%T) to float64", v, v)
+		}
+		vFloat, err := strconv.ParseFloat(vString, 64)
+		if err != nil {
+			return sql.NullFloat64{}, fmt.Errorf("cannot convert %v (%T) to float64: %w", v, v, err)
+		}
+		return sql.NullFloat64{Valid: true, Float64: vFloat}, nil
+	}
+}
+
+// ScanFloat32 converts a real value to float32, or nil for null values.
+func ScanFloat32(v interface{}) (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	var s string
+	switch vv := v.(type) {
+	case json.Number:
+		s = vv.String()
+	case string:
+		s = vv
+	default:
+		return nil, fmt.Errorf("cannot convert %v (%T) to float32", v, v)
+	}
+	f, err := ParseFloat(s, 32)
+	if err != nil {
+		return nil, err
+	}
+	return float32(f), nil
+}
+
+// ParseFloat parses a number returned by the server, including NaN and Infinity,
+// and returns an error wrapping ErrInexactNumber if it's out of range for a float
+// of the given size, or if it has more significant digits than a float32 can hold.
+func ParseFloat(s string, bitSize int) (float64, error) {
+	f, err := strconv.ParseFloat(s, bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%w: %s is out of range for float%d", ErrInexactNumber, s, bitSize)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert %v to float%d: %w", s, bitSize, err)
+	}
+	if bitSize == 32 && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		exact, _ := strconv.ParseFloat(s, 64)
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', -1, 32), 64)
+		if rounded != exact {
+			return 0, fmt.Errorf("%w: %s as float32", ErrInexactNumber, s)
+		}
+	}
+	return f, nil
+}
+
+// Layout for time and timestamp WITHOUT time zone.
+// Trino can support up to 12 digits sub second precision, but Go only 9.
+// (Requires X-Trino-Client-Capabilities: PARAMETRIC_DATETIME)
+var timeLayouts = []string{
+	"2006-01-02",
+	"15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Layout for time and timestamp WITH time zone.
+// Trino can support up to 12 digits sub second precision, but Go only 9.
+// (Requires X-Trino-Client-Capabilities: PARAMETRIC_DATETIME)
+var timeLayoutsTZ = []string{
+	"15:04:05.999999999 -07:00",
+	"2006-01-02 15:04:05.999999999 -07:00",
+	"15:04:05.999999999 -07:00:00",
+	"2006-01-02 15:04:05.999999999 -07:00:00",
+}
+
+// timeOfDayRegexp matches the time of day in time and timestamp values,
+// which is always before any time zone offset.
+var timeOfDayRegexp = regexp.MustCompile(`(\d{2}):(\d{2}):(\d{2})(\.\d+)?`)
+
+// normalizeTimeOfDay replaces a leap second, like 23:59:60, with the previous second,
+// and the end of day, 24:00:00, with the start of the same day. The caller must then
+// add one second or one day to the parsed value.
+func normalizeTimeOfDay(v string) (normalized string, leapSecond, endOfDay bool, err error) {
+	m := timeOfDayRegexp.FindStringSubmatchIndex(v)
+	if m == nil {
+		return v, false, false, nil
+	}
+	hour, minute, second := v[m[2]:m[3]], v[m[4]:m[5]], v[m[6]:m[7]]
+	switch {
+	case hour == "24":
+		if minute != "00" || second != "00" || (m[8] != -1 && strings.Trim(v[m[8]+1:m[9]], "0") != "") {
+			return "", false, false, fmt.Errorf("cannot convert %v to time, hour out of range", v)
+		}
+		return v[:m[2]] + "00" + v[m[3]:], false, true, nil
+	case second == "60":
+		return v[:m[6]] + "59" + v[m[7]:], true, false, nil
+	}
+	return v, false, false, nil
+}
+
+// yearRegexp matches the year of dates and timestamps, with an optional sign.
+var yearRegexp = regexp.MustCompile(`^([+-]?)(\d+)-\d{2}-\d{2}`)
+
+// normalizeYear replaces a year after 9999, like in +10000-01-01 or 10000-01-01,
+// or a negative year, like in -0044-03-15, which time.Parse doesn't support, with
+// the year of the same 400-year cycle of the Gregorian calendar between 2000 and
+// 2399, which has the same leap years. The caller must then add the returned number
+// of years to the parsed value. Like in ISO 8601, year 0 is 1 BC, and -1 is 2 BC.
+func normalizeYear(v string) (normalized string, years int, err error) {
+	m := yearRegexp.FindStringSubmatchIndex(v)
+	if m == nil {
+		return v, 0, nil
+	}
+	sign, digits := v[m[2]:m[3]], v[m[4]:m[5]]
+	if sign == "" && len(digits) == 4 {
+		return v, 0, nil
+	}
+	// dates of Trino are limited to about 5.8 million years
+	if len(digits) > 9 {
+		return "", 0, fmt.Errorf("cannot convert %v to time, year out of range", v)
+	}
+	year, err := strconv.Atoi(digits)
+	if err != nil {
+		return "", 0, err
+	}
+	if sign == "-" {
+		year = -year
+	}
+	cycleYear := 2000 + (year%400+400)%400
+	return strconv.Itoa(cycleYear) + v[m[5]:], year - cycleYear, nil
+}
+
+// CheckStrictTime returns an error if the time or timestamp value has a leap second or is 24:00:00.
+func CheckStrictTime(v interface{}) error {
+	vv, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	if _, leapSecond, endOfDay, err := normalizeTimeOfDay(vv); err != nil || leapSecond || endOfDay {
+		return fmt.Errorf("trino: time value %v is out of range", vv)
+	}
+	return nil
+}
+
+// ScanNullTime converts a date, time or timestamp value, which is invalid for null values.
+func ScanNullTime(v interface{}) (sql.NullTime, error) {
+	if v == nil {
+		return sql.NullTime{}, nil
+	}
+	vv, ok := v.(string)
+	if !ok {
+		return sql.NullTime{}, fmt.Errorf("cannot convert %v (%T) to time string", v, v)
+	}
+	vv, leapSecond, endOfDay, err := normalizeTimeOfDay(vv)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	vv, years, err := normalizeYear(vv)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	t, err := parseTimeString(vv)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	if years != 0 {
+		t.Time = t.Time.AddDate(years, 0, 0)
+	}
+	if leapSecond {
+		t.Time = t.Time.Add(time.Second)
+	}
+	if endOfDay {
+		t.Time = t.Time.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func parseTimeString(vv string) (sql.NullTime, error) {
+	vparts := strings.Split(vv, " ")
+	if last := vparts[len(vparts)-1]; len(vparts) > 1 && last != "" && !unicode.IsDigit(rune(last[0])) {
+		return parseNullTimeWithLocation(vv)
+	}
+	// Time literals may not have spaces before the timezone.
+	if strings.ContainsRune(vv, '+') {
+		return parseNullTimeWithLocation(strings.Replace(vv, "+", " +", 1))
+	}
+	hyphenCount := strings.Count(vv, "-")
+	// We need to ensure we don't treat the hyphens in dates as the minus offset sign.
+	// So if there's only one hyphen or more than 2, we have a negative offset.
+	if hyphenCount == 1 || hyphenCount > 2 {
+		// We add a space before the last hyphen to parse properly.
+		i := strings.LastIndex(vv, "-")
+		timestamp := vv[:i] + strings.Replace(vv[i:], "-", " -", 1)
+		return parseNullTimeWithLocation(timestamp)
+	}
+	return parseNullTime(vv)
+}
+
+func parseNullTime(v string) (sql.NullTime, error) {
+	var t time.Time
+	var err error
+	for _, layout := range timeLayouts {
+		t, err = time.ParseInLocation(layout, v, time.Local)
+		if err == nil {
+			return sql.NullTime{Valid: true, Time: t}, nil
+		}
+	}
+	return sql.NullTime{}, err
+}
+
+func parseNullTimeWithLocation(v string) (sql.NullTime, error) {
+	idx := strings.LastIndex(v, " ")
+	if idx == -1 {
+		return sql.NullTime{}, fmt.Errorf("cannot convert %v (%T) to time+zone", v, v)
+	}
+	stamp, location := v[:idx], v[idx+1:]
+	var t time.Time
+	var err error
+	// Try offset timezones.
+	if strings.HasPrefix(location, "+") || strings.HasPrefix(location, "-") {
+		for _, layout := range timeLayoutsTZ {
+			t, err = time.Parse(layout, v)
+			if err == nil {
+				return sql.NullTime{Valid: true, Time: t}, nil
+			}
+		}
+		return sql.NullTime{}, err
+	}
+	loc, err := time.LoadLocation(location)
+	// Not a named location.
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("cannot load timezone %q: %v", location, err)
+	}
+
+	for _, layout := range timeLayouts {
+		t, err = time.ParseInLocation(layout, stamp, loc)
+		if err == nil {
+			return sql.NullTime{Valid: true, Time: t}, nil
+		}
+	}
+	return sql.NullTime{}, err
+}
+
+// SplitPicoseconds removes the fractional digits beyond nanoseconds from a time
+// or timestamp string, and returns them as picoseconds.
+func SplitPicoseconds(v string) (string, int, error) {
+	dot := strings.IndexByte(v, '.')
+	if dot == -1 {
+		return v, 0, nil
+	}
+	end := dot + 1
+	for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+		end++
+	}
+	digits := v[dot+1 : end]
+	if len(digits) <= 9 {
+		return v, 0, nil
+	}
+	if len(digits) > 12 {
+		return "", 0, fmt.Errorf("cannot convert %v to time, too many fractional digits", v)
+	}
+	picos, err := strconv.Atoi((digits[9:] + "00")[:3])
+	if err != nil {
+		return "", 0, err
+	}
+	return v[:dot+1+9] + v[end:], picos, nil
+}
+
+// ScanSlice scans an array into a slice, converting its elements with scan.
+// Null arrays are scanned into an empty slice, and false.
+func ScanSlice[T any](value interface{}, typeName string, scan func(interface{}) (T, error)) ([]T, bool, error) {
+	if value == nil {
+		return []T{}, false, nil
+	}
+	vs, ok := value.([]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("trino: cannot convert %v (%T) to %s", value, value, typeName)
+	}
+	slice := make([]T, len(vs))
+	for i := range vs {
+		v, err := scan(vs[i])
+		if err != nil {
+			return nil, false, err
+		}
+		slice[i] = v
+	}
+	return slice, true, nil
+}
+
+// MapSlice returns the results of f for the elements of s.
+func MapSlice[T, U any](s []T, f func(T) U) []U {
+	slice := make([]U, len(s))
+	for i, v := range s {
+		slice[i] = f(v)
+	}
+	return slice
+}
@@ -0,0 +1,365 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Split selects a part of the rows of a query, like a range of its partitions.
+type Split struct {
+	// Predicate selects the rows of the split, like "id >= ? AND id < ?", or
+	// all of them if empty.
+	Predicate string
+	// Args are the arguments of the placeholders of Predicate.
+	Args []interface{}
+}
+
+// RangeSplits returns n splits of the rows of a query by the integer values
+// of column, which is an SQL expression, like a quoted identifier. The range
+// from min to max is divided in n ranges of the same size. The first split
+// also has the values lower than min, and the last one those higher than max,
+// and NULL, so that the splits select all the rows.
+func RangeSplits(column string, min, max int64, n int) []Split {
+	if n <= 1 || max <= min {
+		return []Split{{}}
+	}
+	// the span doesn't overflow as an unsigned integer
+	span := uint64(max) - uint64(min)
+	bounds := make([]int64, n-1)
+	for i := range bounds {
+		offset := span/uint64(n)*uint64(i+1) + span%uint64(n)*uint64(i+1)/uint64(n)
+		bounds[i] = int64(uint64(min) + offset)
+	}
+	splits := make([]Split, n)
+	splits[0] = Split{Predicate: column + " < ?", Args: []interface{}{bounds[0]}}
+	for i := 1; i < n-1; i++ {
+		splits[i] = Split{Predicate: column + " >= ? AND " + column + " < ?", Args: []interface{}{bounds[i-1], bounds[i]}}
+	}
+	splits[n-1] = Split{Predicate: column + " >= ? OR " + column + " IS NULL", Args: []interface{}{bounds[n-2]}}
+	return splits
+}
+
+// BucketSplits returns n splits of the rows of a query by the remainder of
+// the division of the integer values of column by n. column is an SQL
+// expression, like a quoted identifier, or "$bucket" for the bucket of the
+// rows of a bucketed Hive table, which the query must select. The first split
+// also has the rows with NULL values.
+func BucketSplits(column string, n int) []Split {
+	if n <= 1 {
+		return []Split{{}}
+	}
+	splits := make([]Split, n)
+	for i := range splits {
+		splits[i].Predicate = fmt.Sprintf("abs(mod(%s, %d)) = %d", column, n, i)
+	}
+	splits[0].Predicate += " OR " + column + " IS NULL"
+	return splits
+}
+
+// QueryParallel runs a query once for each split, with the predicate of the
+// split, and merges their rows, to read large results faster. The queries run
+// concurrently, with as many connections of the pool as there are splits, and
+// their rows are returned in no particular order.
+//
+// Predicates are applied to the columns of the query, which Trino pushes down
+// to the tables, so they should select the partitions of a partitioned table:
+//
+//	rows, err := trino.QueryParallel(ctx, db, "SELECT * FROM orders WHERE status = ?",
+//		trino.RangeSplits("orderkey", 0, 6_000_000, 8), "F")
+//
+// args are the arguments of the query, followed by those of each split.
+func QueryParallel(ctx context.Context, db *sql.DB, query string, splits []Split, args ...interface{}) (*ParallelRows, error) {
+	if len(splits) == 0 {
+		return nil, errors.New("trino: no splits to query")
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	r := &ParallelRows{
+		cancel:  cancel,
+		ready:   make(chan parallelRow),
+		started: make(chan struct{}),
+	}
+	var wg sync.WaitGroup
+	for _, split := range splits {
+		q := query
+		splitArgs := args
+		if split.Predicate != "" {
+			// new lines end comments at the end of the query
+			q = "SELECT * FROM (\n" + query + "\n) WHERE " + split.Predicate
+			splitArgs = append(append([]interface{}{}, args...), split.Args...)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.run(ctx, parent, db, q, splitArgs); err != nil {
+				r.fail(err)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		r.startOnce.Do(func() { close(r.started) })
+		close(r.ready)
+	}()
+	return r, nil
+}
+
+// ParallelRows are the merged rows of the queries of QueryParallel. Like
+// sql.Rows, they must be closed, unless Next returned false.
+type ParallelRows struct {
+	cancel context.CancelFunc
+	// ready receives the rows of the queries positioned on their next row,
+	// and is closed when all of them are done.
+	ready   chan parallelRow
+	current parallelRow
+	// started is closed when the first query started, or all of them failed.
+	started   chan struct{}
+	startOnce sync.Once
+	columns   []string
+
+	mu  sync.Mutex
+	err error
+}
+
+// parallelRow is the row of a query of QueryParallel, read by ParallelRows
+// until it sends on done.
+type parallelRow struct {
+	rows *sql.Rows
+	done chan struct{}
+}
+
+// run runs the query of a split, and passes each of its rows to Next. When ctx
+// is done, it returns the error of parent, the context of the caller, which is
+// nil if the queries were cancelled by Close, or after another one failed.
+func (r *ParallelRows) run(ctx, parent context.Context, db *sql.DB, query string, args []interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	r.startOnce.Do(func() {
+		r.columns, err = rows.Columns()
+		close(r.started)
+	})
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{}, 1)
+	for rows.Next() {
+		select {
+		case r.ready <- parallelRow{rows: rows, done: done}:
+		case <-ctx.Done():
+			return parent.Err()
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return parent.Err()
+		}
+	}
+	return rows.Err()
+}
+
+// fail records the first error of the queries, and cancels the others.
+func (r *ParallelRows) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// Columns returns the names of the columns of the query.
+func (r *ParallelRows) Columns() ([]string, error) {
+	<-r.started
+	if r.columns == nil {
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("trino: no query of the splits started")
+	}
+	return r.columns, nil
+}
+
+// Next prepares the next row of any of the queries for Scan. It returns false
+// when all the queries are done, or one of them failed, or the context of
+// QueryParallel is done, which Err returns.
+func (r *ParallelRows) Next() bool {
+	r.release()
+	row, ok := <-r.ready
+	if !ok {
+		r.cancel()
+		return false
+	}
+	if r.Err() != nil {
+		return false
+	}
+	r.current = row
+	return true
+}
+
+// release lets the query of the current row move to its next row.
+func (r *ParallelRows) release() {
+	if r.current.done != nil {
+		r.current.done <- struct{}{}
+		r.current = parallelRow{}
+	}
+}
+
+// Scan copies the columns of the current row into dest, like sql.Rows.Scan.
+func (r *ParallelRows) Scan(dest ...interface{}) error {
+	if r.current.rows == nil {
+		return errors.New("trino: Scan called without calling Next")
+	}
+	return r.current.rows.Scan(dest...)
+}
+
+// Err returns the first error of the queries.
+func (r *ParallelRows) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close cancels the queries still running, and waits for them to stop.
+func (r *ParallelRows) Close() error {
+	r.cancel()
+	r.release()
+	for range r.ready {
+	}
+	return nil
+}
+
+// Statement is a statement run by RunAll, with its arguments.
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
+
+// StatementResult is the result of a statement run by RunAll.
+type StatementResult struct {
+	// Progress is the last progress of the statement reported by the server,
+	// with its query ID, statistics and number of affected rows.
+	Progress ExecProgress
+	// Err is the error of the statement. It wraps context.Canceled if it was
+	// cancelled, or not run, after a fatal error of another statement.
+	Err error
+}
+
+// RunStats are the aggregated statistics of the statements run by RunAll.
+type RunStats struct {
+	// Succeeded, Failed and Cancelled are the numbers of statements which
+	// succeeded, failed, and were cancelled or not run.
+	Succeeded int
+	Failed    int
+	Cancelled int
+	// RowsAffected, ProcessedRows, ProcessedBytes and PhysicalWrittenBytes
+	// are the sums of those of the statements.
+	RowsAffected         int64
+	ProcessedRows        int64
+	ProcessedBytes       int64
+	PhysicalWrittenBytes int64
+	// Elapsed is the time it took to run all the statements.
+	Elapsed time.Duration
+}
+
+// RunAll executes statements concurrently, at most maxParallel at a time, or
+// all of them if it's 0, and returns their results, in the same order, with
+// their aggregated statistics, for example to run the steps of an ETL job
+// which don't depend on each other.
+//
+// Statements failing with a user error, like a syntax error or a missing
+// table, don't stop the others. Any other error, like an internal error of the
+// server or a connection error, is fatal: the statements still running are
+// cancelled, the remaining ones aren't run, and it's returned.
+func RunAll(ctx context.Context, db *sql.DB, statements []Statement, maxParallel int) ([]StatementResult, RunStats, error) {
+	start := time.Now()
+	if maxParallel <= 0 || maxParallel > len(statements) {
+		maxParallel = len(statements)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		results  = make([]StatementResult, len(statements))
+		mu       sync.Mutex
+		fatalErr error
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, maxParallel)
+	for i, statement := range statements {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Err = context.Canceled
+			continue
+		}
+		wg.Add(1)
+		go func(result *StatementResult, statement Statement) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			progressCtx := WithExecProgress(ctx, func(progress ExecProgress) {
+				result.Progress = progress
+			})
+			_, result.Err = db.ExecContext(progressCtx, statement.Query, statement.Args...)
+			if result.Err == nil || isUserError(result.Err) {
+				return
+			}
+			mu.Lock()
+			if fatalErr == nil && ctx.Err() == nil {
+				fatalErr = result.Err
+				cancel()
+			}
+			mu.Unlock()
+		}(&results[i], statement)
+	}
+	wg.Wait()
+	if fatalErr == nil {
+		// ctx was cancelled by the caller
+		fatalErr = ctx.Err()
+	}
+
+	stats := RunStats{Elapsed: time.Since(start)}
+	for _, result := range results {
+		switch {
+		case result.Err == nil:
+			stats.Succeeded++
+		case errors.Is(result.Err, context.Canceled):
+			stats.Cancelled++
+		default:
+			stats.Failed++
+		}
+		stats.RowsAffected += result.Progress.RowsAffected
+		stats.ProcessedRows += result.Progress.ProcessedRows
+		stats.ProcessedBytes += result.Progress.ProcessedBytes
+		stats.PhysicalWrittenBytes += result.Progress.PhysicalWrittenBytes
+	}
+	return results, stats, fatalErr
+}
+
+// isUserError reports whether err is a user error reported by the server for
+// a query, like a syntax error.
+func isUserError(err error) bool {
+	var trinoErr *ErrTrino
+	return errors.As(err, &trinoErr) && trinoErr.ErrorType == "USER_ERROR"
+}
@@ -0,0 +1,257 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeSplits(t *testing.T) {
+	assert.Equal(t, []Split{{}}, RangeSplits("id", 0, 100, 1))
+	assert.Equal(t, []Split{{}}, RangeSplits("id", 100, 0, 4))
+	assert.Equal(t, []Split{
+		{Predicate: "id < ?", Args: []interface{}{int64(33)}},
+		{Predicate: "id >= ? AND id < ?", Args: []interface{}{int64(33), int64(66)}},
+		{Predicate: "id >= ? OR id IS NULL", Args: []interface{}{int64(66)}},
+	}, RangeSplits("id", 0, 100, 3))
+
+	splits := RangeSplits(`"key"`, math.MinInt64, math.MaxInt64, 4)
+	require.Len(t, splits, 4)
+	assert.Equal(t, []interface{}{int64(math.MinInt64/2 - 1), int64(-1)}, splits[1].Args)
+	assert.Equal(t, []interface{}{int64(math.MaxInt64 / 2)}, splits[3].Args)
+}
+
+func TestBucketSplits(t *testing.T) {
+	assert.Equal(t, []Split{{}}, BucketSplits("id", 0))
+	assert.Equal(t, []Split{
+		{Predicate: `abs(mod("$bucket", 2)) = 0 OR "$bucket" IS NULL`},
+		{Predicate: `abs(mod("$bucket", 2)) = 1`},
+	}, BucketSplits(`"$bucket"`, 2))
+}
+
+// newSplitServer returns a server answering the queries of bucket splits with
+// two pages, with the bucket and the bucket plus n, and failing for the
+// bucket failed.
+func newSplitServer(t *testing.T, n, failed int) (*httptest.Server, func() []string) {
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+	bucketRegexp := regexp.MustCompile(`= (\d+)`)
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodPost:
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			mu.Lock()
+			queries = append(queries, string(b))
+			mu.Unlock()
+			bucket := bucketRegexp.FindStringSubmatch(string(b))[1]
+			if bucket == fmt.Sprint(failed) {
+				json.NewEncoder(w).Encode(&stmtResponse{ID: "failed", Error: ErrTrino{ErrorName: "TEST"}})
+				return
+			}
+			json.NewEncoder(w).Encode(&stmtResponse{ID: bucket, NextURI: ts.URL + "/v1/statement/" + bucket + "/1"})
+			return
+		}
+		var bucket, page int
+		_, err := fmt.Sscanf(r.URL.Path, "/v1/statement/%d/%d", &bucket, &page)
+		require.NoError(t, err)
+		value, next := bucket, fmt.Sprintf(`,"nextUri":"%s/v1/statement/%d/2"`, ts.URL, bucket)
+		if page == 2 {
+			value, next = bucket+n, ""
+		}
+		fmt.Fprintf(w, `{"id":"%d","columns":[{"name":"id","type":"integer","typeSignature":{"rawType":"integer","arguments":[]}}],"data":[[%d]]%s}`, bucket, value, next)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Strings(queries)
+		return queries
+	}
+}
+
+func TestQueryParallel(t *testing.T) {
+	ts, queries := newSplitServer(t, 3, -1)
+	db, err := sql.Open("trino", ts.URL+"?explicitPrepare=false")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	db.SetMaxOpenConns(2)
+
+	rows, err := QueryParallel(context.Background(), db, "SELECT id FROM t WHERE id > ? -- comment", BucketSplits("id", 3), -1)
+	require.NoError(t, err)
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, columns)
+	var ids []int
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	sort.Ints(ids)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, ids)
+	assert.Equal(t, []string{
+		"EXECUTE IMMEDIATE 'SELECT * FROM (\nSELECT id FROM t WHERE id > ? -- comment\n) WHERE abs(mod(id, 3)) = 0 OR id IS NULL' USING -1",
+		"EXECUTE IMMEDIATE 'SELECT * FROM (\nSELECT id FROM t WHERE id > ? -- comment\n) WHERE abs(mod(id, 3)) = 1' USING -1",
+		"EXECUTE IMMEDIATE 'SELECT * FROM (\nSELECT id FROM t WHERE id > ? -- comment\n) WHERE abs(mod(id, 3)) = 2' USING -1",
+	}, queries())
+
+	_, err = QueryParallel(context.Background(), db, "SELECT id FROM t", nil)
+	assert.Error(t, err)
+}
+
+func TestQueryParallelFailed(t *testing.T) {
+	ts, _ := newSplitServer(t, 4, 2)
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	rows, err := QueryParallel(context.Background(), db, "SELECT id FROM t", BucketSplits("id", 4))
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	var queryErr *ErrQueryFailed
+	require.ErrorAs(t, rows.Err(), &queryErr)
+	require.NoError(t, rows.Close())
+
+	// closing before reading all the rows stops the queries
+	rows, err = QueryParallel(context.Background(), db, "SELECT id FROM t", BucketSplits("id", 2))
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+	assert.False(t, rows.Next())
+}
+
+func TestQueryParallelDeadline(t *testing.T) {
+	ts, _ := newSplitServer(t, 4, -1)
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rows, err := QueryParallel(ctx, db, "SELECT id FROM t", BucketSplits("id", 4))
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	<-ctx.Done()
+	n := 1
+	for rows.Next() {
+		n++
+	}
+	assert.Less(t, n, 8)
+	assert.ErrorIs(t, rows.Err(), context.DeadlineExceeded, "the rows are truncated by the deadline of the caller")
+	require.NoError(t, rows.Close())
+}
+
+func TestRunAll(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodPost:
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			id := strings.ReplaceAll(string(b), " ", "_")
+			json.NewEncoder(w).Encode(&stmtResponse{ID: id, NextURI: ts.URL + "/v1/statement/" + id + "/1"})
+			return
+		}
+		resp := stmtResponse{ID: strings.Split(r.URL.Path, "/")[3]}
+		switch {
+		case strings.HasPrefix(resp.ID, "INSERT"):
+			resp.UpdateType, resp.UpdateCount = "INSERT", 5
+			resp.Stats = stmtStats{State: "FINISHED", ProcessedRows: 10, ProcessedBytes: 100}
+		case strings.HasPrefix(resp.ID, "MISSING"):
+			resp.Error = ErrTrino{ErrorName: "TABLE_NOT_FOUND", ErrorType: "USER_ERROR"}
+		case strings.HasPrefix(resp.ID, "BROKEN"):
+			resp.Error = ErrTrino{ErrorName: "GENERIC_INTERNAL_ERROR", ErrorType: "INTERNAL_ERROR"}
+		case strings.HasPrefix(resp.ID, "SLOW"):
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(&resp)
+	}))
+	t.Cleanup(ts.Close)
+
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	ctx := context.Background()
+
+	results, stats, err := RunAll(ctx, db, []Statement{{Query: "INSERT 1"}, {Query: "MISSING"}, {Query: "INSERT 2"}}, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "INSERT_1", results[0].Progress.QueryID)
+	assert.Equal(t, int64(5), results[0].Progress.RowsAffected)
+	var trinoErr *ErrTrino
+	require.ErrorAs(t, results[1].Err, &trinoErr)
+	assert.Equal(t, "TABLE_NOT_FOUND", trinoErr.ErrorName)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, 2, stats.Succeeded)
+	assert.Equal(t, 1, stats.Failed)
+	assert.Equal(t, 0, stats.Cancelled)
+	assert.Equal(t, int64(10), stats.RowsAffected)
+	assert.Equal(t, int64(20), stats.ProcessedRows)
+	assert.Equal(t, int64(200), stats.ProcessedBytes)
+
+	results, stats, err = RunAll(ctx, db, []Statement{{Query: "SLOW"}, {Query: "BROKEN"}, {Query: "INSERT 1"}, {Query: "INSERT 2"}}, 2)
+	require.ErrorAs(t, err, &trinoErr)
+	assert.Equal(t, "GENERIC_INTERNAL_ERROR", trinoErr.ErrorName)
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+	assert.Equal(t, err, results[1].Err)
+	assert.ErrorIs(t, results[2].Err, context.Canceled)
+	assert.ErrorIs(t, results[3].Err, context.Canceled)
+	assert.Equal(t, RunStats{Failed: 1, Cancelled: 3, Elapsed: stats.Elapsed}, stats)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, stats, err = RunAll(cancelled, db, []Statement{{Query: "INSERT 1"}}, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, stats.Cancelled)
+}
@@ -0,0 +1,606 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parquet writes the results of queries to files in the Apache Parquet
+// format, to export them to a data lake without running a separate job.
+//
+// The schema of the file is derived from the types of the columns of the query:
+//
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM orders WHERE orderdate = DATE '2024-01-01'")
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	f, err := os.Create("orders.parquet")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	n, err := parquet.Write(f, rows, &parquet.Options{Compression: parquet.Gzip})
+//
+// All columns are optional, with a flat schema. Values of ARRAY, MAP and ROW
+// columns are written as JSON strings, and values of types without an
+// equivalent Parquet type, like INTERVAL or UUID, as strings.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino"
+)
+
+// Compression is the codec compressing the pages of a Parquet file.
+type Compression int
+
+const (
+	// Uncompressed writes pages without compressing them.
+	Uncompressed Compression = iota
+	// Gzip compresses pages with gzip.
+	Gzip
+)
+
+// DefaultRowGroupSize is the default maximum number of rows of a row group.
+const DefaultRowGroupSize = 64 * 1024
+
+// Options configures how a Parquet file is written.
+type Options struct {
+	// RowGroupSize is the maximum number of rows of a row group, which are
+	// buffered in memory before being written. It defaults to DefaultRowGroupSize.
+	RowGroupSize int
+	// Compression is the codec compressing pages, which defaults to Uncompressed.
+	Compression Compression
+}
+
+var magic = []byte("PAR1")
+
+// Parquet physical types.
+const (
+	typeBoolean   = 0
+	typeInt32     = 1
+	typeInt64     = 2
+	typeFloat     = 4
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Parquet converted types, describing how to interpret physical types.
+const (
+	noConvertedType          = -1
+	convertedUTF8            = 0
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimeMicros      = 8
+	convertedTimestampMicros = 10
+	convertedInt8            = 15
+	convertedInt16           = 16
+	convertedJSON            = 19
+)
+
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	codecGzip         = 2
+
+	repetitionOptional = 1
+	pageTypeData       = 0
+)
+
+// Write writes all the rows to w as a Parquet file, and returns the number of rows written.
+// It doesn't close the rows.
+func Write(w io.Writer, rows *sql.Rows, opts *Options) (int64, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	rowGroupSize := opts.RowGroupSize
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	if opts.Compression != Uncompressed && opts.Compression != Gzip {
+		return 0, fmt.Errorf("parquet: unsupported compression %d", opts.Compression)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	fw := &fileWriter{w: w, compression: opts.Compression}
+	names := make(map[string]bool, len(types))
+	for _, t := range types {
+		if names[t.Name()] {
+			return 0, fmt.Errorf("parquet: duplicate column name %q", t.Name())
+		}
+		names[t.Name()] = true
+		fw.columns = append(fw.columns, newColumn(t))
+	}
+	if err := fw.write(magic); err != nil {
+		return 0, err
+	}
+
+	values := make([]interface{}, len(types))
+	dest := make([]interface{}, len(types))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var n int64
+	groupRows := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		for i, c := range fw.columns {
+			if err := c.add(values[i]); err != nil {
+				return n, fmt.Errorf("parquet: column %s: %w", c.name, err)
+			}
+		}
+		n++
+		groupRows++
+		if groupRows == rowGroupSize {
+			if err := fw.writeRowGroup(groupRows); err != nil {
+				return n, err
+			}
+			groupRows = 0
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	if groupRows != 0 {
+		if err := fw.writeRowGroup(groupRows); err != nil {
+			return n, err
+		}
+	}
+	return n, fw.writeFooter(n)
+}
+
+type fileWriter struct {
+	w           io.Writer
+	offset      int64
+	compression Compression
+	columns     []*column
+	rowGroups   []rowGroup
+}
+
+type rowGroup struct {
+	numRows       int64
+	totalByteSize int64
+	chunks        []columnChunk
+}
+
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+func (fw *fileWriter) write(b []byte) error {
+	n, err := fw.w.Write(b)
+	fw.offset += int64(n)
+	return err
+}
+
+// writeRowGroup writes the buffered values of all columns, in a single page per column.
+func (fw *fileWriter) writeRowGroup(numRows int) error {
+	group := rowGroup{numRows: int64(numRows)}
+	for _, c := range fw.columns {
+		data := c.page()
+		compressed := data
+		if fw.compression == Gzip {
+			var b bytes.Buffer
+			zw := gzip.NewWriter(&b)
+			if _, err := zw.Write(data); err != nil {
+				return err
+			}
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			compressed = b.Bytes()
+		}
+		if len(compressed) > math.MaxInt32 || len(data) > math.MaxInt32 {
+			return fmt.Errorf("parquet: column %s: page too large, use a smaller RowGroupSize", c.name)
+		}
+		header := encodeStruct(func(w *compactWriter) {
+			w.i32(1, pageTypeData)
+			w.i32(2, int32(len(data)))
+			w.i32(3, int32(len(compressed)))
+			w.structField(5, func() {
+				w.i32(1, int32(numRows))
+				w.i32(2, encodingPlain)
+				w.i32(3, encodingRLE)
+				w.i32(4, encodingRLE)
+			})
+		})
+		chunk := columnChunk{
+			offset:           fw.offset,
+			numValues:        int64(numRows),
+			uncompressedSize: int64(len(header) + len(data)),
+			compressedSize:   int64(len(header) + len(compressed)),
+		}
+		if err := fw.write(header); err != nil {
+			return err
+		}
+		if err := fw.write(compressed); err != nil {
+			return err
+		}
+		group.totalByteSize += chunk.uncompressedSize
+		group.chunks = append(group.chunks, chunk)
+		c.reset()
+	}
+	fw.rowGroups = append(fw.rowGroups, group)
+	return nil
+}
+
+func (fw *fileWriter) writeFooter(numRows int64) error {
+	codec := int32(codecUncompressed)
+	if fw.compression == Gzip {
+		codec = codecGzip
+	}
+	footer := encodeStruct(func(w *compactWriter) {
+		w.i32(1, 1)
+		w.structList(2, len(fw.columns)+1, func(i int) {
+			if i == 0 {
+				w.string(4, "schema")
+				w.i32(5, int32(len(fw.columns)))
+				return
+			}
+			c := fw.columns[i-1]
+			w.i32(1, c.physicalType)
+			w.i32(3, repetitionOptional)
+			w.string(4, c.name)
+			if c.convertedType != noConvertedType {
+				w.i32(6, c.convertedType)
+			}
+			if c.convertedType == convertedDecimal {
+				w.i32(7, int32(c.scale))
+				w.i32(8, int32(c.precision))
+			}
+		})
+		w.i64(3, numRows)
+		w.structList(4, len(fw.rowGroups), func(i int) {
+			group := fw.rowGroups[i]
+			w.structList(1, len(group.chunks), func(j int) {
+				chunk, c := group.chunks[j], fw.columns[j]
+				w.i64(2, chunk.offset)
+				w.structField(3, func() {
+					w.i32(1, c.physicalType)
+					w.i32List(2, encodingPlain, encodingRLE)
+					w.stringList(3, c.name)
+					w.i32(4, codec)
+					w.i64(5, chunk.numValues)
+					w.i64(6, chunk.uncompressedSize)
+					w.i64(7, chunk.compressedSize)
+					w.i64(9, chunk.offset)
+				})
+			})
+			w.i64(2, group.totalByteSize)
+			w.i64(3, group.numRows)
+		})
+		w.string(6, "trino-go-client")
+	})
+	if err := fw.write(footer); err != nil {
+		return err
+	}
+	if err := fw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return fw.write(magic)
+}
+
+// column buffers the values of a column for the current row group.
+type column struct {
+	name          string
+	physicalType  int32
+	convertedType int32
+	precision     int
+	scale         int
+	// encode appends the PLAIN encoding of a value that's not NULL.
+	encode func(c *column, v interface{}) error
+
+	valid  []bool
+	values []byte
+	bools  []bool
+}
+
+// typeParameters matches the innermost parameters of a type name, like (10,2).
+var typeParameters = regexp.MustCompile(`\([^()]*\)`)
+
+// newColumn returns a column with the Parquet type of a Trino type.
+func newColumn(t *sql.ColumnType) *column {
+	c := &column{name: t.Name(), physicalType: typeByteArray, convertedType: noConvertedType}
+	typeName := strings.ToUpper(t.DatabaseTypeName())
+	for typeParameters.MatchString(typeName) {
+		typeName = typeParameters.ReplaceAllString(typeName, "")
+	}
+	switch typeName {
+	case "BOOLEAN":
+		c.physicalType, c.encode = typeBoolean, encodeBoolean
+	case "TINYINT":
+		c.physicalType, c.convertedType, c.encode = typeInt32, convertedInt8, encodeInt32
+	case "SMALLINT":
+		c.physicalType, c.convertedType, c.encode = typeInt32, convertedInt16, encodeInt32
+	case "INTEGER":
+		c.physicalType, c.encode = typeInt32, encodeInt32
+	case "BIGINT":
+		c.physicalType, c.encode = typeInt64, encodeInt64
+	case "REAL":
+		c.physicalType, c.encode = typeFloat, encodeFloat
+	case "DOUBLE":
+		c.physicalType, c.encode = typeDouble, encodeDouble
+	case "DECIMAL":
+		if precision, scale, ok := t.DecimalSize(); ok {
+			c.convertedType, c.encode = convertedDecimal, encodeDecimal
+			c.precision, c.scale = int(precision), int(scale)
+		} else {
+			c.convertedType, c.encode = convertedUTF8, encodeString
+		}
+	case "DATE":
+		c.physicalType, c.convertedType, c.encode = typeInt32, convertedDate, encodeDate
+	case "TIME":
+		c.physicalType, c.convertedType, c.encode = typeInt64, convertedTimeMicros, encodeTime
+	case "TIMESTAMP", "TIMESTAMP WITH TIME ZONE":
+		c.physicalType, c.convertedType, c.encode = typeInt64, convertedTimestampMicros, encodeTimestamp
+	case "VARBINARY":
+		c.encode = encodeBinary
+	case "JSON":
+		c.convertedType, c.encode = convertedJSON, encodeString
+	case "ARRAY", "MAP", "ROW":
+		c.convertedType, c.encode = convertedJSON, encodeJSON
+	default:
+		c.convertedType, c.encode = convertedUTF8, encodeString
+	}
+	return c
+}
+
+func (c *column) add(v interface{}) error {
+	if v == nil {
+		c.valid = append(c.valid, false)
+		return nil
+	}
+	c.valid = append(c.valid, true)
+	return c.encode(c, v)
+}
+
+func (c *column) reset() {
+	c.valid = c.valid[:0]
+	c.values = c.values[:0]
+	c.bools = c.bools[:0]
+}
+
+// page returns the data of a page with all the buffered values: the definition
+// levels, with the RLE encoding, prefixed by their length, and the values.
+func (c *column) page() []byte {
+	var levels []byte
+	for i := 0; i < len(c.valid); {
+		j := i
+		for j < len(c.valid) && c.valid[j] == c.valid[i] {
+			j++
+		}
+		levels = binary.AppendUvarint(levels, uint64(j-i)<<1)
+		if c.valid[i] {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		i = j
+	}
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	b = append(b, levels...)
+	if c.physicalType == typeBoolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append(b, packed...)
+	}
+	return append(b, c.values...)
+}
+
+var errUnexpectedValue = errors.New("unexpected value")
+
+func unexpectedValue(v interface{}) error {
+	return fmt.Errorf("%w of type %T", errUnexpectedValue, v)
+}
+
+func encodeBoolean(c *column, v interface{}) error {
+	b, ok := v.(bool)
+	if !ok {
+		return unexpectedValue(v)
+	}
+	c.bools = append(c.bools, b)
+	return nil
+}
+
+func encodeInt32(c *column, v interface{}) error {
+	i, ok := v.(int64)
+	if !ok {
+		return unexpectedValue(v)
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return fmt.Errorf("value %d out of range", i)
+	}
+	c.values = binary.LittleEndian.AppendUint32(c.values, uint32(int32(i)))
+	return nil
+}
+
+func encodeInt64(c *column, v interface{}) error {
+	i, ok := v.(int64)
+	if !ok {
+		return unexpectedValue(v)
+	}
+	c.values = binary.LittleEndian.AppendUint64(c.values, uint64(i))
+	return nil
+}
+
+func encodeFloat(c *column, v interface{}) error {
+	switch f := v.(type) {
+	case float32:
+		c.values = binary.LittleEndian.AppendUint32(c.values, math.Float32bits(f))
+	case float64:
+		c.values = binary.LittleEndian.AppendUint32(c.values, math.Float32bits(float32(f)))
+	default:
+		return unexpectedValue(v)
+	}
+	return nil
+}
+
+func encodeDouble(c *column, v interface{}) error {
+	f, ok := v.(float64)
+	if !ok {
+		return unexpectedValue(v)
+	}
+	c.values = binary.LittleEndian.AppendUint64(c.values, math.Float64bits(f))
+	return nil
+}
+
+func appendByteArray(c *column, b []byte) {
+	c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(b)))
+	c.values = append(c.values, b...)
+}
+
+func encodeString(c *column, v interface{}) error {
+	switch s := v.(type) {
+	case string:
+		appendByteArray(c, []byte(s))
+	case []byte:
+		appendByteArray(c, s)
+	default:
+		appendByteArray(c, []byte(fmt.Sprint(v)))
+	}
+	return nil
+}
+
+func encodeBinary(c *column, v interface{}) error {
+	switch s := v.(type) {
+	case string:
+		// the server encodes VARBINARY values with base64
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		appendByteArray(c, b)
+	case []byte:
+		appendByteArray(c, s)
+	default:
+		return unexpectedValue(v)
+	}
+	return nil
+}
+
+func encodeJSON(c *column, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	appendByteArray(c, b)
+	return nil
+}
+
+func encodeDecimal(c *column, v interface{}) error {
+	s, ok := v.(string)
+	if !ok {
+		return unexpectedValue(v)
+	}
+	b, err := decimalBytes(s, c.scale)
+	if err != nil {
+		return err
+	}
+	appendByteArray(c, b)
+	return nil
+}
+
+// decimalBytes returns the unscaled value of a decimal, as a big-endian two's complement integer.
+func decimalBytes(s string, scale int) ([]byte, error) {
+	negative := strings.HasPrefix(s, "-")
+	intPart, fraction, _ := strings.Cut(strings.TrimLeft(s, "+-"), ".")
+	if len(fraction) > scale {
+		return nil, fmt.Errorf("decimal %s has more than %d fractional digits", s, scale)
+	}
+	var x big.Int
+	if _, ok := x.SetString(intPart+fraction+strings.Repeat("0", scale-len(fraction)), 10); !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	if !negative || x.Sign() == 0 {
+		b := x.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b, nil
+	}
+	// the bits of -x are the complement of the bits of x-1, x being the absolute value
+	x.Sub(&x, big.NewInt(1))
+	b := x.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	return b, nil
+}
+
+func timeValue(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case trino.PreciseTime:
+		return t.Time, nil
+	default:
+		return time.Time{}, unexpectedValue(v)
+	}
+}
+
+func encodeDate(c *column, v interface{}) error {
+	t, err := timeValue(v)
+	if err != nil {
+		return err
+	}
+	days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+	c.values = binary.LittleEndian.AppendUint32(c.values, uint32(int32(days)))
+	return nil
+}
+
+func encodeTime(c *column, v interface{}) error {
+	t, err := timeValue(v)
+	if err != nil {
+		return err
+	}
+	micros := int64(((t.Hour()*60+t.Minute())*60+t.Second())*1e6 + t.Nanosecond()/1e3)
+	c.values = binary.LittleEndian.AppendUint64(c.values, uint64(micros))
+	return nil
+}
+
+func encodeTimestamp(c *column, v interface{}) error {
+	t, err := timeValue(v)
+	if err != nil {
+		return err
+	}
+	c.values = binary.LittleEndian.AppendUint64(c.values, uint64(t.UnixMicro()))
+	return nil
+}
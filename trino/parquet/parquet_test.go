@@ -0,0 +1,276 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/trinodb/trino-go-client/trino"
+)
+
+const testColumns = `[
+	{"name":"id","type":"bigint","typeSignature":{"rawType":"bigint","arguments":[]}},
+	{"name":"name","type":"varchar","typeSignature":{"rawType":"varchar","arguments":[]}},
+	{"name":"active","type":"boolean","typeSignature":{"rawType":"boolean","arguments":[]}},
+	{"name":"price","type":"decimal(10,2)","typeSignature":{"rawType":"decimal","arguments":[{"kind":"LONG","value":10},{"kind":"LONG","value":2}]}},
+	{"name":"day","type":"date","typeSignature":{"rawType":"date","arguments":[]}},
+	{"name":"tags","type":"array(varchar)","typeSignature":{"rawType":"array","arguments":[{"kind":"TYPE","value":{"rawType":"varchar","arguments":[]}}]}}
+]`
+
+const testData = `[
+	[1, "a", true, "12.50", "1970-01-02", ["x", "y"]],
+	[2, null, false, "-0.01", null, null],
+	[null, "c", true, null, "2024-01-01", []]
+]`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			fmt.Fprintf(w, `{"id":"fake-query","nextUri":%q}`, ts.URL+"/v1/statement/fake-query/1")
+			return
+		}
+		fmt.Fprintf(w, `{"id":"fake-query","columns":%s,"data":%s}`, testColumns, testData)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestWrite(t *testing.T) {
+	for _, compression := range []Compression{Uncompressed, Gzip} {
+		t.Run(fmt.Sprint(compression), func(t *testing.T) {
+			db, err := sql.Open("trino", newTestServer(t).URL)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				assert.NoError(t, db.Close())
+			})
+			rows, err := db.Query("SELECT * FROM t")
+			require.NoError(t, err)
+			defer rows.Close()
+
+			var b bytes.Buffer
+			n, err := Write(&b, rows, &Options{RowGroupSize: 2, Compression: compression})
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), n)
+
+			file := b.Bytes()
+			require.Equal(t, "PAR1", string(file[:4]))
+			require.Equal(t, "PAR1", string(file[len(file)-4:]))
+			footerSize := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+			metadata := readStruct(t, bytes.NewReader(file[len(file)-8-footerSize:len(file)-8]))
+			assert.Equal(t, int64(3), metadata[3])
+
+			schema := metadata[2].([]interface{})
+			require.Len(t, schema, 7)
+			assert.Equal(t, "schema", string(schema[0].(map[int16]interface{})[4].([]byte)))
+			assert.Equal(t, int64(6), schema[0].(map[int16]interface{})[5])
+			for i, want := range []struct {
+				name          string
+				physicalType  int64
+				convertedType interface{}
+			}{
+				{"id", typeInt64, nil},
+				{"name", typeByteArray, int64(convertedUTF8)},
+				{"active", typeBoolean, nil},
+				{"price", typeByteArray, int64(convertedDecimal)},
+				{"day", typeInt32, int64(convertedDate)},
+				{"tags", typeByteArray, int64(convertedJSON)},
+			} {
+				element := schema[i+1].(map[int16]interface{})
+				assert.Equal(t, want.name, string(element[4].([]byte)))
+				assert.Equal(t, want.physicalType, element[1], want.name)
+				assert.Equal(t, want.convertedType, element[6], want.name)
+				assert.Equal(t, int64(repetitionOptional), element[3])
+			}
+			price := schema[4].(map[int16]interface{})
+			assert.Equal(t, int64(2), price[7])
+			assert.Equal(t, int64(10), price[8])
+
+			rowGroups := metadata[4].([]interface{})
+			require.Len(t, rowGroups, 2)
+			assert.Equal(t, int64(2), rowGroups[0].(map[int16]interface{})[3])
+			assert.Equal(t, int64(1), rowGroups[1].(map[int16]interface{})[3])
+
+			readColumn := func(column int) []interface{} {
+				var values []interface{}
+				for _, group := range rowGroups {
+					chunk := group.(map[int16]interface{})[1].([]interface{})[column].(map[int16]interface{})
+					meta := chunk[3].(map[int16]interface{})
+					offset := chunk[2].(int64)
+					assert.Equal(t, offset, meta[9])
+					r := bytes.NewReader(file[offset : offset+meta[7].(int64)])
+					header := readStruct(t, r)
+					page, err := io.ReadAll(r)
+					require.NoError(t, err)
+					require.Equal(t, header[3], int64(len(page)))
+					if compression == Gzip {
+						zr, err := gzip.NewReader(bytes.NewReader(page))
+						require.NoError(t, err)
+						page, err = io.ReadAll(zr)
+						require.NoError(t, err)
+					}
+					require.Equal(t, header[2], int64(len(page)))
+					numValues := int(header[5].(map[int16]interface{})[1].(int64))
+					values = append(values, decodePage(t, page, numValues, meta[1].(int64))...)
+				}
+				return values
+			}
+			assert.Equal(t, []interface{}{int64(1), int64(2), nil}, readColumn(0))
+			assert.Equal(t, []interface{}{"a", nil, "c"}, readColumn(1))
+			assert.Equal(t, []interface{}{true, false, true}, readColumn(2))
+			assert.Equal(t, []interface{}{"\x04\xe2", "\xff", nil}, readColumn(3))
+			assert.Equal(t, []interface{}{int64(1), nil, int64(19723)}, readColumn(4))
+			assert.Equal(t, []interface{}{`["x","y"]`, nil, `[]`}, readColumn(5))
+		})
+	}
+}
+
+func TestDecimalBytes(t *testing.T) {
+	for s, want := range map[string][]byte{
+		"0":       {0},
+		"1.27":    {0x7f},
+		"1.28":    {0, 0x80},
+		"-0.01":   {0xff},
+		"-1.28":   {0x80},
+		"-1.29":   {0xff, 0x7f},
+		"-0.00":   {0},
+		"655.35":  {0, 0xff, 0xff},
+		"-655.36": {0xff, 0x00, 0x00},
+	} {
+		b, err := decimalBytes(s, 2)
+		require.NoError(t, err)
+		assert.Equal(t, want, b, s)
+	}
+	_, err := decimalBytes("1.234", 2)
+	assert.Error(t, err)
+}
+
+// decodePage decodes the values of a data page with optional values.
+func decodePage(t *testing.T, page []byte, numValues int, physicalType int64) []interface{} {
+	levelsSize := binary.LittleEndian.Uint32(page)
+	levels := bytes.NewReader(page[4 : 4+levelsSize])
+	values := page[4+levelsSize:]
+	var defined []bool
+	for levels.Len() != 0 {
+		header, err := binary.ReadUvarint(levels)
+		require.NoError(t, err)
+		require.Zero(t, header&1, "bit-packed runs are not used")
+		value, err := levels.ReadByte()
+		require.NoError(t, err)
+		for i := uint64(0); i < header>>1; i++ {
+			defined = append(defined, value == 1)
+		}
+	}
+	require.Len(t, defined, numValues)
+	var result []interface{}
+	index := 0
+	for _, ok := range defined {
+		if !ok {
+			result = append(result, nil)
+			continue
+		}
+		switch physicalType {
+		case typeBoolean:
+			result = append(result, values[index/8]&(1<<(index%8)) != 0)
+		case typeInt32:
+			result = append(result, int64(int32(binary.LittleEndian.Uint32(values))))
+			values = values[4:]
+		case typeInt64:
+			result = append(result, int64(binary.LittleEndian.Uint64(values)))
+			values = values[8:]
+		case typeDouble:
+			result = append(result, math.Float64frombits(binary.LittleEndian.Uint64(values)))
+			values = values[8:]
+		case typeByteArray:
+			size := binary.LittleEndian.Uint32(values)
+			result = append(result, string(values[4:4+size]))
+			values = values[4+size:]
+		}
+		index++
+	}
+	return result
+}
+
+// readStruct decodes a struct encoded with the Thrift compact protocol, keyed by field ID.
+func readStruct(t *testing.T, r *bytes.Reader) map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		b, err := r.ReadByte()
+		require.NoError(t, err)
+		if b == 0 {
+			return fields
+		}
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(readVarint(t, r))
+		}
+		fields[id] = readValue(t, r, typ)
+	}
+}
+
+func readValue(t *testing.T, r *bytes.Reader, typ byte) interface{} {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case compactI32, compactI64:
+		return readVarint(t, r)
+	case compactBinary:
+		size, err := binary.ReadUvarint(r)
+		require.NoError(t, err)
+		b := make([]byte, size)
+		_, err = io.ReadFull(r, b)
+		require.NoError(t, err)
+		return b
+	case compactList:
+		header, err := r.ReadByte()
+		require.NoError(t, err)
+		size := uint64(header >> 4)
+		if size == 15 {
+			size, err = binary.ReadUvarint(r)
+			require.NoError(t, err)
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = readValue(t, r, header&0x0f)
+		}
+		return list
+	case compactStruct:
+		return readStruct(t, r)
+	default:
+		t.Fatalf("unexpected type %d", typ)
+		return nil
+	}
+}
+
+func readVarint(t *testing.T, r *bytes.Reader) int64 {
+	v, err := binary.ReadUvarint(r)
+	require.NoError(t, err)
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import "encoding/binary"
+
+// Types of the Thrift compact protocol, used to encode the metadata of Parquet files.
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter encodes Thrift structs with the compact protocol.
+type compactWriter struct {
+	b []byte
+	// last is the ID of the last field written in each of the nested structs.
+	last []int16
+}
+
+// encodeStruct returns the encoding of a struct, which fields are written by fn.
+func encodeStruct(fn func(w *compactWriter)) []byte {
+	w := &compactWriter{}
+	w.structBegin()
+	fn(w)
+	w.structEnd()
+	return w.b
+}
+
+func (w *compactWriter) structBegin() {
+	w.last = append(w.last, 0)
+}
+
+func (w *compactWriter) structEnd() {
+	w.b = append(w.b, 0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *compactWriter) field(id int16, typ byte) {
+	delta := id - w.last[len(w.last)-1]
+	if delta > 0 && delta <= 15 {
+		w.b = append(w.b, byte(delta)<<4|typ)
+	} else {
+		w.b = append(w.b, typ)
+		w.varint(int64(id))
+	}
+	w.last[len(w.last)-1] = id
+}
+
+// varint writes a zigzag encoded integer.
+func (w *compactWriter) varint(v int64) {
+	w.b = binary.AppendUvarint(w.b, uint64(v<<1)^uint64(v>>63))
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.field(id, compactI32)
+	w.varint(int64(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.field(id, compactI64)
+	w.varint(v)
+}
+
+func (w *compactWriter) string(id int16, v string) {
+	w.field(id, compactBinary)
+	w.b = binary.AppendUvarint(w.b, uint64(len(v)))
+	w.b = append(w.b, v...)
+}
+
+func (w *compactWriter) structField(id int16, fn func()) {
+	w.field(id, compactStruct)
+	w.structBegin()
+	fn()
+	w.structEnd()
+}
+
+func (w *compactWriter) listHeader(id int16, elemType byte, size int) {
+	w.field(id, compactList)
+	if size < 15 {
+		w.b = append(w.b, byte(size)<<4|elemType)
+	} else {
+		w.b = append(w.b, 0xf0|elemType)
+		w.b = binary.AppendUvarint(w.b, uint64(size))
+	}
+}
+
+func (w *compactWriter) i32List(id int16, values ...int32) {
+	w.listHeader(id, compactI32, len(values))
+	for _, v := range values {
+		w.varint(int64(v))
+	}
+}
+
+func (w *compactWriter) stringList(id int16, values ...string) {
+	w.listHeader(id, compactBinary, len(values))
+	for _, v := range values {
+		w.b = binary.AppendUvarint(w.b, uint64(len(v)))
+		w.b = append(w.b, v...)
+	}
+}
+
+// structList writes a list of size structs, which fields are written by fn.
+func (w *compactWriter) structList(id int16, size int, fn func(i int)) {
+	w.listHeader(id, compactStruct, size)
+	for i := 0; i < size; i++ {
+		w.structBegin()
+		fn(i)
+		w.structEnd()
+	}
+}
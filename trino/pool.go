@@ -0,0 +1,79 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// WarmUp opens n connections in the pool and validates each of them
+// by running a trivial query, so the cost of authentication (Kerberos, TLS, tokens)
+// is paid at startup instead of on the first user request.
+//
+// Connections are returned to the pool afterwards. Set db.SetMaxIdleConns
+// to at least n to keep all of them open. n is capped at the limit set with
+// db.SetMaxOpenConns, since WarmUp holds all the connections at once.
+func WarmUp(ctx context.Context, db *sql.DB, n int) error {
+	if n < 0 {
+		return fmt.Errorf("trino: invalid number of connections to warm up: %d", n)
+	}
+	if limit := db.Stats().MaxOpenConnections; limit > 0 && n > limit {
+		n = limit
+	}
+	conns := make([]*sql.Conn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			conns[i] = conn
+			errs[i] = validateConn(ctx, conn)
+		}(i)
+	}
+	wg.Wait()
+	for _, conn := range conns {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("trino: warm up failed: %w", err)
+	}
+	return nil
+}
+
+func validateConn(ctx context.Context, conn *sql.Conn) error {
+	if err := conn.PingContext(ctx); err != nil {
+		return err
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT 1")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmUp(t *testing.T) {
+	var queries atomic.Int32
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			queries.Add(1)
+			json.NewEncoder(w).Encode(&stmtResponse{
+				ID:      "fake-query",
+				NextURI: ts.URL + "/v1/statement/fake-query/1",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(&queryResponse{
+			ID: "fake-query",
+			Columns: []queryColumn{
+				{Name: "_col0", Type: "integer", TypeSignature: typeSignature{RawType: "integer"}},
+			},
+			Data: []queryData{{json.Number("1")}},
+		})
+	}))
+
+	t.Cleanup(ts.Close)
+
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+	db.SetMaxIdleConns(3)
+
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	require.NoError(t, WarmUp(context.Background(), db, 3))
+	assert.Equal(t, int32(3), queries.Load())
+	assert.Equal(t, 3, db.Stats().Idle)
+
+	db.SetMaxOpenConns(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, WarmUp(ctx, db, 5), "n is capped at the maximum number of open connections")
+	assert.Equal(t, int32(5), queries.Load())
+
+	assert.EqualError(t, WarmUp(context.Background(), db, -1), "trino: invalid number of connections to warm up: -1")
+}
+
+func TestWarmUpFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	t.Cleanup(ts.Close)
+
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	err = WarmUp(context.Background(), db, 2)
+	var qf *ErrQueryFailed
+	require.ErrorAs(t, err, &qf)
+	assert.Equal(t, http.StatusUnauthorized, qf.StatusCode)
+}
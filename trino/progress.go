@@ -0,0 +1,170 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"sync"
+	"time"
+)
+
+type QueryProgressInfo struct {
+	QueryId    string
+	QueryStats stmtStats
+	// BufferedBytes is the size of the result pages fetched by the client, but not yet consumed.
+	BufferedBytes int64
+}
+
+type queryProgressCallbackPeriod struct {
+	Period           time.Duration
+	LastCallbackTime time.Time
+	LastQueryState   string
+}
+
+type ProgressUpdater interface {
+	// Update the query progress, immediately when the query starts, when receiving data, and once when the query is finished.
+	Update(QueryProgressInfo)
+}
+
+// ProgressStarter is implemented by a ProgressUpdater to be called once the
+// server created a query, before any Update, with its ID and the URI of its
+// information, like to link to the web UI of the server.
+type ProgressStarter interface {
+	OnStarted(queryID, infoURI string)
+}
+
+// ProgressFinisher is implemented by a ProgressUpdater to be called once a
+// query started with OnStarted is done, after all its updates, with its last
+// progress and the error of the query. The error is nil if all the results
+// were read, and ErrQueryCancelled if the rows were closed before.
+type ProgressFinisher interface {
+	OnFinished(info QueryProgressInfo, err error)
+}
+
+type progressUpdate struct {
+	updater ProgressUpdater
+	info    QueryProgressInfo
+	// flushed is closed when all the updates sent before it are delivered, if set.
+	flushed chan struct{}
+	// call delivers an event instead of an update, if set.
+	call func()
+}
+
+// isUpdate returns true if u is an update of the progress of a query, instead of an event.
+func (u *progressUpdate) isUpdate() bool {
+	return u.flushed == nil && u.call == nil
+}
+
+// progressDispatcher delivers progress updates to their ProgressUpdater from a single
+// goroutine per connection, instead of one per query. Sending never blocks, so a slow
+// ProgressUpdater never blocks fetching results. Updates sent while the previous one
+// of the same query is still queued, in the same state, replace it, so the latest
+// progress and every change of state are delivered, while the queue only holds one
+// update per state of a query.
+type progressDispatcher struct {
+	mu      sync.Mutex
+	pending []progressUpdate
+	// wake is notified when updates are queued.
+	wake chan struct{}
+	done chan struct{}
+}
+
+func newProgressDispatcher() *progressDispatcher {
+	d := &progressDispatcher{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+func (d *progressDispatcher) run() {
+	for {
+		select {
+		case <-d.wake:
+		case <-d.done:
+			return
+		}
+		for {
+			d.mu.Lock()
+			if len(d.pending) == 0 {
+				d.mu.Unlock()
+				break
+			}
+			u := d.pending[0]
+			d.pending[0] = progressUpdate{}
+			d.pending = d.pending[1:]
+			d.mu.Unlock()
+			switch {
+			case u.flushed != nil:
+				close(u.flushed)
+			case u.call != nil:
+				u.call()
+			default:
+				u.updater.Update(u.info)
+			}
+		}
+	}
+}
+
+// send queues an update, without blocking, replacing the last queued update of
+// the same query if its state didn't change. Queries are matched by their ID,
+// not by their ProgressUpdater, which may be a value of an uncomparable type.
+func (d *progressDispatcher) send(updater ProgressUpdater, info QueryProgressInfo) {
+	d.mu.Lock()
+	for i := len(d.pending) - 1; i >= 0 && info.QueryId != ""; i-- {
+		last := &d.pending[i]
+		if !last.isUpdate() || last.info.QueryId != info.QueryId {
+			continue
+		}
+		if last.info.QueryStats.State == info.QueryStats.State {
+			last.info = info
+			d.mu.Unlock()
+			return
+		}
+		break
+	}
+	d.push(progressUpdate{updater: updater, info: info})
+}
+
+// event queues a call to a ProgressStarter or ProgressFinisher, which is never dropped.
+func (d *progressDispatcher) event(call func()) {
+	d.mu.Lock()
+	d.push(progressUpdate{call: call})
+}
+
+// push queues an update, with d.mu held, and unlocks it.
+func (d *progressDispatcher) push(u progressUpdate) {
+	d.pending = append(d.pending, u)
+	d.mu.Unlock()
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// flush waits until all the queued updates are delivered.
+func (d *progressDispatcher) flush() {
+	flushed := make(chan struct{})
+	d.mu.Lock()
+	d.push(progressUpdate{flushed: flushed})
+	select {
+	case <-flushed:
+	case <-d.done:
+	}
+}
+
+func (d *progressDispatcher) close() {
+	close(d.done)
+}
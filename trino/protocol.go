@@ -0,0 +1,542 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino/internal/auth"
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+)
+
+func (c *Conn) newRequest(ctx context.Context, method, url string, body io.Reader, hs http.Header) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("trino: %w", err)
+	}
+
+	if c.kerberosEnabled {
+		if err := c.setSPNEGOHeader(req); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, v := range c.httpHeaders {
+		req.Header[k] = v
+	}
+	for k, v := range hs {
+		req.Header[k] = v
+	}
+
+	if c.tokenSource != nil {
+		token, err := c.tokenSource.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("trino: error getting access token: %w", err)
+		}
+		if err := auth.CheckTokenExpiry(token); err != nil {
+			return nil, err
+		}
+		req.Header.Set(authorizationHeader, getAuthorization(token))
+	} else if c.accessToken != "" {
+		if err := auth.CheckTokenExpiry(c.accessToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.auth != nil {
+		pass, _ := c.auth.Password()
+		req.SetBasicAuth(c.auth.Username(), pass)
+	}
+	if c.authorizationUser != "" {
+		if req.Header.Get(trinoOriginalUserHeader) == "" {
+			req.Header.Set(trinoOriginalUserHeader, req.Header.Get(trinoUserHeader))
+		}
+		req.Header.Set(trinoUserHeader, c.authorizationUser)
+	}
+	if err := c.checkHeaderSize(req.Header); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// setSPNEGOHeader sets the Kerberos authentication of a request to the host of its URL.
+func (c *Conn) setSPNEGOHeader(req *http.Request) error {
+	remoteServiceName := "trino"
+	if c.kerberosRemoteServiceName != "" {
+		remoteServiceName = c.kerberosRemoteServiceName
+	}
+	err := c.kerberosClient.SetSPNEGOHeader(req, remoteServiceName+"/"+req.URL.Hostname())
+	if err != nil {
+		return fmt.Errorf("error setting client SPNEGO header: %w", err)
+	}
+	return nil
+}
+
+// listHeaders are the headers which values are comma-separated lists, that the server
+// reads from all the repeated headers, so they can be split when they're too large.
+var listHeaders = []string{
+	trinoSessionHeader,
+	trinoExtraCredentialHeader,
+	trinoRoleHeader,
+	trinoResourceEstimateHeader,
+	preparedStatementHeader,
+}
+
+// checkHeaderSize validates the size of the headers of a request against maxHeaderValueSize,
+// splitting the values of listHeaders across repeated headers, and against maxHeaderSize.
+// The size of a header is the size of its lines, as sent by the HTTP client.
+func (c *Conn) checkHeaderSize(h http.Header) error {
+	if c.maxHeaderValueSize > 0 {
+		for k, values := range h {
+			if k == userAgentHeader {
+				// it's only informative, so it's truncated instead of failing the request
+				if len(values) == 1 && len(values[0]) > c.maxHeaderValueSize {
+					h[k] = []string{values[0][:c.maxHeaderValueSize]}
+				}
+				continue
+			}
+			if !slices.Contains(listHeaders, k) {
+				for _, v := range values {
+					if len(v) > c.maxHeaderValueSize {
+						return fmt.Errorf("%w: %s value is %d bytes, larger than the maximum of %d", ErrHeaderTooLarge, k, len(v), c.maxHeaderValueSize)
+					}
+				}
+				continue
+			}
+			split, err := splitHeaderValues(values, c.maxHeaderValueSize)
+			if err != nil {
+				return fmt.Errorf("%w: %s value %w", ErrHeaderTooLarge, k, err)
+			}
+			// the values may be shared with the connection's headers, so they're replaced, not modified
+			h[k] = split
+		}
+	}
+	if c.maxHeaderSize > 0 {
+		total, largest, largestSize := 0, "", 0
+		for k, values := range h {
+			size := 0
+			for _, v := range values {
+				size += len(k) + len(": ") + len(v) + len("\r\n")
+			}
+			total += size
+			if size > largestSize {
+				largest, largestSize = k, size
+			}
+		}
+		if total > c.maxHeaderSize {
+			return fmt.Errorf("%w: headers are %d bytes, larger than the maximum of %d, the largest is %s with %d bytes", ErrHeaderTooLarge, total, c.maxHeaderSize, largest, largestSize)
+		}
+	}
+	return nil
+}
+
+// splitHeaderValues splits comma-separated lists of header values into values of at most max bytes.
+func splitHeaderValues(values []string, max int) ([]string, error) {
+	var result []string
+	for _, v := range values {
+		if len(v) <= max {
+			result = append(result, v)
+			continue
+		}
+		var current string
+		for _, item := range strings.Split(v, ",") {
+			item = strings.TrimSpace(item)
+			if len(item) > max {
+				return nil, fmt.Errorf("item %.20q... is %d bytes, larger than the maximum of %d", item, len(item), max)
+			}
+			switch {
+			case current == "":
+				current = item
+			case len(current)+len(",")+len(item) <= max:
+				current += "," + item
+			default:
+				result = append(result, current)
+				current = item
+			}
+		}
+		if current != "" {
+			result = append(result, current)
+		}
+	}
+	return result, nil
+}
+
+// roundRobinCounter rotates the first host tried by connections using round-robin host selection.
+var roundRobinCounter atomic.Uint64
+
+// hostOrder returns the base URLs in the order in which they should be tried
+// to submit a query.
+func (c *Conn) hostOrder() []string {
+	if !c.roundRobin || len(c.baseURLs) < 2 {
+		return c.baseURLs
+	}
+	start := int((roundRobinCounter.Add(1) - 1) % uint64(len(c.baseURLs)))
+	return append(append([]string{}, c.baseURLs[start:]...), c.baseURLs[:start]...)
+}
+
+// recycleGeneration is incremented by RecycleConnections, so that connections
+// close their idle HTTP connections before their next query.
+var recycleGeneration atomic.Int64
+
+// RecycleConnections makes all the connections of the driver close their idle
+// HTTP connections before their next query, so that they resolve the host names
+// of the server again, for example after a coordinator failover changed their
+// addresses. Connections kept alive by the HTTP clients would keep using the
+// addresses they resolved when they were opened otherwise. The connection_max_age
+// parameter of the DSN recycles them periodically instead.
+//
+// The HTTP clients can be shared, like http.DefaultClient, which the driver
+// uses without a custom client or certificates, so their idle connections used
+// by other code are closed too.
+func RecycleConnections() {
+	recycleGeneration.Add(1)
+}
+
+// recycleConnections closes the idle HTTP connections of the client, if
+// RecycleConnections was called, or connectionMaxAge elapsed, since they
+// were last closed.
+func (c *Conn) recycleConnections() {
+	generation := recycleGeneration.Load()
+	if generation == c.recycledGeneration && (c.connectionMaxAge == 0 || time.Since(c.recycledAt) < c.connectionMaxAge) {
+		return
+	}
+	c.recycledGeneration = generation
+	c.recycledAt = time.Now()
+	c.httpClient.CloseIdleConnections()
+}
+
+// isConnectionError returns true if err is a failure to get any response from the server.
+func isConnectionError(err error) bool {
+	var qferr *ErrQueryFailed
+	return errors.As(err, &qferr) && qferr.StatusCode == 0 && !errors.Is(err, ErrRequestTimeout)
+}
+
+// maxConnectionResetRetries is the number of times a request polling for results,
+// or cancelling a query, is retried after the connection was reset.
+const maxConnectionResetRetries = 3
+
+// isConnectionReset reports whether a request failed because the connection was
+// reset or closed by the server, or a proxy, before it responded.
+func isConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withHTTPTimeout returns the request with the timeout of a single request, if set,
+// and a function releasing its resources, which reports whether the timeout expired.
+// The timeout includes reading the body of the response.
+func (c *Conn) withHTTPTimeout(req *http.Request) (*http.Request, func() bool) {
+	if c.httpTimeout <= 0 {
+		return req, func() bool { return false }
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.httpTimeout)
+	return req.WithContext(ctx), func() bool {
+		timedOut := ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil
+		cancel()
+		return timedOut
+	}
+}
+
+// releasingBody is a response body releasing the resources of its request when it's closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func() bool
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// checkURI validates that a URI returned by the server uses the same scheme
+// as the connection, and one of the allowed hosts.
+func (c *Conn) checkURI(uri string) error {
+	if c.allowedHosts == nil {
+		return nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("trino: server response contains an invalid URI: %w", err)
+	}
+	if !strings.HasPrefix(c.baseURL, u.Scheme+"://") {
+		return fmt.Errorf("%w: unexpected scheme %q", ErrDisallowedURI, u.Scheme)
+	}
+	for _, pattern := range c.allowedHosts {
+		if matchHost(strings.TrimSpace(pattern), u) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrDisallowedURI, u.Host)
+}
+
+// matchHost returns true if the host of u matches the pattern. Patterns without
+// a port match any port, and patterns starting with "*." match any subdomain.
+func matchHost(pattern string, u *url.URL) bool {
+	host := u.Hostname()
+	if _, _, err := net.SplitHostPort(pattern); err == nil {
+		host = u.Host
+	}
+	host, pattern = strings.ToLower(host), strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasPrefix(suffix, ".") {
+		return strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
+
+// redirectPolicy returns a redirect policy for the HTTP client, calling next, if set,
+// that sends the authentication of the original request again to redirect targets
+// matching the redirect hosts, since the client drops it on redirects to other
+// hosts. It's never sent to plain HTTP targets when redirecting from HTTPS.
+func (c *Conn) redirectPolicy(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if next != nil {
+			if err := next(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		original := via[0]
+		if original.URL.Scheme == "https" && req.URL.Scheme != "https" {
+			return nil
+		}
+		if !slices.ContainsFunc(c.redirectHosts, func(pattern string) bool {
+			pattern = strings.TrimSpace(pattern)
+			return pattern == "*" || matchHost(pattern, req.URL)
+		}) {
+			return nil
+		}
+		if c.kerberosEnabled {
+			// Kerberos tickets are only valid for the service of the host they were requested for
+			return c.setSPNEGOHeader(req)
+		}
+		if v := original.Header.Get(authorizationHeader); v != "" && req.Header.Get(authorizationHeader) == "" {
+			req.Header.Set(authorizationHeader, v)
+		}
+		return nil
+	}
+}
+
+// maxDelayBetweenRequests is the longest delay before retrying a request.
+const maxDelayBetweenRequests = float64(15 * time.Second)
+
+func (c *Conn) roundTrip(ctx context.Context, req *http.Request) (*http.Response, error) {
+	delay := 100 * time.Millisecond
+	// resets counts the requests which failed because the connection was reset
+	resets := 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			if req.GetBody != nil {
+				// The body of a retried request has already been consumed.
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("trino: %w", err)
+				}
+				req.Body = body
+			}
+			attempt, release := c.withHTTPTimeout(req)
+			resp, err := c.httpClient.Do(attempt)
+			if err != nil {
+				timedOut := release()
+				if req.Method == http.MethodPost {
+					// the server may have created the query, so it's not submitted again
+					if timedOut {
+						return nil, &ErrQueryFailed{Reason: fmt.Errorf("%w after %v: %w", ErrRequestTimeout, c.httpTimeout, err)}
+					}
+					return nil, &ErrQueryFailed{Reason: err}
+				}
+				if !timedOut {
+					if !isConnectionReset(err) || resets >= maxConnectionResetRetries {
+						return nil, &ErrQueryFailed{Reason: err}
+					}
+					resets++
+				}
+				// polling the same URI again, or cancelling a query again, has no other effect
+				timer.Reset(delay)
+				delay = time.Duration(math.Min(
+					float64(delay)*math.Phi,
+					maxDelayBetweenRequests,
+				))
+				continue
+			}
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+			switch resp.StatusCode {
+			case http.StatusOK:
+				for _, h := range responseToRequestHeaders {
+					if v := resp.Header.Get(h.src); v != "" {
+						c.httpHeaders.Set(h.dst, v)
+						c.sessionChanged(h.kind, "", v)
+					}
+				}
+				if v := resp.Header.Get(trinoAddedPrepareHeader); v != "" {
+					// Statements prepared with PREPARE are sent in the headers of all the next
+					// queries, so they're rejected with explicitPrepare=false, instead of
+					// accumulating. They're still kept when EXECUTE IMMEDIATE is used because
+					// of the server version, for EXECUTE to keep working.
+					if !c.useExplicitPrepare && !c.autoExplicitPrepare {
+						resp.Body.Close()
+						name, _ := splitSessionEntry(v)
+						return nil, fmt.Errorf("trino: statement %s prepared with PREPARE isn't kept with %s=false, run it with EXECUTE IMMEDIATE instead", name, explicitPrepareConfig)
+					}
+					if c.addPreparedStatement(v) {
+						name, statement := splitSessionEntry(v)
+						c.sessionChanged(SessionChangeAddPrepared, name, statement)
+					}
+				}
+				if v := resp.Header.Get(trinoDeallocatedPrepareHeader); v != "" {
+					c.removePreparedStatement(v)
+					c.sessionChanged(SessionChangeDeallocatePrepared, v, "")
+				}
+				if v := resp.Header.Get(trinoSetAuthorizationUserHeader); v != "" {
+					c.authorizationUser = v
+					c.sessionChanged(SessionChangeSetAuthorizationUser, "", v)
+				}
+				if v := resp.Header.Get(trinoResetAuthorizationUserHeader); v != "" {
+					c.authorizationUser = ""
+					c.sessionChanged(SessionChangeResetAuthorizationUser, "", "")
+				}
+				if v := resp.Header.Get(trinoSetSessionHeader); v != "" {
+					if err := c.checkSetSession(v); err != nil {
+						resp.Body.Close()
+						return nil, err
+					}
+					c.httpHeaders.Add(trinoSessionHeader, v)
+					name, value := splitSessionEntry(v)
+					c.sessionChanged(SessionChangeSetProperty, name, value)
+				}
+				if v := resp.Header.Get(trinoClearSessionHeader); v != "" {
+					values := c.httpHeaders.Values(trinoSessionHeader)
+					c.httpHeaders.Del(trinoSessionHeader)
+					for _, v2 := range values {
+						if !strings.HasPrefix(v2, v+"=") {
+							c.httpHeaders.Add(trinoSessionHeader, v2)
+						}
+					}
+					c.sessionChanged(SessionChangeResetProperty, v, "")
+				}
+				for _, name := range unsupportedResponseHeaders {
+					if v := resp.Header.Get(name); v != "" {
+						return nil, ErrUnsupportedHeader
+					}
+				}
+				return resp, nil
+			case http.StatusServiceUnavailable:
+				resp.Body.Close()
+				timer.Reset(delay)
+				delay = time.Duration(math.Min(
+					float64(delay)*math.Phi,
+					maxDelayBetweenRequests,
+				))
+				continue
+			case http.StatusTooManyRequests:
+				resp.Body.Close()
+				info := RateLimitInfo{
+					URL:        req.URL.String(),
+					RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+					Header:     resp.Header,
+				}
+				if c.rateLimitCallback != nil {
+					c.rateLimitCallback(info)
+				}
+				timer.Reset(rateLimitDelay(info.RetryAfter, delay))
+				delay = time.Duration(math.Min(
+					float64(delay)*math.Phi,
+					maxDelayBetweenRequests,
+				))
+				continue
+			default:
+				return nil, protocol.NewErrQueryFailedFromResponse(resp)
+			}
+		}
+	}
+}
+
+// rateLimitDelay returns the delay before retrying a rate limited request, the
+// one requested by its Retry-After header, or delay if there's none. It's at
+// most maxDelayBetweenRequests, so a proxy can't stall the request for hours.
+func rateLimitDelay(retryAfter, delay time.Duration) time.Duration {
+	if retryAfter <= 0 {
+		return delay
+	}
+	return time.Duration(math.Min(float64(retryAfter), maxDelayBetweenRequests))
+}
+
+// RateLimitInfo describes an HTTP 429 Too Many Requests response.
+type RateLimitInfo struct {
+	// URL of the rate limited request.
+	URL string
+	// RetryAfter is the delay requested by the Retry-After header, or zero when missing.
+	RetryAfter time.Duration
+	// Header contains all response headers, including any rate-limit metadata,
+	// such as RateLimit-Limit, RateLimit-Remaining or RateLimit-Reset.
+	Header http.Header
+}
+
+// parseRetryAfter parses a Retry-After header value, which contains
+// either a number of seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// The messages of the protocol are defined in the protocol package.
+type (
+	stmtResponse  = protocol.StmtResponse
+	stmtStats     = protocol.StmtStats
+	stmtStage     = protocol.StmtStage
+	queryResponse = protocol.QueryResponse
+)
+
+// countingReader counts the number of bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,94 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+// writeFile writes a file with a distinct modification time, so changes are detected
+// even on file systems with a coarse time resolution.
+func writeFile(t *testing.T, path string, content []byte, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestAccessTokenPath(t *testing.T) {
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{})
+
+	path := filepath.Join(t.TempDir(), "token")
+	now := time.Now()
+	writeFile(t, path, []byte("token1\n"), now.Add(-time.Minute))
+
+	dsn, err := (&Config{ServerURI: server.URL, AccessTokenPath: path}).FormatDSN()
+	require.NoError(t, err)
+	db := openTestDB(t, dsn)
+
+	_, err = db.Exec("SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token1", lastSubmitted(t, server).Header.Get(authorizationHeader))
+
+	writeFile(t, path, []byte("token2\n"), now)
+
+	_, err = db.Exec("SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token2", lastSubmitted(t, server).Header.Get(authorizationHeader))
+}
+
+func TestConfigAccessTokenAndPath(t *testing.T) {
+	_, err := (&Config{ServerURI: "https://localhost", AccessToken: "token", AccessTokenPath: "token"}).FormatDSN()
+	assert.Error(t, err)
+}
+
+func TestSSLCertPathReload(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&stmtResponse{})
+	}))
+	t.Cleanup(ts.Close)
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	now := time.Now()
+	writeFile(t, path, []byte("rotating"), now.Add(-time.Minute))
+
+	db, err := sql.Open("trino", ts.URL+"?"+sslCertPathConfig+"="+url.QueryEscape(path))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	_, err = db.Exec("SELECT 1")
+	assert.ErrorContains(t, err, "certificate")
+
+	writeFile(t, path, cert, now)
+
+	_, err = db.Exec("SELECT 1")
+	assert.NoError(t, err)
+}
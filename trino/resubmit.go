@@ -0,0 +1,113 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// idempotencyKeyPrefix precedes the idempotency key in the comment tagging
+// queries submitted with resubmit_statements=true.
+const idempotencyKeyPrefix = "trino-go idempotency_key="
+
+// maxResubmits is the number of times a query is submitted again, after the
+// request submitting it failed without a response.
+const maxResubmits = 2
+
+// newIdempotencyKey returns a random key identifying a query submitted with
+// resubmit_statements=true, in system.runtime.queries.
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("trino: error generating an idempotency key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// tagIdempotencyKey returns the query with a comment containing the key.
+// system.runtime.queries doesn't have the client info or tags of queries,
+// but it has their text.
+func tagIdempotencyKey(query, key string) string {
+	return "/* " + idempotencyKeyPrefix + key + " */ " + query
+}
+
+// isUnanswered reports whether a request failed without a response from the
+// server, which may or may not have received it.
+func isUnanswered(err error) bool {
+	var qferr *ErrQueryFailed
+	return errors.As(err, &qferr) && qferr.StatusCode == 0
+}
+
+// resubmit handles the failure err of the request req submitting a query tagged
+// with key. If it failed without a response, and the server didn't start running
+// the query, it's submitted again, otherwise it returns an error wrapping
+// ErrQuerySubmitted, since the results of the query can't be read without the
+// response. It returns err if the query can't be looked up.
+func (st *driverStmt) resubmit(ctx context.Context, req *http.Request, key string, err error) (*http.Response, error) {
+	for i := 0; i < maxResubmits && isUnanswered(err) && ctx.Err() == nil; i++ {
+		queryID, state, lookupErr := st.conn.lookupIdempotencyKey(ctx, st.user, key)
+		if lookupErr != nil {
+			return nil, err
+		}
+		if queryID != "" {
+			return nil, fmt.Errorf("%w: query %s is %s: %w", ErrQuerySubmitted, queryID, state, err)
+		}
+		var resp *http.Response
+		resp, err = st.conn.roundTrip(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+	}
+	return nil, err
+}
+
+// lookupIdempotencyKey returns the ID and the state of the query of user tagged
+// with key, from system.runtime.queries, or an empty ID if there is none.
+func (c *Conn) lookupIdempotencyKey(ctx context.Context, user, key string) (queryID, state string, err error) {
+	// the key is split, so this query doesn't match itself
+	lookup := &driverStmt{
+		conn:     c,
+		query:    "SELECT query_id, state FROM system.runtime.queries WHERE strpos(query, '" + idempotencyKeyPrefix + "' || '" + key + "') > 0",
+		internal: true,
+	}
+	defer lookup.Close()
+	var args []driver.NamedValue
+	if user != "" {
+		args = append(args, driver.NamedValue{Name: trinoUserHeader, Value: user})
+	}
+	rows, err := lookup.QueryContext(ctx, args)
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 2)
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF || err == sql.ErrNoRows {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	queryID, _ = dest[0].(string)
+	state, _ = dest[1].(string)
+	return queryID, state, nil
+}
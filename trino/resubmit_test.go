@@ -0,0 +1,167 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResubmitStatements(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		posted []string
+		resets int
+		found  bool
+		// foundLater makes the lookups after the first find the query.
+		foundLater bool
+		lookups    int
+	)
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPost:
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			posted = append(posted, string(b))
+			id := "fake-query"
+			if strings.Contains(string(b), "system.runtime.queries") {
+				id = "lookup"
+				lookups++
+			} else if resets > 0 {
+				resets--
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				require.NoError(t, conn.(*net.TCPConn).SetLinger(0))
+				conn.Close()
+				return
+			}
+			json.NewEncoder(w).Encode(&stmtResponse{ID: id, NextURI: ts.URL + "/v1/statement/" + id + "/1"})
+		default:
+			if strings.Contains(r.URL.Path, "lookup") {
+				data := "[]"
+				if found || foundLater && lookups > 1 {
+					data = `[["20240101_000000_00001_abcde","RUNNING"]]`
+				}
+				w.Write([]byte(`{"id":"lookup","columns":[
+					{"name":"query_id","type":"varchar","typeSignature":{"rawType":"varchar","arguments":[]}},
+					{"name":"state","type":"varchar","typeSignature":{"rawType":"varchar","arguments":[]}}
+				],"data":` + data + `}`))
+				return
+			}
+			w.Write([]byte(`{"id":"fake-query","updateType":"INSERT","updateCount":1}`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	db, err := sql.Open("trino", ts.URL+"?resubmit_statements=true")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	// expect resets the next n requests submitting queries, and sets whether the
+	// lookup finds the query, and submitted returns the submitted queries.
+	expect := func(n int, lookupFinds bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		posted, resets, found = nil, n, lookupFinds
+	}
+	submitted := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return posted
+	}
+	keyRegexp := regexp.MustCompile(`^/\* trino-go idempotency_key=([0-9a-f]{32}) \*/ INSERT INTO t VALUES \(1\)$`)
+
+	expect(1, false)
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	queries := submitted()
+	require.Len(t, queries, 3)
+	key := keyRegexp.FindStringSubmatch(queries[0])
+	require.NotNil(t, key, queries[0])
+	assert.Contains(t, queries[1], "'"+key[1]+"'")
+	assert.NotContains(t, queries[1], idempotencyKeyPrefix+key[1], "the lookup must not match itself")
+	assert.Equal(t, queries[0], queries[2], "the query is submitted again with the same key")
+
+	expect(1, true)
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	assert.ErrorIs(t, err, ErrQuerySubmitted)
+	assert.ErrorContains(t, err, "query 20240101_000000_00001_abcde is RUNNING")
+	assert.Len(t, submitted(), 2)
+
+	// each query has its own key
+	expect(0, false)
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	queries = submitted()
+	require.Len(t, queries, 2)
+	assert.NotEqual(t, queries[0], queries[1])
+
+	db, err = sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	expect(1, false)
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"INSERT INTO t VALUES (1)"}, submitted(), "queries aren't submitted again by default")
+
+	// the lookup isn't rewritten or cached, so the query submitted again is
+	// found by a lookup after one which didn't find it
+	connector, err := NewConnector(&Config{
+		ServerURI:          ts.URL,
+		ResubmitStatements: "true",
+		ResultCache:        NewResultCache(time.Minute, 1<<20),
+		QueryRewriter: QueryRewriterFunc(func(ctx context.Context, query string) (string, error) {
+			return query + " -- rewritten", nil
+		}),
+	})
+	require.NoError(t, err)
+	db = sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	expect(2, false)
+	mu.Lock()
+	foundLater, lookups = true, 0
+	mu.Unlock()
+	_, err = db.Exec("INSERT INTO t VALUES (1)")
+	assert.ErrorIs(t, err, ErrQuerySubmitted)
+	queries = submitted()
+	require.Len(t, queries, 4)
+	assert.NotContains(t, queries[1], "rewritten")
+	assert.Equal(t, queries[1], queries[3], "the query is looked up again")
+}
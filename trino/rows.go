@@ -0,0 +1,508 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+)
+
+// ColumnMetadata is the metadata of a column returned by the server.
+type ColumnMetadata struct {
+	// Name is the name of the column.
+	Name string
+	// Type is the type of the column, including its parameters, like decimal(38,10).
+	Type string
+	// TypeSignature is the parsed type of the column.
+	TypeSignature TypeSignature
+}
+
+type driverRows struct {
+	ctx     context.Context
+	stmt    *driverStmt
+	queryID string
+	nextURI string
+
+	err      error
+	rowindex int
+	rowsRead int64
+	columns  []string
+	coltype  []*typeConverter
+	// skipped are the columns that are not converted, when using WithColumns.
+	skipped   []bool
+	data      []queryData
+	dataBytes int64
+	// holdsPage is set while the page being read is counted in the buffered pages of the statement.
+	holdsPage    bool
+	rowsAffected int64
+
+	// cached are the results read from the ResultCache, instead of the server.
+	cached     *resultCacheEntry
+	cachedPage int
+	// recorder stores the results in the ResultCache, if they can be cached.
+	recorder *resultRecorder
+	// execProgress is called with every status of the statement, when set with WithExecProgress.
+	execProgress func(ExecProgress)
+	// done is called once all the rows were read, or the query failed or was closed,
+	// when set by QueryContext.
+	done func()
+	// progressInfo is the last progress of the query, reported to a ProgressFinisher
+	// once it's finished, which sets progressFinished.
+	progressInfo     QueryProgressInfo
+	progressFinished bool
+	// statementLogged is set once the statement was passed to the StatementLogger.
+	statementLogged bool
+	// updateType is the type of statement, like INSERT, or empty for queries returning rows.
+	updateType string
+	// columnsFirst makes the first fetch return once the columns are known, without
+	// waiting for rows, when set with WithColumnsFirst.
+	columnsFirst bool
+}
+
+var _ driver.Rows = &driverRows{}
+
+var _ driver.Result = &driverRows{}
+
+var _ driver.RowsColumnTypeScanType = &driverRows{}
+
+var _ driver.RowsColumnTypeDatabaseTypeName = &driverRows{}
+
+var _ driver.RowsColumnTypeLength = &driverRows{}
+
+var _ driver.RowsColumnTypePrecisionScale = &driverRows{}
+
+// Close closes the rows iterator.
+func (qr *driverRows) Close() error {
+	if qr.done != nil {
+		defer qr.done()
+	}
+	if qr.err == sql.ErrNoRows || qr.err == io.EOF {
+		return nil
+	}
+	qr.err = io.EOF
+	qr.logStatement(ErrQueryCancelled)
+	if qr.cached != nil {
+		return nil
+	}
+	qr.finishProgress(ErrQueryCancelled)
+	hs := make(http.Header)
+	if qr.stmt.user != "" {
+		hs.Add(trinoUserHeader, qr.stmt.user)
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(qr.ctx), DefaultCancelQueryTimeout)
+	defer cancel()
+	req, err := qr.stmt.conn.newRequest(ctx, "DELETE", qr.stmt.baseURL+"/v1/query/"+url.PathEscape(qr.queryID), nil, hs)
+	if err != nil {
+		return err
+	}
+	resp, err := qr.stmt.conn.roundTrip(ctx, req)
+	if err != nil {
+		qferr, ok := err.(*ErrQueryFailed)
+		if ok && qferr.StatusCode == http.StatusNoContent {
+			qr.nextURI = ""
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return qr.err
+}
+
+// returnsRows reports whether the query returns rows, like SELECT, instead of
+// the count of rows affected by a statement, like INSERT.
+func (qr *driverRows) returnsRows() bool {
+	return qr.columns != nil && qr.updateType == ""
+}
+
+// stopSelect cancels a query returning rows executed with Exec, after its first
+// page, instead of downloading all its rows, and returns an error when using
+// exec_select=error.
+func (qr *driverRows) stopSelect() error {
+	var err error
+	if qr.stmt.conn.execSelect == execSelectError {
+		err = &UnsupportedOperationError{
+			Operation:   "Exec of a query returning rows, like SELECT",
+			Alternative: "use Query to read its rows",
+		}
+	}
+	qr.finishProgress(err)
+	qr.logStatement(err)
+	qr.Close()
+	return err
+}
+
+// Columns returns the names of the columns.
+func (qr *driverRows) Columns() []string {
+	if qr.err != nil {
+		return []string{}
+	}
+	if qr.columns == nil {
+		if err := qr.fetch(); err != nil && err != io.EOF {
+			qr.err = err
+			return []string{}
+		}
+	}
+	return qr.columns
+}
+
+// ColumnTypeDatabaseTypeName returns the database type name of a column, in upper case.
+// By default, it doesn't include the parameters of the type, like the precision
+// and scale of DECIMAL, unless using full_type_names=true in the DSN.
+func (qr *driverRows) ColumnTypeDatabaseTypeName(index int) string {
+	typeName := qr.coltype[index].parsedType[0]
+	if typeName == "map" || typeName == "array" || typeName == "row" || qr.stmt.conn.fullTypeNames {
+		typeName = qr.coltype[index].typeName
+	}
+	return strings.ToUpper(typeName)
+}
+
+// ColumnTypeScanType returns the type of a variable the values of a column can be scanned into.
+// Using pointer_scan_types=true in the DSN, scalar types are reported as pointers,
+// like *string instead of sql.NullString.
+func (qr *driverRows) ColumnTypeScanType(index int) reflect.Type {
+	if qr.stmt.rawJSON {
+		return reflect.TypeOf(json.RawMessage{})
+	}
+	c := qr.coltype[index]
+	if qr.stmt.conn.pointerScanTypes {
+		if c.realAsFloat32 && c.parsedType[0] == "real" {
+			return reflect.TypeOf(new(float32))
+		}
+		if t, ok := pointerScanTypes[c.scanType]; ok {
+			return t
+		}
+	}
+	return c.scanType
+}
+
+// pointerScanTypes are the pointer types reported instead of the scan types of
+// scalar columns, when using pointer_scan_types=true.
+var pointerScanTypes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(sql.NullBool{}):    reflect.TypeOf(new(bool)),
+	reflect.TypeOf(sql.NullString{}):  reflect.TypeOf(new(string)),
+	reflect.TypeOf(sql.NullInt32{}):   reflect.TypeOf(new(int32)),
+	reflect.TypeOf(sql.NullInt64{}):   reflect.TypeOf(new(int64)),
+	reflect.TypeOf(sql.NullFloat64{}): reflect.TypeOf(new(float64)),
+	reflect.TypeOf(sql.NullTime{}):    reflect.TypeOf(new(time.Time)),
+	reflect.TypeOf(NullPreciseTime{}): reflect.TypeOf(new(PreciseTime)),
+}
+
+func (qr *driverRows) ColumnTypeLength(index int) (int64, bool) {
+	return qr.coltype[index].size.value, qr.coltype[index].size.hasValue
+}
+
+func (qr *driverRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	return qr.coltype[index].precision.value, qr.coltype[index].scale.value, qr.coltype[index].precision.hasValue
+}
+
+// ColumnTypeSignature returns the parsed type of a column.
+func (qr *driverRows) ColumnTypeSignature(index int) TypeSignature {
+	return qr.coltype[index].signature
+}
+
+// ColumnMetadata returns the metadata of all the columns.
+func (qr *driverRows) ColumnMetadata() []ColumnMetadata {
+	metadata := make([]ColumnMetadata, len(qr.columns))
+	for i, name := range qr.columns {
+		metadata[i] = ColumnMetadata{
+			Name:          name,
+			Type:          qr.coltype[i].typeName,
+			TypeSignature: qr.coltype[i].signature,
+		}
+	}
+	return metadata
+}
+
+// Next is called to populate the next row of data into
+// the provided slice. The provided slice will be the same
+// size as the Columns() are wide.
+//
+// Next should return io.EOF when there are no more rows.
+func (qr *driverRows) Next(dest []driver.Value) error {
+	if qr.err != nil {
+		return qr.err
+	}
+	if qr.columns == nil || qr.rowindex >= len(qr.data) {
+		if qr.nextURI == "" && qr.cached == nil {
+			qr.err = io.EOF
+			return qr.err
+		}
+		if err := qr.fetch(); err != nil {
+			qr.err = err
+			return err
+		}
+	}
+	if len(qr.coltype) == 0 {
+		qr.err = sql.ErrNoRows
+		return qr.err
+	}
+	for i, v := range qr.coltype {
+		if i > len(dest)-1 {
+			break
+		}
+		if qr.skipped != nil && qr.skipped[i] {
+			dest[i] = nil
+			continue
+		}
+		if qr.stmt.rawJSON {
+			dest[i] = qr.data[qr.rowindex][i]
+			continue
+		}
+		vv, err := v.ConvertValue(qr.data[qr.rowindex][i])
+		if err != nil {
+			qr.err = &ConversionError{
+				Column: qr.columns[i],
+				Type:   v.typeName,
+				Row:    qr.rowsRead,
+				Value:  qr.data[qr.rowindex][i],
+				Err:    err,
+			}
+			return qr.err
+		}
+		dest[i] = vv
+	}
+	qr.rowindex++
+	qr.rowsRead++
+	return nil
+}
+
+// LastInsertId returns the database's auto-generated ID
+// after, for example, an INSERT into a table with primary
+// key.
+func (qr driverRows) LastInsertId() (int64, error) {
+	return 0, &UnsupportedOperationError{
+		Operation:   "LastInsertId",
+		Alternative: "use RowsAffected to get the number of changed rows, or query the inserted rows",
+	}
+}
+
+// RowsAffected returns the number of rows affected by the query.
+func (qr driverRows) RowsAffected() (int64, error) {
+	return qr.rowsAffected, nil
+}
+
+func (qr *driverRows) fetch() error {
+	err := qr.fetchPage()
+	if err != nil {
+		if err == io.EOF {
+			qr.finishProgress(nil)
+			qr.logStatement(nil)
+		} else {
+			qr.finishProgress(err)
+			qr.logStatement(err)
+		}
+		if qr.done != nil {
+			qr.done()
+		}
+	}
+	return err
+}
+
+// finishProgress reports the end of the query to a ProgressFinisher, once.
+func (qr *driverRows) finishProgress(err error) {
+	if qr.progressFinished || qr.cached != nil || qr.stmt.conn.progress == nil {
+		return
+	}
+	qr.progressFinished = true
+	if finisher, ok := qr.stmt.conn.progressUpdater.(ProgressFinisher); ok {
+		info := qr.progressInfo
+		info.BufferedBytes = qr.stmt.bufferedBytes.Load()
+		qr.stmt.conn.progress.event(func() { finisher.OnFinished(info, err) })
+	}
+}
+
+// fetchPage fetches the next page of results with data, or the final status of the query.
+func (qr *driverRows) fetchPage() error {
+	qr.releaseData()
+	if qr.cached != nil {
+		return qr.fetchCached()
+	}
+	var qresp queryResponse
+	var err error
+	for {
+		select {
+		case qresp = <-qr.stmt.queryResponses:
+			if qresp.ID == "" {
+				// Fetching stopped, either after the last page, or because of an
+				// error, which is sent before queryResponses is closed.
+				select {
+				case err = <-qr.stmt.errors:
+					return qr.fetchFailed(err)
+				default:
+					if qr.recorder != nil {
+						qr.recorder.store()
+					}
+					return io.EOF
+				}
+			}
+			if qr.recorder != nil {
+				qr.recorder.record(&qresp)
+			}
+			err = qr.initColumns(&qresp)
+			if err != nil {
+				return err
+			}
+			qr.rowindex = 0
+			qr.data = qresp.Data
+			qr.dataBytes = qresp.Bytes
+			qr.holdsPage = true
+			qr.setRowsAffected(qresp.UpdateCount)
+			if qresp.UpdateType != "" {
+				qr.updateType = qresp.UpdateType
+			}
+			qr.scheduleProgressUpdate(qresp.ID, qresp.Stats)
+			if qr.execProgress != nil {
+				qr.execProgress(newExecProgress(qresp.ID, qresp.Stats, qr.rowsAffected))
+			}
+			if len(qr.data) != 0 {
+				return nil
+			}
+			qr.releaseData()
+			if qr.columnsFirst && qr.columns != nil {
+				// return the columns, Next fetches the rows
+				qr.columnsFirst = false
+				return nil
+			}
+		case err = <-qr.stmt.errors:
+			return qr.fetchFailed(err)
+		}
+	}
+}
+
+// setRowsAffected keeps the update count reported by the server. It's the total count,
+// reported once the statement finished, and not reported by the other responses,
+// like the ones returning data, so those don't reset it.
+func (qr *driverRows) setRowsAffected(updateCount int64) {
+	if updateCount != 0 {
+		qr.rowsAffected = updateCount
+	}
+}
+
+func (qr *driverRows) fetchFailed(err error) error {
+	qr.finishProgress(err)
+	qr.logStatement(err)
+	if err == context.Canceled {
+		qr.Close()
+	}
+	qr.err = err
+	return err
+}
+
+// releaseData removes the current page from the statement's buffered pages and bytes.
+func (qr *driverRows) releaseData() {
+	if !qr.holdsPage {
+		return
+	}
+	qr.stmt.bufferedBytes.Add(-qr.dataBytes)
+	qr.stmt.bufferedPages.Add(-1)
+	qr.dataBytes = 0
+	qr.holdsPage = false
+	select {
+	case qr.stmt.releasedCh <- struct{}{}:
+	default:
+	}
+}
+
+func (qr *driverRows) initColumns(qresp *queryResponse) error {
+	if qr.columns != nil || len(qresp.Columns) == 0 {
+		return nil
+	}
+	var err error
+	for i := range qresp.Columns {
+		err = protocol.UnmarshalArguments(&(qresp.Columns[i].TypeSignature))
+		if err != nil {
+			return fmt.Errorf("error decoding column type signature: %w", err)
+		}
+	}
+	qr.columns = make([]string, len(qresp.Columns))
+	qr.coltype = make([]*typeConverter, len(qresp.Columns))
+	for i, col := range qresp.Columns {
+		err = protocol.UnmarshalArguments(&(qresp.Columns[i].TypeSignature))
+		if err != nil {
+			return fmt.Errorf("error decoding column type signature: %w", err)
+		}
+		qr.columns[i] = col.Name
+		qr.coltype[i], err = newTypeConverter(col.Type, col.TypeSignature)
+		if err != nil {
+			return err
+		}
+		qr.coltype[i].setSubNanosecondTime(qr.stmt.conn.subNanosecondTime)
+		qr.coltype[i].strictTime = qr.stmt.conn.strictTime
+		qr.coltype[i].realAsFloat32 = qr.stmt.conn.realAsFloat32
+		qr.coltype[i].strictStringLength = qr.stmt.conn.strictStringLength
+		qr.coltype[i].setColumnConverter(qr.stmt.conn.columnConverters, col.Name)
+	}
+	if names, ok := qr.ctx.Value(columnsContextKey).([]string); ok {
+		qr.skipped = make([]bool, len(qr.columns))
+		for i := range qr.skipped {
+			qr.skipped[i] = true
+		}
+		for _, name := range names {
+			found := false
+			for i, column := range qr.columns {
+				if strings.EqualFold(name, column) {
+					qr.skipped[i] = false
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("trino: column %q selected with WithColumns not found in query results", name)
+			}
+		}
+	}
+	if fn, ok := qr.ctx.Value(columnMetadataContextKey).(func([]ColumnMetadata)); ok && fn != nil {
+		fn(qr.ColumnMetadata())
+	}
+	return nil
+}
+
+func (qr *driverRows) scheduleProgressUpdate(id string, stats stmtStats) {
+	qrStats := QueryProgressInfo{
+		QueryId:       id,
+		QueryStats:    stats,
+		BufferedBytes: qr.stmt.bufferedBytes.Load(),
+	}
+	// the last progress is also logged by the StatementLogger
+	qr.progressInfo = qrStats
+	if qr.stmt.conn.progressUpdater == nil {
+		return
+	}
+
+	currentTime := time.Now()
+	diff := currentTime.Sub(qr.stmt.conn.progressUpdaterPeriod.LastCallbackTime)
+	period := qr.stmt.conn.progressUpdaterPeriod.Period
+
+	// Check if period has not passed yet AND if query state did not change
+	if diff < period && qr.stmt.conn.progressUpdaterPeriod.LastQueryState == qrStats.QueryStats.State {
+		return
+	}
+
+	if qr.stmt.conn.progress != nil {
+		qr.stmt.conn.progress.send(qr.stmt.conn.progressUpdater, qrStats)
+	}
+	qr.stmt.conn.progressUpdaterPeriod.LastCallbackTime = currentTime
+	qr.stmt.conn.progressUpdaterPeriod.LastQueryState = qrStats.QueryStats.State
+}
@@ -0,0 +1,427 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino/internal/types"
+)
+
+// NullMapOf represents a map with typed keys and values that may be null.
+//
+// Trino returns map keys as strings, which are parsed into K, so maps
+// with keys of numeric, boolean, or date and time types can be scanned
+// into, for example, NullMapOf[int64, string] or NullMapOf[time.Time, float64].
+// Use a pointer or a sql.Scanner, like sql.NullString, for V if the map
+// can contain null values.
+type NullMapOf[K comparable, V any] struct {
+	Map   map[K]V
+	Valid bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (m *NullMapOf[K, V]) Scan(value interface{}) error {
+	if value == nil {
+		m.Map, m.Valid = map[K]V{}, false
+		return nil
+	}
+	result := make(map[K]V)
+	if err := convertScanned(value, reflect.ValueOf(&result).Elem()); err != nil {
+		return err
+	}
+	m.Map, m.Valid = result, true
+	return nil
+}
+
+// NullSliceRow represents an array of rows that may be null, like ARRAY(ROW(...)),
+// scanned into a slice of structs.
+//
+// Trino returns the fields of a row in the order of the row type, without their
+// names, so they're assigned to the exported fields of T in the order in which
+// they are declared. Fields tagged with `trino:"-"` are skipped. T must have
+// exactly as many fields as the rows.
+type NullSliceRow[T any] struct {
+	SliceRow []T
+	Valid    bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSliceRow[T]) Scan(value interface{}) error {
+	if value == nil {
+		s.SliceRow, s.Valid = []T{}, false
+		return nil
+	}
+	var result []T
+	if err := convertScanned(value, reflect.ValueOf(&result).Elem()); err != nil {
+		return err
+	}
+	s.SliceRow, s.Valid = result, true
+	return nil
+}
+
+// NullArray represents an array with any number of dimensions that may be null,
+// which elements are scanned into T, like a sql.Scanner or a pointer. It's the
+// scan type of arrays of four or more dimensions, like NullArray[sql.NullInt64]
+// for ARRAY(ARRAY(ARRAY(ARRAY(BIGINT)))).
+//
+// The elements of one-dimensional arrays are in Elements, and the inner arrays
+// of arrays of arrays in Arrays. Arrays which elements are all null are scanned
+// into Elements, since their values don't tell how many dimensions they have.
+type NullArray[T any] struct {
+	Elements []T
+	Arrays   []NullArray[T]
+	Valid    bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (a *NullArray[T]) Scan(value interface{}) error {
+	*a = NullArray[T]{}
+	if value == nil {
+		return nil
+	}
+	vs, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("trino: cannot convert %v (%T) to an array", value, value)
+	}
+	a.Valid = true
+	for _, v := range vs {
+		if _, ok := v.([]interface{}); ok {
+			a.Arrays = make([]NullArray[T], len(vs))
+			for i := range vs {
+				if err := a.Arrays[i].Scan(vs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	a.Elements = make([]T, len(vs))
+	for i := range vs {
+		if err := convertScanned(vs[i], reflect.ValueOf(&a.Elements[i]).Elem()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (a NullArray[T]) Value() (driver.Value, error) {
+	if !a.Valid {
+		return nil, nil
+	}
+	if a.Arrays != nil {
+		slice := make([]interface{}, len(a.Arrays))
+		for i, v := range a.Arrays {
+			var err error
+			if slice[i], err = v.Value(); err != nil {
+				return nil, err
+			}
+		}
+		return slice, nil
+	}
+	return elementValues(a.Elements)
+}
+
+// NullSlice represents an array that may be null, which elements are scanned
+// into T, like a sql.Scanner or a pointer. Besides the element types of the
+// NullSlice* types, like NullSlice[sql.NullString] for ARRAY(VARCHAR), it supports
+// types implementing encoding.TextUnmarshaler, like NullSlice[*big.Rat] for
+// ARRAY(DECIMAL(38,10)), or a UUID type for ARRAY(UUID).
+type NullSlice[T any] struct {
+	Slice []T
+	Valid bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice[T]) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[]"+typeName[T](), scanElement[T])
+	if err != nil {
+		return err
+	}
+	s.Slice, s.Valid = slice, valid
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice[T]) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return elementValues(s.Slice)
+}
+
+// NullMatrix represents a two-dimensional array that may be null, which elements
+// are scanned into T, like in NullSlice. Null inner arrays are scanned into empty slices.
+type NullMatrix[T any] struct {
+	Matrix [][]T
+	Valid  bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (m *NullMatrix[T]) Scan(value interface{}) error {
+	name := typeName[T]()
+	matrix, valid, err := types.ScanSlice(value, "[][]"+name, func(v interface{}) ([]T, error) {
+		slice, _, err := types.ScanSlice(v, "[]"+name, scanElement[T])
+		return slice, err
+	})
+	if err != nil {
+		return err
+	}
+	m.Matrix, m.Valid = matrix, valid
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (m NullMatrix[T]) Value() (driver.Value, error) {
+	if !m.Valid {
+		return nil, nil
+	}
+	matrix := make([]interface{}, len(m.Matrix))
+	for i, v := range m.Matrix {
+		var err error
+		if matrix[i], err = elementValues(v); err != nil {
+			return nil, err
+		}
+	}
+	return matrix, nil
+}
+
+// scanElement converts an element of an array into T.
+func scanElement[T any](v interface{}) (T, error) {
+	var e T
+	err := convertScanned(v, reflect.ValueOf(&e).Elem())
+	return e, err
+}
+
+// elementValues returns the elements of an array passed as a query argument,
+// calling the Value method of the ones implementing driver.Valuer.
+func elementValues[T any](elements []T) (driver.Value, error) {
+	slice := make([]interface{}, len(elements))
+	for i, v := range elements {
+		slice[i] = v
+		if valuer, ok := any(v).(driver.Valuer); ok {
+			var err error
+			if slice[i], err = callValuer(valuer); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return slice, nil
+}
+
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+var (
+	scannerType         = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// convertScanned converts a value returned by the driver, or one of its elements
+// when it's an array or a map, into dv, which must be settable.
+func convertScanned(src interface{}, dv reflect.Value) error {
+	if dv.CanAddr() && dv.Addr().Type().Implements(scannerType) {
+		return dv.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+	if dv.Kind() == reflect.Pointer {
+		if src == nil {
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+		p := reflect.New(dv.Type().Elem())
+		if err := convertScanned(src, p.Elem()); err != nil {
+			return err
+		}
+		dv.Set(p)
+		return nil
+	}
+	if src == nil {
+		if dv.Kind() == reflect.Interface {
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+		return fmt.Errorf("trino: cannot convert null to %s, use a pointer or a sql.Scanner", dv.Type())
+	}
+	if dv.Type() == timeType {
+		t, err := scanNullTime(src)
+		if err != nil {
+			return fmt.Errorf("trino: %w", err)
+		}
+		dv.Set(reflect.ValueOf(t.Time))
+		return nil
+	}
+	// values of types like decimal and uuid are returned as strings
+	if s, ok := numberString(src); ok && dv.CanAddr() && dv.Addr().Type().Implements(textUnmarshalerType) {
+		if err := dv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("trino: cannot convert %v to %s: %w", src, dv.Type(), err)
+		}
+		return nil
+	}
+	switch dv.Kind() {
+	case reflect.Interface:
+		sv := reflect.ValueOf(src)
+		if !sv.Type().AssignableTo(dv.Type()) {
+			return convertError(src, dv)
+		}
+		dv.Set(sv)
+		return nil
+	case reflect.String:
+		switch v := src.(type) {
+		case string:
+			dv.SetString(v)
+		case json.Number:
+			dv.SetString(v.String())
+		case bool:
+			dv.SetString(strconv.FormatBool(v))
+		default:
+			return convertError(src, dv)
+		}
+		return nil
+	case reflect.Bool:
+		switch v := src.(type) {
+		case bool:
+			dv.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return convertError(src, dv)
+			}
+			dv.SetBool(b)
+		default:
+			return convertError(src, dv)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, ok := numberString(src)
+		if !ok {
+			return convertError(src, dv)
+		}
+		n, err := strconv.ParseInt(s, 10, dv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("trino: cannot convert %v to %s: %w", src, dv.Type(), err)
+		}
+		dv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, ok := numberString(src)
+		if !ok {
+			return convertError(src, dv)
+		}
+		n, err := strconv.ParseUint(s, 10, dv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("trino: cannot convert %v to %s: %w", src, dv.Type(), err)
+		}
+		dv.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		s, ok := numberString(src)
+		if !ok {
+			return convertError(src, dv)
+		}
+		f, err := types.ParseFloat(s, dv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dv.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		vs, ok := src.([]interface{})
+		if !ok {
+			return convertError(src, dv)
+		}
+		slice := reflect.MakeSlice(dv.Type(), len(vs), len(vs))
+		for i := range vs {
+			if err := convertScanned(vs[i], slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		dv.Set(slice)
+		return nil
+	case reflect.Map:
+		vm, ok := src.(map[string]interface{})
+		if !ok {
+			return convertError(src, dv)
+		}
+		m := reflect.MakeMapWithSize(dv.Type(), len(vm))
+		for k, v := range vm {
+			key := reflect.New(dv.Type().Key()).Elem()
+			if err := convertScanned(k, key); err != nil {
+				return err
+			}
+			value := reflect.New(dv.Type().Elem()).Elem()
+			if err := convertScanned(v, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		dv.Set(m)
+		return nil
+	case reflect.Struct:
+		vs, ok := src.([]interface{})
+		if !ok {
+			return convertError(src, dv)
+		}
+		fields := rowFields(dv.Type())
+		if len(fields) != len(vs) {
+			return fmt.Errorf("trino: cannot convert a row with %d fields to %s with %d fields", len(vs), dv.Type(), len(fields))
+		}
+		for i, field := range fields {
+			if err := convertScanned(vs[i], dv.Field(field)); err != nil {
+				return fmt.Errorf("%w, in field %s", err, dv.Type().Field(field).Name)
+			}
+		}
+		return nil
+	}
+	return convertError(src, dv)
+}
+
+// rowFields returns the indexes of the fields of a struct which row fields are assigned to.
+func rowFields(t reflect.Type) []int {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("trino") == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+// numberString returns the text of a numeric value, which can be a string for map keys,
+// and for the NaN and Infinity floating point values.
+func numberString(src interface{}) (string, bool) {
+	switch v := src.(type) {
+	case json.Number:
+		return v.String(), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+func convertError(src interface{}, dv reflect.Value) error {
+	return fmt.Errorf("trino: cannot convert %v (%T) to %s", src, src, dv.Type())
+}
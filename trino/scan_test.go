@@ -0,0 +1,223 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullMapOf(t *testing.T) {
+	t.Run("string to int64", func(t *testing.T) {
+		var m NullMapOf[string, int64]
+		require.NoError(t, m.Scan(map[string]interface{}{"a": json.Number("1"), "b": json.Number("2")}))
+		assert.True(t, m.Valid)
+		assert.Equal(t, map[string]int64{"a": 1, "b": 2}, m.Map)
+	})
+
+	t.Run("integer keys", func(t *testing.T) {
+		var m NullMapOf[int32, string]
+		require.NoError(t, m.Scan(map[string]interface{}{"1": "a", "-2": "b"}))
+		assert.Equal(t, map[int32]string{1: "a", -2: "b"}, m.Map)
+	})
+
+	t.Run("boolean keys", func(t *testing.T) {
+		var m NullMapOf[bool, float64]
+		require.NoError(t, m.Scan(map[string]interface{}{"true": json.Number("1.5"), "false": "NaN"}))
+		assert.Equal(t, 1.5, m.Map[true])
+		assert.True(t, math.IsNaN(m.Map[false]))
+	})
+
+	t.Run("date keys", func(t *testing.T) {
+		var m NullMapOf[time.Time, bool]
+		require.NoError(t, m.Scan(map[string]interface{}{"2023-01-02": true}))
+		assert.Equal(t, map[time.Time]bool{time.Date(2023, 1, 2, 0, 0, 0, 0, time.Local): true}, m.Map)
+	})
+
+	t.Run("null values", func(t *testing.T) {
+		var m NullMapOf[string, *int64]
+		require.NoError(t, m.Scan(map[string]interface{}{"a": nil}))
+		assert.Equal(t, map[string]*int64{"a": nil}, m.Map)
+
+		var ns NullMapOf[string, sql.NullString]
+		require.NoError(t, ns.Scan(map[string]interface{}{"a": nil, "b": "x"}))
+		assert.Equal(t, map[string]sql.NullString{"a": {}, "b": {String: "x", Valid: true}}, ns.Map)
+
+		var n NullMapOf[string, int64]
+		assert.Error(t, n.Scan(map[string]interface{}{"a": nil}))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		var m NullMapOf[string, []map[string]int64]
+		require.NoError(t, m.Scan(map[string]interface{}{
+			"a": []interface{}{map[string]interface{}{"x": json.Number("1")}},
+		}))
+		assert.Equal(t, map[string][]map[string]int64{"a": {{"x": 1}}}, m.Map)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		var m NullMapOf[string, int8]
+		assert.Error(t, m.Scan(map[string]interface{}{"a": json.Number("128")}))
+	})
+
+	t.Run("null", func(t *testing.T) {
+		m := NullMapOf[string, int64]{Valid: true}
+		require.NoError(t, m.Scan(nil))
+		assert.False(t, m.Valid)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var m NullMapOf[string, int64]
+		assert.Error(t, m.Scan([]interface{}{}))
+		assert.Error(t, m.Scan(map[string]interface{}{"a": "b"}))
+	})
+}
+
+func TestNullSliceRow(t *testing.T) {
+	type item struct {
+		Name     string
+		Quantity int64
+		Price    *float64
+		internal int
+		Ignored  string `trino:"-"`
+		Tags     []string
+	}
+	price := 1.5
+
+	var s NullSliceRow[item]
+	require.NoError(t, s.Scan([]interface{}{
+		[]interface{}{"apple", json.Number("3"), json.Number("1.5"), []interface{}{"fruit"}},
+		[]interface{}{"pear", json.Number("1"), nil, []interface{}{}},
+	}))
+	assert.True(t, s.Valid)
+	assert.Equal(t, []item{
+		{Name: "apple", Quantity: 3, Price: &price, Tags: []string{"fruit"}},
+		{Name: "pear", Quantity: 1, Tags: []string{}},
+	}, s.SliceRow)
+
+	require.NoError(t, s.Scan(nil))
+	assert.False(t, s.Valid)
+
+	assert.ErrorContains(t, s.Scan([]interface{}{[]interface{}{"apple", json.Number("3")}}), "2 fields")
+	assert.ErrorContains(t, s.Scan([]interface{}{[]interface{}{"apple", "x", nil, []interface{}{}}}), "Quantity")
+}
+
+func TestConvertScannedFloat32(t *testing.T) {
+	var m NullMapOf[string, float32]
+	require.NoError(t, m.Scan(map[string]interface{}{"a": json.Number("0.1"), "b": "-Infinity"}))
+	assert.Equal(t, float32(0.1), m.Map["a"])
+	assert.True(t, math.IsInf(float64(m.Map["b"]), -1))
+
+	assert.ErrorIs(t, m.Scan(map[string]interface{}{"a": json.Number("0.123456789012")}), ErrInexactNumber)
+	assert.ErrorIs(t, m.Scan(map[string]interface{}{"a": json.Number("1e39")}), ErrInexactNumber)
+}
+
+func TestNullArray(t *testing.T) {
+	scanType, err := getScanType([]string{"array", "array", "array", "array", "array", "bigint"})
+	require.NoError(t, err)
+	assert.Equal(t, reflect.TypeOf(NullArray[sql.NullInt64]{}), scanType)
+	_, err = getScanType([]string{"array", "array", "array", "array"})
+	assert.Equal(t, ErrInvalidResponseType, err)
+
+	var a NullArray[sql.NullInt64]
+	value := []interface{}{
+		[]interface{}{[]interface{}{[]interface{}{json.Number("1"), nil}}},
+		nil,
+		[]interface{}{},
+	}
+	require.NoError(t, a.Scan(value))
+	assert.True(t, a.Valid)
+	require.Len(t, a.Arrays, 3)
+	assert.False(t, a.Arrays[1].Valid)
+	assert.Equal(t, []sql.NullInt64{{Int64: 1, Valid: true}, {}}, a.Arrays[0].Arrays[0].Arrays[0].Elements)
+	assert.Empty(t, a.Arrays[2].Elements)
+	v, err := a.Value()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		[]interface{}{[]interface{}{[]interface{}{int64(1), nil}}},
+		nil,
+		[]interface{}{},
+	}, v)
+
+	require.NoError(t, a.Scan([]interface{}{nil, nil}))
+	assert.Equal(t, []sql.NullInt64{{}, {}}, a.Elements, "arrays of nulls are scanned into elements")
+	assert.Nil(t, a.Arrays)
+
+	require.NoError(t, a.Scan(nil))
+	assert.False(t, a.Valid)
+	v, err = a.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.Error(t, a.Scan("[]"))
+	assert.Error(t, a.Scan([]interface{}{[]interface{}{"x"}}))
+
+	var times NullArray[*time.Time]
+	require.NoError(t, times.Scan([]interface{}{[]interface{}{"2024-01-02 03:04:05"}}))
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local), *times.Arrays[0].Elements[0])
+}
+
+func TestNullSlice(t *testing.T) {
+	var decimals NullSlice[*big.Rat]
+	require.NoError(t, decimals.Scan([]interface{}{"1.50", nil, json.Number("-2")}))
+	assert.True(t, decimals.Valid)
+	require.Len(t, decimals.Slice, 3)
+	assert.Equal(t, "3/2", decimals.Slice[0].String())
+	assert.Nil(t, decimals.Slice[1])
+	assert.Equal(t, "-2/1", decimals.Slice[2].String())
+	assert.Error(t, decimals.Scan([]interface{}{"x"}))
+
+	var addresses NullSlice[netip.Addr]
+	require.NoError(t, addresses.Scan([]interface{}{"10.0.0.1", "::1"}))
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")}, addresses.Slice)
+
+	var names NullSlice[sql.NullString]
+	require.NoError(t, names.Scan([]interface{}{"a", nil}))
+	assert.Equal(t, []sql.NullString{{String: "a", Valid: true}, {}}, names.Slice)
+	v, err := names.Value()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", nil}, v)
+
+	require.NoError(t, names.Scan(nil))
+	assert.False(t, names.Valid)
+	assert.Empty(t, names.Slice)
+	v, err = names.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	assert.EqualError(t, names.Scan("a"), "trino: cannot convert a (string) to []sql.NullString")
+}
+
+func TestNullMatrix(t *testing.T) {
+	var m NullMatrix[sql.NullInt64]
+	require.NoError(t, m.Scan([]interface{}{[]interface{}{json.Number("1"), nil}, nil}))
+	assert.True(t, m.Valid)
+	assert.Equal(t, [][]sql.NullInt64{{{Int64: 1, Valid: true}, {}}, {}}, m.Matrix)
+	v, err := m.Value()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{int64(1), nil}, []interface{}{}}, v)
+
+	assert.EqualError(t, m.Scan([]interface{}{"1"}), "trino: cannot convert 1 (string) to []sql.NullInt64")
+	require.NoError(t, m.Scan(nil))
+	assert.False(t, m.Valid)
+}
@@ -15,11 +15,15 @@
 package trino
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -35,6 +39,15 @@ func (e UnsupportedArgError) Error() string {
 // If another string format is used it will error to serialise
 type Numeric string
 
+// formatYear formats a year with at least 4 digits, and a minus sign for years
+// before 0, like time.Time.Format and Trino.
+func formatYear(year int) string {
+	if year < 0 {
+		return fmt.Sprintf("-%04d", -year)
+	}
+	return fmt.Sprintf("%04d", year)
+}
+
 // trinoDate represents a Date type in Trino.
 type trinoDate struct {
 	year  int
@@ -94,9 +107,124 @@ func Timestamp(year int,
 	return trinoTimestamp(time.Date(year, month, day, hour, minute, second, nanosecond, time.UTC))
 }
 
+// SerializerFunc returns the SQL literal of a query argument, like DECIMAL '10.50'.
+type SerializerFunc func(v interface{}) (string, error)
+
+// SerializerContextFunc returns the SQL literal of a query argument, like
+// SerializerFunc, given the context and the session of the query, to render
+// literals depending on the session time zone, for example.
+type SerializerContextFunc func(ctx context.Context, session SessionInfo, v interface{}) (string, error)
+
+// SessionInfo is the session of a query, whose arguments are serialized.
+type SessionInfo struct {
+	// TimeZone is the time zone of the session, set by the X-Trino-Time-Zone
+	// header, or empty for the time zone of the server.
+	TimeZone string
+	// Properties are the session properties of the connection and the query,
+	// like those of WithSessionProperties.
+	Properties map[string]string
+	// ServerVersion is the version of the server, if known.
+	ServerVersion string
+}
+
+var serializerRegistry = struct {
+	sync.RWMutex
+	Index map[reflect.Type]SerializerContextFunc
+}{
+	Index: make(map[reflect.Type]SerializerContextFunc),
+}
+
+// RegisterSerializer registers a function serializing query arguments of type t,
+// so values of domain types, like money or coordinates, can be passed to queries
+// without converting them first. It takes precedence over the built-in serialization,
+// including for types implementing driver.Valuer or encoding.TextMarshaler, and
+// applies to the elements of slices too.
+//
+// The function must return a valid SQL literal. It can call Serial with a
+// supported type, to quote strings, for example:
+//
+//	trino.RegisterSerializer(reflect.TypeOf(Money{}), func(v interface{}) (string, error) {
+//		m := v.(Money)
+//		currency, err := trino.Serial(m.Currency)
+//		if err != nil {
+//			return "", err
+//		}
+//		return fmt.Sprintf("CAST(ROW(DECIMAL '%d.%02d', %s) AS ROW(amount DECIMAL(18,2), currency VARCHAR))",
+//			m.Cents/100, m.Cents%100, currency), nil
+//	})
+func RegisterSerializer(t reflect.Type, fn SerializerFunc) error {
+	if fn == nil {
+		return errors.New("trino: serializer type and function must not be nil")
+	}
+	return RegisterSerializerContext(t, func(_ context.Context, _ SessionInfo, v interface{}) (string, error) {
+		return fn(v)
+	})
+}
+
+// RegisterSerializerContext registers a function serializing query arguments of
+// type t, like RegisterSerializer, which is given the session of the query, to
+// render civil times in the session time zone, for example:
+//
+//	trino.RegisterSerializerContext(reflect.TypeOf(civil.DateTime{}), func(ctx context.Context, session trino.SessionInfo, v interface{}) (string, error) {
+//		loc, err := time.LoadLocation(session.TimeZone)
+//		if err != nil {
+//			return "", err
+//		}
+//		return trino.Serial(v.(civil.DateTime).In(loc))
+//	})
+func RegisterSerializerContext(t reflect.Type, fn SerializerContextFunc) error {
+	if t == nil || fn == nil {
+		return errors.New("trino: serializer type and function must not be nil")
+	}
+	serializerRegistry.Lock()
+	serializerRegistry.Index[t] = fn
+	serializerRegistry.Unlock()
+	return nil
+}
+
+// DeregisterSerializer removes the serializer of type t.
+func DeregisterSerializer(t reflect.Type) {
+	serializerRegistry.Lock()
+	delete(serializerRegistry.Index, t)
+	serializerRegistry.Unlock()
+}
+
+func getSerializer(v interface{}) SerializerContextFunc {
+	if v == nil {
+		return nil
+	}
+	serializerRegistry.RLock()
+	defer serializerRegistry.RUnlock()
+	return serializerRegistry.Index[reflect.TypeOf(v)]
+}
+
 // Serial converts any supported value to its equivalent string for as a Trino parameter
 // See https://trino.io/docs/current/language/types.html
 func Serial(v interface{}) (string, error) {
+	return serial(context.Background(), SessionInfo{}, v)
+}
+
+// SerialContext converts a value to its SQL literal like Serial, passing the
+// session of conn, with the session properties of ctx, to the serializers
+// registered with RegisterSerializerContext. The driver serializes query
+// arguments with it. conn may be nil, for an empty session.
+func SerialContext(ctx context.Context, conn *Conn, v interface{}) (string, error) {
+	var session SessionInfo
+	if conn != nil {
+		hs := make(http.Header)
+		if properties, ok := ctx.Value(sessionPropertiesContextKey).(map[string]string); ok && len(properties) > 0 {
+			hs.Set(trinoSessionHeader, conn.sessionHeader(hs, properties))
+		}
+		session = conn.serialSession(hs)
+	}
+	return serial(ctx, session, v)
+}
+
+// serial converts a value to its SQL literal, in the given session.
+func serial(ctx context.Context, session SessionInfo, v interface{}) (string, error) {
+	if fn := getSerializer(v); fn != nil {
+		return fn(ctx, session, v)
+	}
 	switch x := v.(type) {
 	case nil:
 		return "NULL", nil
@@ -133,6 +261,10 @@ func Serial(v interface{}) (string, error) {
 		if _, err := strconv.ParseFloat(string(x), 64); err != nil {
 			return "", err
 		}
+		// ParseFloat accepts NaN, Inf and hexadecimal numbers, which aren't number literals
+		if !isNumberLiteral(string(x)) {
+			return "", fmt.Errorf("trino: invalid Numeric value: %q", string(x))
+		}
 		return string(x), nil
 
 		// note byte and uint are not supported, this is because byte is an alias for uint8
@@ -145,14 +277,14 @@ func Serial(v interface{}) (string, error) {
 		return strconv.FormatBool(x), nil
 
 	case string:
-		return "'" + strings.Replace(x, "'", "''", -1) + "'", nil
+		return quoteString(x), nil
 
 		// TODO - []byte should probably be matched to 'VARBINARY' in trino
 	case []byte:
 		return "", UnsupportedArgError{"[]byte"}
 
 	case trinoDate:
-		return fmt.Sprintf("DATE '%04d-%02d-%02d'", x.year, x.month, x.day), nil
+		return fmt.Sprintf("DATE '%s-%02d-%02d'", formatYear(x.year), x.month, x.day), nil
 	case trinoTime:
 		return fmt.Sprintf("TIME '%02d:%02d:%02d.%09d'", x.hour, x.minute, x.second, x.nanosecond), nil
 	case trinoTimeTz:
@@ -182,7 +314,7 @@ func Serial(v interface{}) (string, error) {
 			slice[i] = x.Index(i).Interface()
 		}
 
-		return serialSlice(slice)
+		return serialSlice(ctx, session, slice)
 	}
 
 	if reflect.TypeOf(v).Kind() == reflect.Map {
@@ -195,11 +327,16 @@ func Serial(v interface{}) (string, error) {
 	return "", UnsupportedArgError{fmt.Sprintf("%T", v)}
 }
 
-func serialSlice(v []interface{}) (string, error) {
+// quoteString returns a SQL string literal of s.
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func serialSlice(ctx context.Context, session SessionInfo, v []interface{}) (string, error) {
 	ss := make([]string, len(v))
 
 	for i, x := range v {
-		s, err := Serial(x)
+		s, err := serial(ctx, session, x)
 		if err != nil {
 			return "", err
 		}
@@ -208,3 +345,170 @@ func serialSlice(v []interface{}) (string, error) {
 
 	return "ARRAY[" + strings.Join(ss, ", ") + "]", nil
 }
+
+// ValidateLiteral checks that s is a literal as serialized by Serial: NULL,
+// true, false, a number, a quoted string, a DATE, TIME, TIMESTAMP or DECIMAL
+// literal, or an ARRAY of literals. Anything else, like an expression or a
+// comment, returns an error wrapping ErrInvalidLiteral.
+//
+// Connections with validate_literals in their DSN validate every query argument
+// with it, so that a serializer bug can't change the statement submitted to the
+// server.
+func ValidateLiteral(s string) error {
+	p := literalParser{s: s}
+	if err := p.literal(); err != nil {
+		return err
+	}
+	if p.pos != len(s) {
+		return p.errorf("unexpected %q", s[p.pos:])
+	}
+	return nil
+}
+
+// isNumberLiteral reports whether s is a number literal, like -1.5 or 2e10.
+func isNumberLiteral(s string) bool {
+	p := literalParser{s: s}
+	return p.number() == nil && p.pos == len(s)
+}
+
+// literalParser parses the literals of ValidateLiteral.
+type literalParser struct {
+	s   string
+	pos int
+}
+
+func (p *literalParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w %q at offset %d: %s", ErrInvalidLiteral, p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+// peek returns the next byte, or 0 at the end of the literal.
+func (p *literalParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+// spaces skips spaces, and reports whether there were any.
+func (p *literalParser) spaces() bool {
+	start := p.pos
+	for p.peek() == ' ' {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *literalParser) literal() error {
+	switch c := p.peek(); {
+	case c == '\'':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || isDigit(c):
+		return p.number()
+	}
+	start := p.pos
+	for c := p.peek(); (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'); c = p.peek() {
+		p.pos++
+	}
+	word := p.s[start:p.pos]
+	switch strings.ToUpper(word) {
+	case "NULL", "TRUE", "FALSE":
+		return nil
+	case "DATE", "TIME", "TIMESTAMP", "DECIMAL":
+		if !p.spaces() || p.peek() != '\'' {
+			return p.errorf("expected a string after %s", word)
+		}
+		return p.string()
+	case "ARRAY":
+		return p.array()
+	case "":
+		return p.errorf("expected a literal")
+	}
+	p.pos = start
+	return p.errorf("unexpected %q", word)
+}
+
+// string parses a quoted string, whose quotes are escaped by doubling them.
+func (p *literalParser) string() error {
+	start := p.pos
+	p.pos++
+	for {
+		i := strings.IndexByte(p.s[p.pos:], '\'')
+		if i < 0 {
+			p.pos = start
+			return p.errorf("unterminated string")
+		}
+		p.pos += i + 1
+		if p.peek() != '\'' {
+			return nil
+		}
+		p.pos++
+	}
+}
+
+// number parses a decimal number, with an optional sign and exponent.
+func (p *literalParser) number() error {
+	if c := p.peek(); c == '-' || c == '+' {
+		p.pos++
+	}
+	digits := p.digits()
+	if p.peek() == '.' {
+		p.pos++
+		digits += p.digits()
+	}
+	if digits == 0 {
+		return p.errorf("expected a number")
+	}
+	if c := p.peek(); c == 'e' || c == 'E' {
+		p.pos++
+		if c := p.peek(); c == '-' || c == '+' {
+			p.pos++
+		}
+		if p.digits() == 0 {
+			return p.errorf("expected an exponent")
+		}
+	}
+	return nil
+}
+
+// digits skips decimal digits, and returns their number.
+func (p *literalParser) digits() int {
+	start := p.pos
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	return p.pos - start
+}
+
+// array parses the elements of an ARRAY, after the keyword.
+func (p *literalParser) array() error {
+	p.spaces()
+	if p.peek() != '[' {
+		return p.errorf("expected [ after ARRAY")
+	}
+	p.pos++
+	p.spaces()
+	if p.peek() == ']' {
+		p.pos++
+		return nil
+	}
+	for {
+		if err := p.literal(); err != nil {
+			return err
+		}
+		p.spaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+			p.spaces()
+		case ']':
+			p.pos++
+			return nil
+		default:
+			return p.errorf("expected , or ] in ARRAY")
+		}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
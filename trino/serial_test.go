@@ -15,10 +15,17 @@
 package trino
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
 )
 
 func TestSerial(t *testing.T) {
@@ -110,6 +117,16 @@ func TestSerial(t *testing.T) {
 			value:         Numeric("not-a-number"),
 			expectedError: true,
 		},
+		{
+			name:          "NaN Numeric",
+			value:         Numeric("NaN"),
+			expectedError: true,
+		},
+		{
+			name:          "hexadecimal Numeric",
+			value:         Numeric("0x1p-2"),
+			expectedError: true,
+		},
 		{
 			name:           "bool true",
 			value:          true,
@@ -125,6 +142,16 @@ func TestSerial(t *testing.T) {
 			value:          Date(2017, 7, 10),
 			expectedSerial: "DATE '2017-07-10'",
 		},
+		{
+			name:           "date before year 0",
+			value:          Date(-44, 3, 15),
+			expectedSerial: "DATE '-0044-03-15'",
+		},
+		{
+			name:           "date after year 9999",
+			value:          Date(12345, 6, 7),
+			expectedSerial: "DATE '12345-06-07'",
+		},
 		{
 			name:           "time without timezone",
 			value:          Time(11, 34, 25, 123456),
@@ -145,6 +172,11 @@ func TestSerial(t *testing.T) {
 			value:          Timestamp(2017, 7, 10, 11, 34, 25, 123456),
 			expectedSerial: "TIMESTAMP '2017-07-10 11:34:25.000123456'",
 		},
+		{
+			name:           "timestamp before year 0",
+			value:          Timestamp(-1, 12, 31, 23, 59, 59, 0),
+			expectedSerial: "TIMESTAMP '-0001-12-31 23:59:59'",
+		},
 		{
 			name:           "timestamp with time zone in Fixed Zone",
 			value:          time.Date(2017, 7, 10, 11, 34, 25, 123456, time.FixedZone("test zone", +2*3600)),
@@ -160,6 +192,11 @@ func TestSerial(t *testing.T) {
 			value:          time.Date(2017, 7, 10, 11, 34, 25, 123456, time.UTC),
 			expectedSerial: "TIMESTAMP '2017-07-10 11:34:25.000123456 Z'",
 		},
+		{
+			name:           "timestamp with time zone before year 0",
+			value:          time.Date(-44, 3, 15, 12, 0, 0, 0, time.UTC),
+			expectedSerial: "TIMESTAMP '-0044-03-15 12:00:00 Z'",
+		},
 		{
 			name:           "nil",
 			value:          nil,
@@ -209,3 +246,215 @@ func TestSerial(t *testing.T) {
 		})
 	}
 }
+
+type testMoney struct {
+	Cents    int64
+	Currency string
+}
+
+func TestRegisterSerializer(t *testing.T) {
+	moneyType := reflect.TypeOf(testMoney{})
+	require.NoError(t, RegisterSerializer(moneyType, func(v interface{}) (string, error) {
+		m := v.(testMoney)
+		currency, err := Serial(m.Currency)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("ROW(DECIMAL '%d.%02d', %s)", m.Cents/100, m.Cents%100, currency), nil
+	}))
+	t.Cleanup(func() {
+		DeregisterSerializer(moneyType)
+	})
+	require.Error(t, RegisterSerializer(moneyType, nil))
+
+	s, err := Serial(testMoney{Cents: 1050, Currency: "EUR"})
+	require.NoError(t, err)
+	require.Equal(t, "ROW(DECIMAL '10.50', 'EUR')", s)
+
+	s, err = Serial([]testMoney{{Cents: 1, Currency: "USD"}})
+	require.NoError(t, err)
+	require.Equal(t, "ARRAY[ROW(DECIMAL '0.01', 'USD')]", s)
+
+	server := newMockServer(t)
+	server.HandleMatch(anyStatement, trinomock.Response{})
+	db := openTestDB(t, server.DSN()+"?explicitPrepare=false")
+	_, err = db.Exec("SELECT ?", testMoney{Cents: 200, Currency: "GBP"})
+	require.NoError(t, err)
+	require.Equal(t, "EXECUTE IMMEDIATE 'SELECT ?' USING ROW(DECIMAL '2.00', 'GBP')", lastSubmitted(t, server).Body)
+
+	DeregisterSerializer(moneyType)
+	_, err = Serial(testMoney{})
+	require.Error(t, err)
+}
+
+type testLocalTime struct {
+	Hour int
+}
+
+func TestRegisterSerializerContext(t *testing.T) {
+	localTimeType := reflect.TypeOf(testLocalTime{})
+	require.NoError(t, RegisterSerializerContext(localTimeType, func(ctx context.Context, session SessionInfo, v interface{}) (string, error) {
+		loc, err := time.LoadLocation(session.TimeZone)
+		if err != nil {
+			return "", err
+		}
+		s, err := Serial(time.Date(2024, 1, 2, v.(testLocalTime).Hour, 0, 0, 0, loc))
+		if err != nil {
+			return "", err
+		}
+		return s + " /* " + session.Properties["query_priority"] + " */", nil
+	}))
+	t.Cleanup(func() {
+		DeregisterSerializer(localTimeType)
+	})
+	require.Error(t, RegisterSerializerContext(localTimeType, nil))
+
+	s, err := Serial(testLocalTime{Hour: 3})
+	require.NoError(t, err)
+	require.Equal(t, "TIMESTAMP '2024-01-02 03:00:00 Z' /*  */", s)
+
+	server := newMockServer(t)
+	server.HandleMatch(anyStatement, trinomock.Response{})
+	db := openTestDB(t, server.DSN()+"?explicitPrepare=false&session_properties=query_priority=1")
+	_, err = db.Exec("SELECT ?", []testLocalTime{{Hour: 3}})
+	require.NoError(t, err)
+	require.Equal(t, "EXECUTE IMMEDIATE 'SELECT ?' USING ARRAY[TIMESTAMP '2024-01-02 03:00:00 Z' /* 1 */]", lastSubmitted(t, server).Body)
+
+	ctx := WithSessionProperties(context.Background(), map[string]string{"query_priority": "2"})
+	_, err = db.ExecContext(ctx, "SELECT ?", testLocalTime{Hour: 3}, sql.Named(trinoTimeZoneHeader, "Asia/Tokyo"))
+	require.NoError(t, err)
+	require.Equal(t, "EXECUTE IMMEDIATE 'SELECT ?' USING TIMESTAMP '2024-01-02 03:00:00 +09:00' /* 2 */", lastSubmitted(t, server).Body)
+
+	_, err = db.Exec("SELECT ?", testLocalTime{Hour: 3}, sql.Named(trinoTimeZoneHeader, "Nowhere/Unknown"))
+	require.Error(t, err)
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, conn.Close())
+	})
+	require.NoError(t, conn.Raw(func(driverConn interface{}) error {
+		s, err = SerialContext(ctx, driverConn.(*Conn), testLocalTime{Hour: 3})
+		return err
+	}))
+	require.Equal(t, "TIMESTAMP '2024-01-02 03:00:00 Z' /* 2 */", s)
+}
+
+// hostileStrings are strings trying to end the literal they're serialized in.
+var hostileStrings = []string{
+	"",
+	"'",
+	"''",
+	"' OR 1=1 --",
+	"'; DROP TABLE users; --",
+	"\\'; SELECT 1; --",
+	"*/ SELECT 1 /*",
+	"a\x00b",
+	"line\nbreak\r\n",
+	"\u2019 OR \u2018",
+	"\xff\xfe",
+	"?",
+	"ARRAY[1]', '2",
+}
+
+func TestValidateLiteral(t *testing.T) {
+	for _, literal := range []string{
+		"NULL",
+		"true",
+		"FALSE",
+		"0",
+		"-12",
+		"+1.5",
+		".5",
+		"5.",
+		"1e10",
+		"-2.5E-3",
+		"''",
+		"'it''s'",
+		"DATE '2017-07-10'",
+		"TIMESTAMP '2017-07-10 01:02:03.000000004 Z'",
+		"DECIMAL  '1.50'",
+		"ARRAY[]",
+		"ARRAY[1, 2]",
+		"ARRAY[ARRAY['a', NULL], ARRAY[]]",
+	} {
+		assert.NoError(t, ValidateLiteral(literal), literal)
+	}
+	for _, literal := range []string{
+		"",
+		" 1",
+		"1 ",
+		"1; SELECT 1",
+		"1 -- comment",
+		"1 /* comment */",
+		"'a' || 'b'",
+		"'unterminated",
+		"'a''",
+		"'a' 'b'",
+		"-",
+		"1e",
+		"0x10",
+		"NaN",
+		"Infinity",
+		"abs(-1)",
+		"CAST(1 AS VARCHAR)",
+		"DATE'2017-07-10'",
+		"DATE 1",
+		"ARRAY[1,",
+		"ARRAY[1 2]",
+		"ARRAY[1]]",
+		"ARRAY(SELECT 1)",
+		"(1)",
+		"1) OR (1=1",
+	} {
+		err := ValidateLiteral(literal)
+		assert.ErrorIs(t, err, ErrInvalidLiteral, literal)
+	}
+
+	for _, hostile := range hostileStrings {
+		for _, v := range []interface{}{hostile, []string{hostile, hostile}, [][]string{{hostile}}} {
+			s, err := Serial(v)
+			require.NoError(t, err)
+			assert.NoError(t, ValidateLiteral(s), s)
+		}
+		s, err := Serial(hostile)
+		require.NoError(t, err)
+		assert.Equal(t, hostile, unquoteString(s))
+
+		_, err = Serial(Numeric(hostile))
+		assert.Error(t, err, hostile)
+	}
+}
+
+// unquoteString returns the value of a string literal.
+func unquoteString(s string) string {
+	return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+}
+
+func TestValidateLiterals(t *testing.T) {
+	brokenType := reflect.TypeOf(testMoney{})
+	require.NoError(t, RegisterSerializer(brokenType, func(v interface{}) (string, error) {
+		// doesn't escape the quotes of the currency
+		return "'" + v.(testMoney).Currency + "'", nil
+	}))
+	t.Cleanup(func() {
+		DeregisterSerializer(brokenType)
+	})
+
+	server := newMockServer(t)
+	server.HandleMatch(anyStatement, trinomock.Response{})
+
+	db := openTestDB(t, server.DSN()+"?explicitPrepare=false&validate_literals=true")
+	hostile := testMoney{Currency: "EUR'; DROP TABLE users; --"}
+	_, err := db.Exec("SELECT ?", hostile)
+	assert.ErrorIs(t, err, ErrInvalidLiteral)
+	_, err = db.Exec("SELECT ?, ?", 1, hostileStrings)
+	require.NoError(t, err)
+	require.Len(t, submitted(server), 1)
+
+	db = openTestDB(t, server.DSN()+"?explicitPrepare=false")
+	_, err = db.Exec("SELECT ?", hostile)
+	require.NoError(t, err)
+	require.Len(t, submitted(server), 2)
+	assert.Equal(t, "EXECUTE IMMEDIATE 'SELECT ?' USING 'EUR'; DROP TABLE users; --'", lastSubmitted(t, server).Body)
+}
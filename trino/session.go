@@ -0,0 +1,445 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithSessionProperties returns a context executing queries with the given
+// session properties, like query_max_run_time or hive.insert_existing_partitions_behavior,
+// in addition to those of the connection, which they override. Unlike SET SESSION,
+// they only apply to the queries using the context, and not to the next queries of
+// the connection.
+func WithSessionProperties(ctx context.Context, properties map[string]string) context.Context {
+	return context.WithValue(ctx, sessionPropertiesContextKey, properties)
+}
+
+// QueryScript runs the last statement of a script, after its leading SET SESSION
+// statements, which only apply to this query, as with WithSessionProperties:
+//
+//	rows, err := trino.QueryScript(ctx, db, `
+//		SET SESSION query_max_run_time = '10m';
+//		SET SESSION join_distribution_type = 'BROADCAST';
+//		SELECT * FROM orders JOIN customers USING (custkey)`)
+//
+// The script is parsed with ParseSessionScript, and args are passed to the query.
+func QueryScript(ctx context.Context, db *sql.DB, script string, args ...interface{}) (*sql.Rows, error) {
+	properties, statement, err := ParseSessionScript(script)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(WithSessionProperties(ctx, properties), statement, args...)
+}
+
+// ExecScript is like QueryScript, for statements not returning rows.
+func ExecScript(ctx context.Context, db *sql.DB, script string, args ...interface{}) (sql.Result, error) {
+	properties, statement, err := ParseSessionScript(script)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(WithSessionProperties(ctx, properties), statement, args...)
+}
+
+// SessionPolicy restricts the session properties queries may set, with
+// WithSessionProperties, the X-Trino-Session header or SET SESSION, on the
+// connections of a Connector. The session properties of the DSN are always
+// allowed. Names can end with a wildcard, like hive.*, to match all the
+// session properties of a catalog. SET SESSION statements whose property can't
+// be parsed are rejected, and so are the session properties set by the server
+// in its responses.
+type SessionPolicy struct {
+	// Allowed are the session properties queries may set, or nil to allow all
+	// those not Denied.
+	Allowed []string
+	// Denied are the session properties queries may not set, even if Allowed.
+	Denied []string
+}
+
+// SessionPropertyError is returned when a query sets a session property not
+// allowed by the SessionPolicy of the Connector.
+type SessionPropertyError struct {
+	Property string
+}
+
+func (e *SessionPropertyError) Error() string {
+	return fmt.Sprintf("trino: session property %s is not allowed", e.Property)
+}
+
+// check returns a SessionPropertyError if the policy doesn't allow setting
+// the session property name.
+func (p *SessionPolicy) check(name string) error {
+	if p == nil {
+		return nil
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if (p.Allowed != nil && !matchSessionProperty(p.Allowed, name)) || matchSessionProperty(p.Denied, name) {
+		return &SessionPropertyError{Property: name}
+	}
+	return nil
+}
+
+// matchSessionProperty reports whether one of the patterns matches the session property name.
+func matchSessionProperty(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == name || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(name, pattern[:len(pattern)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSessionProperties checks that the session policy allows the session
+// properties set by a query, with its headers hs, the properties of its context,
+// and its statement.
+func (c *Conn) checkSessionProperties(hs http.Header, properties map[string]string, statement string) error {
+	if c.sessionPolicy == nil {
+		return nil
+	}
+	for _, v := range hs.Values(trinoSessionHeader) {
+		for _, entry := range strings.Split(v, ",") {
+			if name, _, _ := strings.Cut(entry, "="); strings.TrimSpace(name) != "" {
+				if err := c.sessionPolicy.check(name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for name := range properties {
+		if err := c.sessionPolicy.check(name); err != nil {
+			return err
+		}
+	}
+	statement = stripComments(statement)
+	if name, _, ok := parseSetSession(statement); ok {
+		return c.sessionPolicy.check(name)
+	}
+	// fail closed on the SET SESSION statements whose property can't be parsed
+	if setSessionPrefixRegexp.MatchString(statement) && !setSessionAuthorizationRegexp.MatchString(statement) {
+		return fmt.Errorf("trino: cannot check the session property set by %q against the session policy", statement)
+	}
+	return nil
+}
+
+// checkSetSession checks that the session policy allows the session property
+// set by the X-Trino-Set-Session header of a response.
+func (c *Conn) checkSetSession(v string) error {
+	if c.sessionPolicy == nil {
+		return nil
+	}
+	name, _ := splitSessionEntry(v)
+	return c.sessionPolicy.check(name)
+}
+
+const sessionIdentifier = `(?:[a-z_][a-z0-9_]*|"(?:[^"]|"")+")`
+
+var (
+	setSessionRegexp              = regexp.MustCompile(`(?is)^SET\s+SESSION\s+(` + sessionIdentifier + `(?:\s*\.\s*` + sessionIdentifier + `)?)\s*=\s*(.+)$`)
+	sessionIdentifierRegexp       = regexp.MustCompile(`(?i)` + sessionIdentifier)
+	setSessionPrefixRegexp        = regexp.MustCompile(`(?is)^SET\s+SESSION\b`)
+	setSessionAuthorizationRegexp = regexp.MustCompile(`(?is)^SET\s+SESSION\s+AUTHORIZATION\b`)
+)
+
+// parseSetSession returns the name of the session property set by a SET SESSION
+// statement, without comments, unquoting its identifiers, and its value.
+func parseSetSession(statement string) (name, value string, ok bool) {
+	m := setSessionRegexp.FindStringSubmatch(statement)
+	if m == nil {
+		return "", "", false
+	}
+	parts := sessionIdentifierRegexp.FindAllString(m[1], -1)
+	for i, part := range parts {
+		if strings.HasPrefix(part, `"`) {
+			parts[i] = strings.ReplaceAll(part[1:len(part)-1], `""`, `"`)
+		}
+	}
+	return strings.ToLower(strings.Join(parts, ".")), m[2], true
+}
+
+// SessionChangeKind is the kind of a SessionChange.
+type SessionChangeKind string
+
+const (
+	// SessionChangeCatalog is a change of the catalog, by USE.
+	SessionChangeCatalog SessionChangeKind = "catalog"
+	// SessionChangeSchema is a change of the schema, by USE.
+	SessionChangeSchema SessionChangeKind = "schema"
+	// SessionChangeSetProperty sets a session property, by SET SESSION.
+	SessionChangeSetProperty SessionChangeKind = "set_property"
+	// SessionChangeResetProperty resets a session property, by RESET SESSION.
+	SessionChangeResetProperty SessionChangeKind = "reset_property"
+	// SessionChangeAddPrepared adds a prepared statement, by PREPARE,
+	// unless using explicitPrepare=false.
+	SessionChangeAddPrepared SessionChangeKind = "add_prepared"
+	// SessionChangeDeallocatePrepared removes a prepared statement, by
+	// DEALLOCATE PREPARE.
+	SessionChangeDeallocatePrepared SessionChangeKind = "deallocate_prepared"
+	// SessionChangeSetAuthorizationUser sets the authorization user, by SET SESSION AUTHORIZATION.
+	SessionChangeSetAuthorizationUser SessionChangeKind = "set_authorization_user"
+	// SessionChangeResetAuthorizationUser resets the authorization user, by RESET SESSION AUTHORIZATION.
+	SessionChangeResetAuthorizationUser SessionChangeKind = "reset_authorization_user"
+)
+
+// SessionChange describes a change of the session state of a connection, made
+// by a statement and applied from the headers of its response, passed to the
+// SessionChangeCallback of the Config.
+type SessionChange struct {
+	Kind SessionChangeKind
+	// Name is the name of the session property or the prepared statement,
+	// or empty for the other kinds.
+	Name string
+	// Value is the new catalog, schema or authorization user, the value of the
+	// session property, or the prepared statement, or empty when it's reset
+	// or deallocated.
+	Value string
+}
+
+// sessionChanged passes the change to the SessionChangeCallback, if any.
+func (c *Conn) sessionChanged(kind SessionChangeKind, name, value string) {
+	if c.sessionChangeCallback != nil {
+		c.sessionChangeCallback(SessionChange{Kind: kind, Name: name, Value: value})
+	}
+}
+
+// splitSessionEntry splits a name=value entry of the session or prepared
+// statement headers, unescaping the value.
+func splitSessionEntry(v string) (name, value string) {
+	name, value, _ = strings.Cut(v, "=")
+	if unescaped, err := url.QueryUnescape(value); err == nil {
+		value = unescaped
+	}
+	return strings.TrimSpace(name), value
+}
+
+// ParseSessionScript splits a script of statements separated by semicolons into
+// the session properties set by its leading SET SESSION statements, and the
+// statement following them, which must be the last one. Property values are
+// string literals, numbers or booleans, and string literals are unquoted.
+func ParseSessionScript(script string) (properties map[string]string, statement string, err error) {
+	statements := splitStatements(script)
+	if len(statements) == 0 {
+		return nil, "", fmt.Errorf("trino: script has no statement")
+	}
+	properties = make(map[string]string)
+	for _, s := range statements[:len(statements)-1] {
+		name, value, ok := parseSetSession(stripComments(s))
+		if !ok {
+			return nil, "", fmt.Errorf("trino: script must only have SET SESSION statements before its last statement, got %q", s)
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "'") {
+			if len(value) < 2 || !strings.HasSuffix(value, "'") || strings.Contains(strings.ReplaceAll(value[1:len(value)-1], "''", ""), "'") {
+				return nil, "", fmt.Errorf("trino: invalid value of session property %s: %s", name, value)
+			}
+			value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		} else if strings.ContainsAny(value, " \t\r\n()'\"") {
+			return nil, "", fmt.Errorf("trino: value of session property %s must be a literal, got %s", name, value)
+		}
+		properties[name] = value
+	}
+	statement = statements[len(statements)-1]
+	if setSessionRegexp.MatchString(stripComments(statement)) {
+		return nil, "", fmt.Errorf("trino: script has no statement after SET SESSION")
+	}
+	return properties, statement, nil
+}
+
+// splitStatements splits a script on semicolons outside of quoted strings,
+// identifiers and comments, and returns its non-empty statements, trimmed.
+func splitStatements(script string) []string {
+	var statements []string
+	add := func(s string) {
+		if s = strings.TrimSpace(s); stripComments(s) != "" {
+			statements = append(statements, s)
+		}
+	}
+	start := 0
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"':
+			if end := strings.IndexByte(script[i+1:], c); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(script)
+			}
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == ';':
+			add(script[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(script) {
+		add(script[start:])
+	}
+	return statements
+}
+
+var commentRegexp = regexp.MustCompile(`(?s)--[^\n]*(?:\n|$)|/\*.*?\*/`)
+
+// stripComments removes the comments around a statement, for parsing it.
+// Comments inside its quoted strings are kept.
+func stripComments(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+		loc := commentRegexp.FindStringIndex(s)
+		if loc == nil || (loc[0] != 0 && loc[1] != len(s)) {
+			return s
+		}
+		s = s[:loc[0]] + s[loc[1]:]
+	}
+}
+
+// sessionHeader returns the value of the session header of a query, with the
+// session properties of the query overriding those of its headers, or of the
+// connection.
+func (c *Conn) sessionHeader(hs http.Header, properties map[string]string) string {
+	values := hs.Values(trinoSessionHeader)
+	if len(values) == 0 {
+		values = c.httpHeaders.Values(trinoSessionHeader)
+	}
+	var entries []string
+	for _, v := range values {
+		for _, entry := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(entry, "=")
+			if _, ok := properties[strings.TrimSpace(name)]; !ok && strings.TrimSpace(entry) != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entries = append(entries, name+"="+url.QueryEscape(properties[name]))
+	}
+	return strings.Join(entries, ",")
+}
+
+// serialSession returns the session of a query with the headers hs, which
+// override those of the connection, to serialize its arguments.
+func (c *Conn) serialSession(hs http.Header) SessionInfo {
+	session := SessionInfo{
+		TimeZone:      hs.Get(trinoTimeZoneHeader),
+		Properties:    make(map[string]string),
+		ServerVersion: c.serverVersion,
+	}
+	if session.TimeZone == "" {
+		session.TimeZone = c.httpHeaders.Get(trinoTimeZoneHeader)
+	}
+	values := hs.Values(trinoSessionHeader)
+	if len(values) == 0 {
+		values = c.httpHeaders.Values(trinoSessionHeader)
+	}
+	for _, v := range values {
+		for _, entry := range strings.Split(v, ",") {
+			name, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				continue
+			}
+			if unescaped, err := url.QueryUnescape(value); err == nil {
+				value = unescaped
+			}
+			session.Properties[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return session
+}
+
+// executionTimeMargin is added to query_max_execution_time for the deadline of
+// queries, to leave time to the server to fail them, and for their queued and
+// planning time, which it doesn't count. It's changed in tests.
+var executionTimeMargin = time.Minute
+
+// executionTimeout returns the timeout of a query with the given headers, from
+// its query_max_execution_time session property, if it's set and
+// execution_time_deadline isn't disabled.
+func (c *Conn) executionTimeout(hs http.Header) (time.Duration, bool) {
+	if !c.executionTimeDeadline {
+		return 0, false
+	}
+	v, ok := c.serialSession(hs).Properties["query_max_execution_time"]
+	if !ok {
+		return 0, false
+	}
+	d, err := parseTrinoDuration(v)
+	if err != nil || d > math.MaxInt64-executionTimeMargin {
+		// the server rejects invalid durations, and doesn't limit the query
+		// much with huge ones
+		return 0, false
+	}
+	return d + executionTimeMargin, true
+}
+
+var trinoDurationRegexp = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*([a-zA-Z]+)\s*$`)
+
+// parseTrinoDuration parses a duration in the format of Trino session
+// properties, like 10m, 1.5h or 2d.
+func parseTrinoDuration(s string) (time.Duration, error) {
+	m := trinoDurationRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("trino: invalid duration: %q", s)
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("trino: invalid duration: %q", s)
+	}
+	var unit time.Duration
+	switch m[2] {
+	case "ns":
+		unit = time.Nanosecond
+	case "us":
+		unit = time.Microsecond
+	case "ms":
+		unit = time.Millisecond
+	case "s":
+		unit = time.Second
+	case "m":
+		unit = time.Minute
+	case "h":
+		unit = time.Hour
+	case "d":
+		unit = 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("trino: invalid duration: %q", s)
+	}
+	if value*float64(unit) >= math.MaxInt64 {
+		return 0, fmt.Errorf("trino: duration out of range: %q", s)
+	}
+	return time.Duration(value * float64(unit)), nil
+}
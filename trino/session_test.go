@@ -0,0 +1,259 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+func TestParseSessionScript(t *testing.T) {
+	properties, statement, err := ParseSessionScript(`
+		-- limits
+		SET SESSION query_max_run_time = '10m';
+		set session hive.insert_existing_partitions_behavior='OVERWRITE' ;
+		SET SESSION query_priority = 2; /* the query */
+		SELECT 'a;b', "c;d" FROM t -- trailing; comment
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"query_max_run_time":                       "10m",
+		"hive.insert_existing_partitions_behavior": "OVERWRITE",
+		"query_priority":                           "2",
+	}, properties)
+	assert.Equal(t, `/* the query */
+		SELECT 'a;b', "c;d" FROM t -- trailing; comment`, statement)
+
+	properties, statement, err = ParseSessionScript("SELECT 1;")
+	require.NoError(t, err)
+	assert.Empty(t, properties)
+	assert.Equal(t, "SELECT 1", statement)
+
+	properties, _, err = ParseSessionScript("SET SESSION comment = 'it''s'; SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, "it's", properties["comment"])
+
+	for _, script := range []string{
+		"",
+		" ; -- nothing",
+		"SET SESSION query_priority = 2",
+		"SELECT 1; SELECT 2",
+		"SET SESSION query_priority = 2; SELECT 1; SELECT 2",
+		"SET SESSION query_priority = abs(-2); SELECT 1",
+		"SET SESSION query_max_run_time = '10' 'm'; SELECT 1",
+	} {
+		_, _, err := ParseSessionScript(script)
+		assert.Error(t, err, script)
+	}
+}
+
+func TestQueryScript(t *testing.T) {
+	server := newMockServer(t)
+	server.HandleMatch(anyStatement, trinomock.Response{
+		Columns: []trinomock.Column{{Name: "_col0", Type: "integer"}},
+		Rows:    [][]interface{}{{1}},
+	})
+
+	db := openTestDB(t, server.DSN()+"?session_properties=query_max_run_time=1h,query_priority=1")
+	ctx := context.Background()
+
+	_, err := ExecScript(ctx, db, "SELECT 1")
+	require.NoError(t, err)
+	_, err = db.ExecContext(WithSessionProperties(ctx, map[string]string{"query_priority": "3"}), "SELECT 1",
+		sql.Named(trinoSessionHeader, "query_priority=4,join_distribution_type=BROADCAST"))
+	require.NoError(t, err)
+	rows, err := QueryScript(ctx, db, "SET SESSION query_priority = 2; SET SESSION time_zone_id = 'America/New_York'; SELECT ?", 1)
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+
+	var queries, sessions []string
+	for _, r := range submitted(server) {
+		queries = append(queries, r.Body)
+		sessions = append(sessions, r.Header.Get(trinoSessionHeader))
+	}
+	assert.Equal(t, []string{"SELECT 1", "SELECT 1", "EXECUTE _trino_go USING 1"}, queries)
+	assert.Equal(t, []string{
+		"query_max_run_time=1h,query_priority=1",
+		"join_distribution_type=BROADCAST,query_priority=3",
+		"query_max_run_time=1h,query_priority=2,time_zone_id=America%2FNew_York",
+	}, sessions)
+}
+
+func TestSessionPolicy(t *testing.T) {
+	server := newMockServer(t)
+	server.HandleMatch(anyStatement, trinomock.Response{})
+
+	connector, err := NewConnector(&Config{
+		ServerURI:         server.DSN(),
+		SessionProperties: map[string]string{"query_max_run_time": "1h"},
+		SessionPolicy: &SessionPolicy{
+			Allowed: []string{"query_priority", "Hive.*"},
+			Denied:  []string{"hive.insert_existing_partitions_behavior"},
+		},
+	})
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	ctx := context.Background()
+
+	_, err = db.ExecContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+	_, err = db.ExecContext(WithSessionProperties(ctx, map[string]string{"query_priority": "2", "hive.parquet_use_column_names": "true"}), "SELECT 1")
+	require.NoError(t, err)
+	_, err = ExecScript(ctx, db, "SET SESSION QUERY_PRIORITY = 3; SELECT 1")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "SET SESSION hive.compression_codec = 'ZSTD'")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `SET SESSION "hive"."compression_codec" = 'ZSTD'`)
+	require.NoError(t, err)
+	assert.Len(t, submitted(server), 5)
+
+	for _, tc := range []struct {
+		ctx      context.Context
+		query    string
+		args     []interface{}
+		property string
+	}{
+		{WithSessionProperties(ctx, map[string]string{"query_max_run_time": "1d"}), "SELECT 1", nil, "query_max_run_time"},
+		{ctx, "SELECT 1", []interface{}{sql.Named(trinoSessionHeader, "query_priority=1,join_distribution_type=BROADCAST")}, "join_distribution_type"},
+		{ctx, "/* tuning */ SET SESSION hive.insert_existing_partitions_behavior = 'OVERWRITE'", nil, "hive.insert_existing_partitions_behavior"},
+		{ctx, "set session iceberg.compression_codec = 'ZSTD'", nil, "iceberg.compression_codec"},
+		{ctx, `SET SESSION "query_max_memory" = '1TB'`, nil, "query_max_memory"},
+		{ctx, `SET SESSION "Query_Max_Memory" = '1TB'`, nil, "query_max_memory"},
+		{ctx, `SET SESSION hive."insert_existing_partitions_behavior" = 'OVERWRITE'`, nil, "hive.insert_existing_partitions_behavior"},
+		{ctx, `SET SESSION "hive" . "insert_existing_partitions_behavior" = 'OVERWRITE'`, nil, "hive.insert_existing_partitions_behavior"},
+	} {
+		_, err = db.ExecContext(tc.ctx, tc.query, tc.args...)
+		var propertyErr *SessionPropertyError
+		require.ErrorAs(t, err, &propertyErr, tc.query)
+		assert.Equal(t, tc.property, propertyErr.Property)
+	}
+	_, err = ExecScript(ctx, db, "SET SESSION join_distribution_type = 'BROADCAST'; SELECT 1")
+	assert.Error(t, err)
+	for _, query := range []string{
+		"SET SESSION query_max_memory '1TB'",
+		`SET SESSION "query_max_memory = '1TB'`,
+		"SET SESSION hive.a.b = 'c'",
+	} {
+		_, err = db.ExecContext(ctx, query)
+		assert.ErrorContains(t, err, "cannot check the session property", query)
+	}
+	assert.Len(t, submitted(server), 5, "rejected queries must not be submitted")
+}
+
+func TestSessionPolicySetByServer(t *testing.T) {
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{})
+	server.Handle("CALL system.tune()", trinomock.Response{
+		Header: http.Header{trinoSetSessionHeader: []string{"query_max_memory=1TB"}},
+	})
+
+	connector, err := NewConnector(&Config{
+		ServerURI:     server.DSN(),
+		SessionPolicy: &SessionPolicy{Allowed: []string{"hive.*"}},
+	})
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, conn.Close())
+	})
+
+	_, err = conn.ExecContext(context.Background(), "SET SESSION hive.compression_codec = 'ZSTD'")
+	require.NoError(t, err)
+	_, err = conn.ExecContext(context.Background(), "CALL system.tune()")
+	var propertyErr *SessionPropertyError
+	require.ErrorAs(t, err, &propertyErr)
+	assert.Equal(t, "query_max_memory", propertyErr.Property)
+	_, err = conn.ExecContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "hive.compression_codec=ZSTD", lastSubmitted(t, server).Header.Get(trinoSessionHeader),
+		"session properties set by the server must be checked")
+}
+
+func TestParseTrinoDuration(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"10m":    10 * time.Minute,
+		"1.5h":   90 * time.Minute,
+		" 2 d ":  48 * time.Hour,
+		"500ms":  500 * time.Millisecond,
+		"30s":    30 * time.Second,
+		"100us":  100 * time.Microsecond,
+		"0.00ns": 0,
+	} {
+		d, err := parseTrinoDuration(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, d, s)
+	}
+	for _, s := range []string{"", "10", "m", "-1s", "1w", "1e3s", "1000000000d"} {
+		_, err := parseTrinoDuration(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestExecutionTimeDeadline(t *testing.T) {
+	executionTimeMargin = 0
+	t.Cleanup(func() {
+		executionTimeMargin = time.Minute
+	})
+	// every response of the query takes longer than its maximum execution time
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{PageDelay: 500 * time.Millisecond})
+
+	db := openTestDB(t, server.DSN()+"?session_properties=query_max_execution_time=100ms")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	start := time.Now()
+	_, err := db.ExecContext(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the property of the query overrides the one of the connection
+	start = time.Now()
+	_, err = db.ExecContext(WithSessionProperties(ctx, map[string]string{"query_max_execution_time": "0.2s"}), "SELECT 1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	// an earlier deadline of the context is kept
+	shortCtx, cancelShort := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelShort()
+	start = time.Now()
+	_, err = db.ExecContext(shortCtx, "SELECT 1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	db = openTestDB(t, server.DSN()+"?session_properties=query_max_execution_time=100ms&execution_time_deadline=false")
+	_, err = db.ExecContext(ctx, "SELECT 1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShutdown is returned for queries started after calling Shutdown.
+var ErrShutdown = errors.New("trino: database is shut down")
+
+// Shutdown stops a database opened with this driver from starting new queries,
+// which fail with ErrShutdown, and waits for the queries in progress to finish,
+// including reading their rows, for services restarting without leaving queries
+// running on the server. When ctx is done before, the remaining queries are
+// cancelled, like when their context is canceled, so they're cancelled on the
+// server, and Shutdown returns the error of ctx.
+//
+// It doesn't close db, which must still be closed after.
+func Shutdown(ctx context.Context, db *sql.DB) error {
+	d, ok := db.Driver().(*Driver)
+	if !ok || d.connector == nil {
+		return errors.New("trino: Shutdown requires a database opened with this driver")
+	}
+	return d.connector.queries.shutdown(ctx)
+}
+
+// queryTracker tracks the queries in progress of the connections of a Connector.
+type queryTracker struct {
+	mu      sync.Mutex
+	closed  bool
+	nextID  int
+	cancels map[int]context.CancelFunc
+	// idle is closed once there are no queries in progress, after shutting down.
+	idle chan struct{}
+}
+
+// start tracks a query, returning the context to execute it with, and a function
+// to call once it's done, including reading its rows. It returns ErrShutdown after
+// shutting down. A nil tracker doesn't track queries.
+func (t *queryTracker) start(ctx context.Context) (context.Context, func(), error) {
+	if t == nil {
+		return ctx, func() {}, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, ErrShutdown
+	}
+	if t.cancels == nil {
+		t.cancels = make(map[int]context.CancelFunc)
+	}
+	id := t.nextID
+	t.nextID++
+	ctx, cancel := context.WithCancel(ctx)
+	t.cancels[id] = cancel
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			delete(t.cancels, id)
+			if t.closed && len(t.cancels) == 0 {
+				close(t.idle)
+			}
+		})
+	}, nil
+}
+
+func (t *queryTracker) shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		t.idle = make(chan struct{})
+		if len(t.cancels) == 0 {
+			close(t.idle)
+		}
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+	t.mu.Lock()
+	n := len(t.cancels)
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+	t.mu.Unlock()
+	return fmt.Errorf("trino: cancelled %d queries in progress: %w", n, ctx.Err())
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newShutdownServer returns a server returning a page with a row, and the
+// last page once release is closed, and reporting cancelled queries to deleted.
+func newShutdownServer(t *testing.T, release <-chan struct{}, deleted chan<- string) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			json.NewEncoder(w).Encode(&stmtResponse{ID: "fake-query", NextURI: ts.URL + "/v1/statement/fake-query/1"})
+		case http.MethodDelete:
+			select {
+			case deleted <- r.URL.Path:
+			default:
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if r.URL.Path == "/v1/statement/fake-query/1" {
+				w.Write([]byte(`{"id":"fake-query","nextUri":"` + ts.URL + `/v1/statement/fake-query/2",` +
+					`"columns":[{"name":"_col0","type":"integer","typeSignature":{"rawType":"integer","arguments":[]}}],"data":[[1]]}`))
+				return
+			}
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+			json.NewEncoder(w).Encode(&stmtResponse{ID: "fake-query"})
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestShutdown(t *testing.T) {
+	release := make(chan struct{})
+	ts := newShutdownServer(t, release, make(chan string, 1))
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- Shutdown(context.Background(), db)
+	}()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shut down with a query in progress: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+	assert.NoError(t, <-shutdown, "shut down once the rows were read")
+
+	_, err = db.Query("SELECT 1")
+	assert.ErrorIs(t, err, ErrShutdown)
+	_, err = db.Exec("SELECT 1")
+	assert.ErrorIs(t, err, ErrShutdown)
+	assert.NoError(t, Shutdown(context.Background(), db), "shutting down again")
+}
+
+func TestShutdownCancel(t *testing.T) {
+	deleted := make(chan string, 1)
+	ts := newShutdownServer(t, make(chan struct{}), deleted)
+	db, err := sql.Open("trino", ts.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = Shutdown(ctx, db)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "cancelled 1 queries")
+
+	assert.False(t, rows.Next())
+	assert.ErrorIs(t, rows.Err(), context.Canceled)
+	assert.Equal(t, "/v1/query/fake-query", <-deleted, "the query is cancelled on the server")
+}
+
+func TestShutdownOtherDriver(t *testing.T) {
+	db := sql.OpenDB(otherConnector{})
+	assert.Error(t, Shutdown(context.Background(), db))
+}
+
+type otherConnector struct{}
+
+func (otherConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, driver.ErrBadConn
+}
+
+func (otherConnector) Driver() driver.Driver {
+	return nil
+}
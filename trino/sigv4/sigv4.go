@@ -0,0 +1,262 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sigv4 provides an http.RoundTripper signing requests with AWS
+// Signature Version 4, for Trino servers behind AWS authentication layers,
+// like API Gateway with IAM authorization.
+//
+// Register a client using the transport in the driver, and refer to it in the DSN:
+//
+//	client := &http.Client{
+//		Transport: &sigv4.Transport{
+//			Region:      "us-east-1",
+//			Service:     "execute-api",
+//			Credentials: sigv4.EnvCredentials(),
+//		},
+//	}
+//	trino.RegisterCustomClient("aws", client)
+//	db, err := sql.Open("trino", "https://user@example.execute-api.us-east-1.amazonaws.com?custom_client=aws")
+//
+// Since the signature is sent in the Authorization header, it cannot be
+// combined with HTTP Basic or JWT authentication.
+package sigv4
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm       = "AWS4-HMAC-SHA256"
+	amzDateFormat   = "20060102T150405Z"
+	shortDateFormat = "20060102"
+
+	amzDateHeader          = "X-Amz-Date"
+	amzSecurityTokenHeader = "X-Amz-Security-Token"
+)
+
+// Credentials are AWS credentials used to sign requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Session token of temporary credentials (optional)
+}
+
+// CredentialsProvider returns the credentials to sign a request with.
+// It's called for every request, so it should cache credentials
+// and refresh them before they expire.
+type CredentialsProvider func(ctx context.Context) (Credentials, error)
+
+// StaticCredentials returns a provider always returning the given credentials.
+func StaticCredentials(accessKeyID, secretAccessKey, sessionToken string) CredentialsProvider {
+	c := Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+	}
+	return func(context.Context) (Credentials, error) {
+		return c, nil
+	}
+}
+
+// EnvCredentials returns a provider reading credentials from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables.
+func EnvCredentials() CredentialsProvider {
+	return func(context.Context) (Credentials, error) {
+		c := Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return Credentials{}, errors.New("sigv4: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+		}
+		return c, nil
+	}
+}
+
+// Transport is an http.RoundTripper signing every request with AWS Signature Version 4.
+type Transport struct {
+	// Base is the transport used to send signed requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Region is the AWS region of the service, e.g. us-east-1.
+	Region string
+	// Service is the name of the AWS service, e.g. execute-api for API Gateway.
+	Service string
+	// Credentials provides the credentials to sign requests with.
+	Credentials CredentialsProvider
+
+	// now returns the signing time, and is replaced in tests.
+	now func() time.Time
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Credentials == nil {
+		return nil, errors.New("sigv4: missing credentials provider")
+	}
+	creds, err := t.Credentials(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("sigv4: error retrieving credentials: %w", err)
+	}
+	payload, err := readBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("sigv4: error reading request body: %w", err)
+	}
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+
+	// RoundTrip must not modify the original request.
+	signed := req.Clone(req.Context())
+	t.sign(signed, payload, creds, now().UTC())
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be read twice")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (t *Transport) sign(req *http.Request, payload []byte, creds Credentials, now time.Time) {
+	amzDate := now.Format(amzDateFormat)
+	shortDate := now.Format(shortDateFormat)
+
+	req.Header.Set(amzDateHeader, amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set(amzSecurityTokenHeader, creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{
+		"host":                                  host,
+		strings.ToLower(amzDateHeader):          amzDate,
+		strings.ToLower(amzSecurityTokenHeader): creds.SessionToken,
+	}
+	var names []string
+	for name, value := range headers {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escape(path, false),
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(payload),
+	}, "\n")
+
+	scope := strings.Join([]string{shortDate, t.Region, t.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		algorithm,
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), shortDate)
+	key = hmacSHA256(key, t.Region)
+	key = hmacSHA256(key, t.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escape(key, true)+"="+escape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// escape URI-encodes every byte except unreserved characters, and slashes unless encodeSlash is set.
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,23 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlxtest tests the common patterns of sqlx, like StructScan,
+// NamedExec and In, with the driver. It has no code besides its tests, which
+// use an in-memory server, and a Trino server in Docker unless running with
+// -short, or the server of the trino_server_dsn flag:
+//
+//	cd trino/sqlxtest && go test -trino_server_dsn http://test@localhost:8080
+//
+// It's a separate module, so that the driver doesn't depend on sqlx.
+package sqlxtest
@@ -0,0 +1,101 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlxtest
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trinodb/trino-go-client/trino"
+	"github.com/trinodb/trino-go-client/trino/trinotest"
+)
+
+var serverDSN = flag.String("trino_server_dsn", "", "dsn of a Trino server to test with, instead of starting one in Docker")
+
+// openIntegrationDB opens a database with a Trino server, and loads the users table.
+func openIntegrationDB(t *testing.T) *sqlx.DB {
+	dsn := *serverDSN
+	if dsn == "" {
+		dsn = trinotest.StartContainer(t, nil).DSN
+	}
+	db, err := sqlx.Open("trino", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, trinotest.LoadTables(context.Background(), db.DB, trinotest.Table{
+		Name: "memory.default.users",
+		Columns: []trinotest.Column{
+			{Name: "id", Type: "BIGINT"},
+			{Name: "name", Type: "VARCHAR"},
+			{Name: "email", Type: "VARCHAR"},
+			{Name: "age", Type: "BIGINT"},
+			{Name: "balance", Type: "DECIMAL(10,2)"},
+			{Name: "created", Type: "TIMESTAMP(3) WITH TIME ZONE"},
+		},
+		Rows: [][]interface{}{
+			{1, "alice", "alice@example.com", 30, trino.Numeric("12.50"), created},
+			{2, "bob", nil, nil, trino.Numeric("0"), created},
+		},
+	}))
+	return db
+}
+
+func TestIntegration(t *testing.T) {
+	db := openIntegrationDB(t)
+
+	var u user
+	require.NoError(t, db.Get(&u, "SELECT * FROM memory.default.users WHERE id = ?", 2))
+	assert.Equal(t, "bob", u.Name)
+	assert.False(t, u.Email.Valid)
+	assert.Nil(t, u.Age)
+	assert.Equal(t, trino.Numeric("0.00"), u.Balance)
+	assert.True(t, u.Created.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	_, err := db.NamedExec("INSERT INTO memory.default.users (id, name, email, age, balance, created) VALUES (:id, :name, :email, :age, :balance, :created)",
+		[]user{
+			{ID: 3, Name: "carol", Balance: "1.50", audit: audit{Created: time.Now()}},
+			{ID: 4, Name: "it's", Balance: "2", audit: audit{Created: time.Now()}},
+		})
+	require.NoError(t, err)
+
+	var users []user
+	require.NoError(t, db.Select(&users, "SELECT * FROM memory.default.users ORDER BY id"))
+	require.Len(t, users, 4)
+	assert.Equal(t, "it's", users[3].Name)
+	assert.Equal(t, trino.Numeric("1.50"), users[2].Balance)
+
+	query, args, err := sqlx.In("SELECT name FROM memory.default.users WHERE id IN (?) ORDER BY id", []int64{1, 3})
+	require.NoError(t, err)
+	var names []string
+	require.NoError(t, db.Select(&names, db.Rebind(query), args...))
+	assert.Equal(t, []string{"alice", "carol"}, names)
+
+	stmt, err := db.PrepareNamed("SELECT count(*) FROM memory.default.users WHERE balance > :balance")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, stmt.Close())
+	})
+	var count int
+	require.NoError(t, stmt.Get(&count, map[string]interface{}{"balance": trino.Numeric("1")}))
+	assert.Equal(t, 3, count)
+}
@@ -0,0 +1,223 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlxtest
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trinodb/trino-go-client/trino"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+type audit struct {
+	Created time.Time `db:"created"`
+}
+
+type user struct {
+	ID      int64          `db:"id"`
+	Name    string         `db:"name"`
+	Email   sql.NullString `db:"email"`
+	Age     *int64         `db:"age"`
+	Balance trino.Numeric  `db:"balance"`
+	audit
+}
+
+var userColumns = []trinomock.Column{
+	{Name: "id", Type: "bigint"},
+	{Name: "name", Type: "varchar"},
+	{Name: "email", Type: "varchar"},
+	{Name: "age", Type: "bigint"},
+	{Name: "balance", Type: "decimal(10,2)"},
+	{Name: "created", Type: "timestamp(3) with time zone"},
+}
+
+var userRows = [][]interface{}{
+	{1, "alice", "alice@example.com", 30, "12.50", "2024-01-02 03:04:05.000 UTC"},
+	{2, "bob", nil, nil, "0.00", "2024-01-03 03:04:05.000 UTC"},
+}
+
+func newServer(t *testing.T) (*trinomock.Server, *sqlx.DB) {
+	server := trinomock.NewServer()
+	t.Cleanup(server.Close)
+	db, err := sqlx.Open("trino", server.DSN())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return server, db
+}
+
+// submitted returns the last statement received by the server, with its arguments.
+func submitted(server *trinomock.Server) string {
+	requests := server.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].Method == "POST" {
+			return requests[i].Body
+		}
+	}
+	return ""
+}
+
+func TestGet(t *testing.T) {
+	server, db := newServer(t)
+	server.Handle("SELECT * FROM users WHERE id = ?", trinomock.Response{Columns: userColumns, Rows: userRows[1:]})
+
+	var u user
+	require.NoError(t, db.Get(&u, "SELECT * FROM users WHERE id = ?", 2))
+	assert.Equal(t, user{
+		ID:      2,
+		Name:    "bob",
+		Balance: "0.00",
+		audit:   audit{Created: time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC)},
+	}, u)
+
+	server.Handle("SELECT count(*) FROM users", trinomock.Response{Columns: []trinomock.Column{{Name: "_col0", Type: "bigint"}}, Rows: [][]interface{}{{2}}})
+	var count int
+	require.NoError(t, db.Get(&count, "SELECT count(*) FROM users"))
+	assert.Equal(t, 2, count)
+}
+
+func TestSelect(t *testing.T) {
+	server, db := newServer(t)
+	server.Handle("SELECT * FROM users", trinomock.Response{Columns: userColumns, Rows: userRows, PageSize: 1})
+	server.Handle("SELECT id FROM users", trinomock.Response{Columns: userColumns[:1], Rows: [][]interface{}{{1}, {2}}})
+
+	var users []user
+	require.NoError(t, db.Select(&users, "SELECT * FROM users"))
+	require.Len(t, users, 2)
+	age := int64(30)
+	assert.Equal(t, user{
+		ID:      1,
+		Name:    "alice",
+		Email:   sql.NullString{String: "alice@example.com", Valid: true},
+		Age:     &age,
+		Balance: "12.50",
+		audit:   audit{Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}, users[0])
+
+	var ids []int64
+	require.NoError(t, db.Select(&ids, "SELECT id FROM users"))
+	assert.Equal(t, []int64{1, 2}, ids)
+}
+
+func TestMapAndSliceScan(t *testing.T) {
+	server, db := newServer(t)
+	server.Handle("SELECT * FROM users", trinomock.Response{Columns: userColumns, Rows: userRows[1:]})
+
+	rows, err := db.Queryx("SELECT * FROM users")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	m := make(map[string]interface{})
+	require.NoError(t, rows.MapScan(m))
+	assert.Equal(t, map[string]interface{}{
+		"id":      int64(2),
+		"name":    "bob",
+		"email":   nil,
+		"age":     nil,
+		"balance": "0.00",
+		"created": time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC),
+	}, m)
+	require.NoError(t, rows.Close())
+
+	rows, err = db.Queryx("SELECT * FROM users")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	values, err := rows.SliceScan()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(2), "bob", nil, nil, "0.00", time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC)}, values)
+	require.NoError(t, rows.Close())
+}
+
+func TestNamedExec(t *testing.T) {
+	server, db := newServer(t)
+	server.HandleMatch(func(statement string) bool {
+		return strings.HasPrefix(statement, "INSERT INTO users")
+	}, trinomock.Response{UpdateType: "INSERT", UpdateCount: 1})
+	const insert = "INSERT INTO users (id, name, email, age, balance, created) VALUES (:id, :name, :email, :age, :balance, :created)"
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	_, err := db.NamedExec(insert, user{ID: 1, Name: "it's", Balance: "1.50", audit: audit{Created: created}})
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (id, name, email, age, balance, created) VALUES (?, ?, ?, ?, ?, ?)", server.Statements()[0])
+	assert.Contains(t, submitted(server), "USING 1, 'it''s', NULL, NULL, 1.50, TIMESTAMP '2024-01-02 03:04:05 Z'")
+
+	_, err = db.NamedExec(insert, map[string]interface{}{
+		"id": 2, "name": "bob", "email": "bob@example.com", "age": 40, "balance": trino.Numeric("0"), "created": created,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, submitted(server), "USING 2, 'bob', 'bob@example.com', 40, 0, TIMESTAMP")
+
+	result, err := db.NamedExec(insert, []user{{ID: 3, Name: "c", Balance: "0"}, {ID: 4, Name: "d", Balance: "0"}})
+	require.NoError(t, err, "batch inserts")
+	n, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, "INSERT INTO users (id, name, email, age, balance, created) VALUES (?, ?, ?, ?, ?, ?),(?, ?, ?, ?, ?, ?)", server.Statements()[2])
+}
+
+func TestNamedQuery(t *testing.T) {
+	server, db := newServer(t)
+	server.Handle("SELECT * FROM users WHERE name = ? AND id > ?", trinomock.Response{Columns: userColumns, Rows: userRows})
+
+	rows, err := db.NamedQuery("SELECT * FROM users WHERE name = :name AND id > :id", map[string]interface{}{"name": "alice", "id": 0})
+	require.NoError(t, err)
+	var users []user
+	for rows.Next() {
+		var u user
+		require.NoError(t, rows.StructScan(&u))
+		users = append(users, u)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	assert.Len(t, users, 2)
+
+	stmt, err := db.PrepareNamed("SELECT * FROM users WHERE name = :name AND id > :id")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, stmt.Close())
+	})
+	users = nil
+	require.NoError(t, stmt.Select(&users, map[string]interface{}{"name": "alice", "id": 0}))
+	assert.Len(t, users, 2)
+	assert.Contains(t, submitted(server), "'alice', 0")
+
+	// named arguments of database/sql aren't bound by the driver, only by sqlx
+	err = db.Select(&users, "SELECT * FROM users WHERE name = :name", sql.Named("name", "alice"))
+	assert.ErrorIs(t, err, trino.ErrOperationNotSupported)
+}
+
+func TestIn(t *testing.T) {
+	server, db := newServer(t)
+	server.Handle("SELECT * FROM users WHERE id IN (?, ?) AND name <> ?", trinomock.Response{Columns: userColumns, Rows: userRows})
+
+	query, args, err := sqlx.In("SELECT * FROM users WHERE id IN (?) AND name <> ?", []int64{1, 2}, "carol")
+	require.NoError(t, err)
+	var users []user
+	require.NoError(t, db.Select(&users, db.Rebind(query), args...))
+	assert.Len(t, users, 2)
+	assert.Contains(t, submitted(server), "USING 1, 2, 'carol'")
+
+	// arrays are passed as such, without expanding them, when not using In
+	server.Handle("SELECT * FROM users WHERE contains(?, id)", trinomock.Response{Columns: userColumns, Rows: userRows})
+	require.NoError(t, db.Select(&users, "SELECT * FROM users WHERE contains(?, id)", []int64{1, 2}))
+	assert.Contains(t, submitted(server), "USING ARRAY[1, 2]")
+}
@@ -0,0 +1,663 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+)
+
+type driverStmt struct {
+	conn  *Conn
+	query string
+	user  string
+	// statement is the last query submitted, after rewriting it, and started the time
+	// it was submitted, for the StatementLogger.
+	statement string
+	started   time.Time
+	// baseURL is the base URL of the host which accepted the query.
+	baseURL        string
+	nextURIs       chan string
+	httpResponses  chan *http.Response
+	queryResponses chan queryResponse
+	// errors receives at most one error from each goroutine fetching results,
+	// so it's buffered to never block them.
+	errors chan error
+	// cancelFetch stops the goroutines fetching results, if they were started.
+	cancelFetch context.CancelFunc
+	// fetchWG waits for the goroutines fetching results to exit.
+	fetchWG sync.WaitGroup
+	// cached are the results of the last query read from the ResultCache, if any.
+	cached *resultCacheEntry
+	// recorder stores the results of the last query in the ResultCache, if they can be cached.
+	recorder *resultRecorder
+	// rawJSON returns the values of the last query as raw JSON, when set with WithRawJSON.
+	rawJSON bool
+	// internal marks a query run by the driver itself, like the one looking up a
+	// query to resubmit, which isn't resubmitted, rewritten, checked by the
+	// SessionPolicy or cached.
+	internal bool
+	// preparedName is the name of the statement prepared with PREPARE executed by
+	// the query, if the connection kept it when the query was prepared.
+	preparedName string
+
+	// bufferedBytes is the size of the result pages fetched, but not yet consumed.
+	bufferedBytes atomic.Int64
+	// maxBufferedBytes stops fetching the next page, until the current one is consumed,
+	// when bufferedBytes goes above it. Zero means no limit.
+	maxBufferedBytes int64
+	// maxScannedBytes cancels the query once it read more physical input bytes, unless it's 0.
+	maxScannedBytes int64
+	// bufferedPages is the number of result pages fetched, but not yet consumed,
+	// including the one being read.
+	bufferedPages atomic.Int64
+	// releasedCh is notified when a consumed page is released from bufferedBytes.
+	releasedCh chan struct{}
+}
+
+var (
+	_ driver.Stmt              = &driverStmt{}
+	_ driver.StmtQueryContext  = &driverStmt{}
+	_ driver.StmtExecContext   = &driverStmt{}
+	_ driver.NamedValueChecker = &driverStmt{}
+)
+
+// Close closes statement just before releasing connection.
+// It stops fetching results, and returns after all the goroutines doing it exited.
+// Calling it more than once is a no-op. Statements are prepared by sending their query
+// in the prepared statement header of every execution, so there's nothing to deallocate
+// on the server.
+func (st *driverStmt) Close() error {
+	st.stopFetching()
+	if st.conn.progress != nil {
+		// wait for the pending updates, so none are delivered after the statement is closed
+		st.conn.progress.flush()
+	}
+	return nil
+}
+
+// fetchFailed reports an error from one of the goroutines fetching results, which must
+// return right after. It reports context.Canceled if the query's context was canceled,
+// and nothing if fetchCtx was canceled because the statement was closed.
+func (st *driverStmt) fetchFailed(ctx, fetchCtx context.Context, err error) {
+	switch {
+	case ctx.Err() == context.Canceled:
+		err = context.Canceled
+	case ctx.Err() == nil && fetchCtx.Err() != nil:
+		return
+	}
+	st.errors <- err
+}
+
+// stopFetching cancels the goroutines fetching results of the last query, and waits for them to exit.
+func (st *driverStmt) stopFetching() {
+	if st.cancelFetch == nil {
+		return
+	}
+	st.cancelFetch()
+	st.fetchWG.Wait()
+	st.cancelFetch = nil
+}
+
+func (st *driverStmt) NumInput() int {
+	return -1
+}
+
+func (st *driverStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (st *driverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, done, err := st.conn.queries.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	sr, err := st.exec(ctx, args)
+	if err != nil {
+		st.logFailed(sr, err)
+		return nil, err
+	}
+	rows := &driverRows{
+		ctx:          ctx,
+		stmt:         st,
+		queryID:      sr.ID,
+		nextURI:      sr.NextURI,
+		rowsAffected: sr.UpdateCount,
+		cached:       st.cached,
+		recorder:     st.recorder,
+		progressInfo: QueryProgressInfo{QueryId: sr.ID, QueryStats: sr.Stats},
+	}
+	if fn, ok := ctx.Value(execProgressContextKey).(func(ExecProgress)); ok && fn != nil {
+		rows.execProgress = fn
+		fn(newExecProgress(sr.ID, sr.Stats, sr.UpdateCount))
+	}
+	// consume all results, if there are any
+	for err == nil {
+		err = rows.fetch()
+		if err == nil && st.conn.execSelect != execSelectRead && rows.returnsRows() {
+			if err := rows.stopSelect(); err != nil {
+				return nil, err
+			}
+			return rows, nil
+		}
+	}
+
+	if errors.Is(err, ErrBudgetExceeded) {
+		// the query is still running
+		rows.Close()
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (st *driverStmt) CheckNamedValue(arg *driver.NamedValue) error {
+	// Other named arguments would be bound by their position, ignoring their names.
+	if arg.Name != "" && !strings.HasPrefix(arg.Name, trinoHeaderPrefix) {
+		return &UnsupportedOperationError{
+			Operation:   fmt.Sprintf("named parameters (%s)", arg.Name),
+			Alternative: "use ? placeholders, or bind named parameters with a library like sqlx",
+		}
+	}
+	if getSerializer(arg.Value) != nil {
+		return nil
+	}
+	switch arg.Value.(type) {
+	case nil:
+		return nil
+	case Numeric, trinoDate, trinoTime, trinoTimeTz, trinoTimestamp, time.Time:
+		return nil
+	case sql.Out:
+		return &UnsupportedOperationError{
+			Operation:   "output parameters (sql.Out)",
+			Alternative: "return values as columns of the query results",
+		}
+	case driver.Valuer, encoding.TextMarshaler:
+		// The default converter rejects valuers returning slices, like NullSliceInt64,
+		// or types supported by Serial but not by database/sql, like int32, and
+		// doesn't support text marshalers, like netip.Addr.
+		v, err := convertArgument(arg.Value)
+		if err != nil {
+			return err
+		}
+		arg.Value = v
+		return nil
+	default:
+		{
+			if reflect.TypeOf(arg.Value).Kind() == reflect.Slice {
+				return nil
+			}
+
+			if arg.Name == trinoProgressCallbackParam {
+				return nil
+			}
+			if arg.Name == trinoProgressCallbackPeriodParam {
+				return nil
+			}
+		}
+	}
+
+	return driver.ErrSkip
+}
+
+// convertArgument converts a value implementing driver.Valuer or encoding.TextMarshaler
+// to the value it represents. Text is passed as a string.
+func convertArgument(v interface{}) (interface{}, error) {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, nil
+	}
+	if vr, ok := v.(driver.Valuer); ok {
+		value, err := callValuer(vr)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.(driver.Valuer); ok {
+			return nil, fmt.Errorf("trino: Value method of %T returned another driver.Valuer, %T", v, value)
+		}
+		if _, ok := value.(time.Time); ok {
+			return value, nil
+		}
+		if _, ok := value.(encoding.TextMarshaler); !ok {
+			return value, nil
+		}
+		v = value
+	}
+	text, err := v.(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return nil, fmt.Errorf("trino: error marshaling %T argument: %w", v, err)
+	}
+	return string(text), nil
+}
+
+// callValuer calls the Value method, returning nil for nil pointers to types
+// implementing it with a value receiver, like the default converter does.
+func callValuer(vr driver.Valuer) (driver.Value, error) {
+	if rv := reflect.ValueOf(vr); rv.Kind() == reflect.Pointer && rv.IsNil() && rv.Type().Elem().Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+		return nil, nil
+	}
+	return vr.Value()
+}
+
+func (st *driverStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+func (st *driverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, done, err := st.conn.queries.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sr, err := st.exec(ctx, args)
+	if err != nil {
+		st.logFailed(sr, err)
+		done()
+		return nil, err
+	}
+	rows := &driverRows{
+		ctx:          ctx,
+		stmt:         st,
+		queryID:      sr.ID,
+		nextURI:      sr.NextURI,
+		cached:       st.cached,
+		recorder:     st.recorder,
+		done:         done,
+		progressInfo: QueryProgressInfo{QueryId: sr.ID, QueryStats: sr.Stats},
+	}
+	rows.columnsFirst, _ = ctx.Value(columnsFirstContextKey).(bool)
+	if err = rows.fetch(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (st *driverStmt) exec(ctx context.Context, args []driver.NamedValue) (*stmtResponse, error) {
+	statement := st.query
+	st.statement, st.started = st.query, time.Now()
+	if st.preparedName != "" && !st.conn.hasPreparedStatement(st.preparedName) {
+		return nil, fmt.Errorf("%w: %s", ErrStmtDeallocated, st.preparedName)
+	}
+	st.rawJSON, _ = ctx.Value(rawJSONContextKey).(bool)
+	st.maxScannedBytes = st.conn.maxScannedBytes
+	if budget, ok := ctx.Value(scannedBytesBudgetContextKey).(int64); ok {
+		st.maxScannedBytes = budget
+	}
+	if st.conn.queryRewriter != nil && !st.internal {
+		var err error
+		statement, err = st.conn.queryRewriter.RewriteQuery(ctx, st.query)
+		if err != nil {
+			return nil, fmt.Errorf("trino: query rejected: %w", err)
+		}
+		st.statement = statement
+	}
+	query := statement
+	hs := make(http.Header)
+	// Ensure the server returns timestamps preserving their precision, without truncating them to timestamp(3).
+	hs.Add("X-Trino-Client-Capabilities", "PARAMETRIC_DATETIME")
+
+	var values []interface{}
+	if len(args) > 0 {
+		for _, arg := range args {
+			if arg.Name == trinoProgressCallbackParam {
+				st.conn.progressUpdater = arg.Value.(ProgressUpdater)
+				continue
+			}
+			if arg.Name == trinoProgressCallbackPeriodParam {
+				st.conn.progressUpdaterPeriod.Period = arg.Value.(time.Duration)
+				continue
+			}
+			if arg.Name == trinoMaxBufferedBytesParam {
+				maxBufferedBytes, ok := arg.Value.(int64)
+				if !ok || maxBufferedBytes < 0 {
+					return nil, fmt.Errorf("trino: %s must be a non-negative integer, got %v", trinoMaxBufferedBytesParam, arg.Value)
+				}
+				st.maxBufferedBytes = maxBufferedBytes
+				continue
+			}
+
+			if strings.HasPrefix(arg.Name, trinoHeaderPrefix) {
+				headerValue, ok := arg.Value.(string)
+				if !ok {
+					return nil, fmt.Errorf("trino: %s must be a string, got %T", arg.Name, arg.Value)
+				}
+
+				if arg.Name == trinoUserHeader {
+					st.user = headerValue
+				}
+
+				hs.Add(arg.Name, headerValue)
+			} else {
+				values = append(values, arg.Value)
+			}
+		}
+		if (st.conn.progressUpdater != nil && st.conn.progressUpdaterPeriod.Period == 0) || (st.conn.progressUpdater == nil && st.conn.progressUpdaterPeriod.Period > 0) {
+			return nil, ErrInvalidProgressCallbackHeader
+		}
+	}
+
+	if user, ok := ctx.Value(userContextKey).(string); ok && user != "" && hs.Get(trinoUserHeader) == "" {
+		st.user = user
+		hs.Set(trinoUserHeader, user)
+	}
+
+	properties, _ := ctx.Value(sessionPropertiesContextKey).(map[string]string)
+	if !st.internal {
+		if err := st.conn.checkSessionProperties(hs, properties, statement); err != nil {
+			return nil, err
+		}
+	}
+	if len(properties) > 0 {
+		hs.Set(trinoSessionHeader, st.conn.sessionHeader(hs, properties))
+	}
+
+	if len(values) > 0 {
+		// Arguments are serialized in the session of the query, with its headers.
+		session := st.conn.serialSession(hs)
+		ss := make([]string, len(values))
+		for i, v := range values {
+			s, err := serial(ctx, session, v)
+			if err != nil {
+				return nil, err
+			}
+			if st.conn.validateLiterals {
+				if err := ValidateLiteral(s); err != nil {
+					return nil, err
+				}
+			}
+			ss[i] = s
+		}
+		preparedStatement := url.QueryEscape(statement)
+		if st.conn.useExplicitPrepare && (len(preparedStatement) <= maxPreparedStatementHeaderSize || !st.conn.supportsExecuteImmediate()) {
+			for _, v := range st.conn.httpHeaders.Values(preparedStatementHeader) {
+				hs.Add(preparedStatementHeader, v)
+			}
+			hs.Add(preparedStatementHeader, preparedStatementName+"="+preparedStatement)
+			query = "EXECUTE " + preparedStatementName + " USING " + strings.Join(ss, ", ")
+		} else {
+			// Statements too large for a header are sent in the body, which requires Trino 431 or newer.
+			query = "EXECUTE IMMEDIATE " + quoteString(statement) + " USING " + strings.Join(ss, ", ")
+		}
+	}
+
+	if priority, _ := ctx.Value(priorityContextKey).(string); priority != "" {
+		if err := st.conn.setPriorityTag(hs, priority); err != nil {
+			return nil, err
+		}
+	}
+	if st.conn.queryTagger != nil {
+		if err := st.conn.queryTagger.setHeaders(ctx, hs); err != nil {
+			return nil, err
+		}
+	}
+
+	st.cached, st.recorder = nil, nil
+	if st.conn.resultCache != nil && !st.internal && ctx.Value(noResultCacheContextKey) == nil && !st.rawJSON && isCacheableQuery(statement) {
+		key := st.conn.resultCacheKey(query, hs)
+		if entry, ok := st.conn.resultCache.get(key); ok {
+			st.stopFetching()
+			st.cached = entry
+			return &stmtResponse{ID: entry.queryID}, nil
+		}
+		st.recorder = &resultRecorder{cache: st.conn.resultCache, entry: &resultCacheEntry{key: key}}
+	}
+
+	var idempotencyKey string
+	if st.conn.resubmitStatements && !st.internal {
+		var err error
+		idempotencyKey, err = newIdempotencyKey()
+		if err != nil {
+			return nil, err
+		}
+		query = tagIdempotencyKey(query, idempotencyKey)
+	}
+
+	body := []byte(query)
+	if st.conn.compressRequestBody {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := io.WriteString(zw, query); err != nil {
+			return nil, fmt.Errorf("trino: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("trino: %w", err)
+		}
+		body = buf.Bytes()
+		hs.Set("Content-Encoding", "gzip")
+	}
+
+	var cancel context.CancelFunc = func() {}
+	if timeout, ok := st.conn.executionTimeout(hs); ok {
+		// an earlier deadline of ctx is kept
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else if _, ok := ctx.Deadline(); !ok {
+		ctx, cancel = context.WithTimeout(ctx, DefaultQueryTimeout)
+	}
+	var (
+		resp *http.Response
+		err  error
+	)
+	st.conn.recycleConnections()
+	baseURLs := st.conn.hostOrder()
+	for i, baseURL := range baseURLs {
+		var req *http.Request
+		req, err = st.conn.newRequest(ctx, "POST", baseURL+"/v1/statement", bytes.NewReader(body), hs)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		if observe, ok := ctx.Value(statementObserverContextKey).(func(SubmittedStatement)); ok {
+			header := req.Header.Clone()
+			header.Del(authorizationHeader)
+			observe(SubmittedStatement{URL: req.URL.String(), Query: query, Header: header})
+		}
+		resp, err = st.conn.roundTrip(ctx, req)
+		if err != nil && idempotencyKey != "" {
+			resp, err = st.resubmit(ctx, req, idempotencyKey, err)
+		}
+		if err == nil {
+			st.baseURL = baseURL
+			break
+		}
+		// fail over to the next host only if this one didn't respond at all
+		if i == len(baseURLs)-1 || !isConnectionError(err) || ctx.Err() != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	defer resp.Body.Close()
+	var sr stmtResponse
+	d := json.NewDecoder(resp.Body)
+	d.UseNumber()
+	err = d.Decode(&sr)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("trino: %w", err)
+	}
+
+	// stop fetching the results of a previous query on this statement, if any
+	st.stopFetching()
+	fetchCtx, cancelFetch := context.WithCancel(ctx)
+	st.cancelFetch = cancelFetch
+	st.nextURIs = make(chan string)
+	st.httpResponses = make(chan *http.Response)
+	// pages beyond the first one fetched ahead are buffered, so the next ones can be fetched
+	st.queryResponses = make(chan queryResponse, max(st.conn.prefetchPages-1, 0))
+	st.errors = make(chan error, 2)
+	st.releasedCh = make(chan struct{}, 1)
+	st.bufferedBytes.Store(0)
+	st.bufferedPages.Store(0)
+	st.fetchWG.Add(2)
+	go func() {
+		defer st.fetchWG.Done()
+		defer close(st.httpResponses)
+		for {
+			select {
+			case nextURI := <-st.nextURIs:
+				if nextURI == "" {
+					return
+				}
+				if err := st.conn.checkURI(nextURI); err != nil {
+					st.fetchFailed(ctx, fetchCtx, err)
+					return
+				}
+				hs := make(http.Header)
+				hs.Add(trinoUserHeader, st.user)
+				req, err := st.conn.newRequest(fetchCtx, "GET", nextURI, nil, hs)
+				if err != nil {
+					st.fetchFailed(ctx, fetchCtx, err)
+					return
+				}
+				resp, err := st.conn.roundTrip(fetchCtx, req)
+				if err != nil {
+					st.fetchFailed(ctx, fetchCtx, err)
+					return
+				}
+				select {
+				case st.httpResponses <- resp:
+				case <-fetchCtx.Done():
+					resp.Body.Close()
+					st.fetchFailed(ctx, fetchCtx, fetchCtx.Err())
+					return
+				}
+			case <-fetchCtx.Done():
+				st.fetchFailed(ctx, fetchCtx, fetchCtx.Err())
+				return
+			}
+		}
+	}()
+	go func() {
+		defer st.fetchWG.Done()
+		defer close(st.queryResponses)
+		defer cancel()
+		stopped := func() {
+			st.fetchFailed(ctx, fetchCtx, fetchCtx.Err())
+		}
+		for {
+			select {
+			case resp := <-st.httpResponses:
+				if resp == nil {
+					return
+				}
+				var qresp queryResponse
+				body := &countingReader{r: resp.Body}
+				d := json.NewDecoder(body)
+				d.UseNumber()
+				var err error
+				if st.rawJSON {
+					err = protocol.DecodeRawQueryResponse(d, &qresp)
+				} else {
+					err = d.Decode(&qresp)
+				}
+				if err != nil {
+					resp.Body.Close()
+					st.fetchFailed(ctx, fetchCtx, fmt.Errorf("trino: %w", err))
+					return
+				}
+				qresp.Bytes = body.n
+				st.bufferedBytes.Add(qresp.Bytes)
+				st.bufferedPages.Add(1)
+				err = resp.Body.Close()
+				if err != nil {
+					st.fetchFailed(ctx, fetchCtx, err)
+					return
+				}
+				err = handleResponseError(resp.StatusCode, qresp.ID, qresp.Error)
+				if err != nil {
+					st.fetchFailed(ctx, fetchCtx, err)
+					return
+				}
+				if st.maxScannedBytes > 0 && qresp.Stats.PhysicalInputBytes > st.maxScannedBytes {
+					st.fetchFailed(ctx, fetchCtx, fmt.Errorf("%w: query %s read %d bytes, more than %d",
+						ErrBudgetExceeded, qresp.ID, qresp.Stats.PhysicalInputBytes, st.maxScannedBytes))
+					return
+				}
+				select {
+				case st.queryResponses <- qresp:
+				case <-fetchCtx.Done():
+					stopped()
+					return
+				}
+				// Apply back-pressure by waiting for pages to be consumed before fetching
+				// the next one, when more than prefetchPages are ahead of the one being read,
+				// or when the buffered pages are larger than maxBufferedBytes.
+				for st.bufferedPages.Load() > int64(st.conn.prefetchPages) ||
+					(st.maxBufferedBytes > 0 && st.bufferedBytes.Load() > st.maxBufferedBytes) {
+					select {
+					case <-st.releasedCh:
+					case <-fetchCtx.Done():
+						stopped()
+						return
+					}
+				}
+				select {
+				case st.nextURIs <- qresp.NextURI:
+				case <-fetchCtx.Done():
+					stopped()
+					return
+				}
+			case <-fetchCtx.Done():
+				stopped()
+				return
+			}
+		}
+	}()
+	// the fetching goroutines stop without reading it if ctx is already done
+	select {
+	case st.nextURIs <- sr.NextURI:
+	case <-fetchCtx.Done():
+	}
+	err = handleResponseError(resp.StatusCode, sr.ID, sr.Error)
+	if st.conn.progressUpdater != nil {
+		if st.conn.progress == nil {
+			st.conn.progress = newProgressDispatcher()
+		}
+		if starter, ok := st.conn.progressUpdater.(ProgressStarter); ok {
+			st.conn.progress.event(func() { starter.OnStarted(sr.ID, sr.InfoURI) })
+		}
+		// initial progress callback call
+		info := QueryProgressInfo{
+			QueryId:       sr.ID,
+			QueryStats:    sr.Stats,
+			BufferedBytes: st.bufferedBytes.Load(),
+		}
+		st.conn.progress.send(st.conn.progressUpdater, info)
+		st.conn.progressUpdaterPeriod.LastCallbackTime = time.Now()
+		st.conn.progressUpdaterPeriod.LastQueryState = sr.Stats.State
+		if finisher, ok := st.conn.progressUpdater.(ProgressFinisher); ok && err != nil {
+			// there are no rows to report the end of the query
+			st.conn.progress.event(func() { finisher.OnFinished(info, err) })
+		}
+	}
+	return &sr, err
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// StatementLogger receives a summary of every statement once it's finished,
+// for example to keep an audit trail of the queries of an application without
+// wrapping the code running them.
+type StatementLogger interface {
+	// LogStatement is called once for every statement, from the goroutine
+	// finishing it, like the one reading the last row, closing the rows, or
+	// calling Exec, so it should return quickly.
+	LogStatement(StatementSummary)
+}
+
+// StatementLoggerFunc is an adapter allowing to use a function as a StatementLogger.
+type StatementLoggerFunc func(StatementSummary)
+
+// LogStatement implements the StatementLogger interface.
+func (f StatementLoggerFunc) LogStatement(s StatementSummary) {
+	f(s)
+}
+
+// StatementSummary summarizes a finished statement, passed to a StatementLogger.
+type StatementSummary struct {
+	// QueryID is the ID of the query, or empty if the statement failed before
+	// the server created it.
+	QueryID string
+	// User is the user running the statement.
+	User string
+	// Query is the statement, as passed to the database/sql methods, or as
+	// returned by the QueryRewriter, without its arguments.
+	Query string
+	// Start is the time the statement was submitted, and Duration the time it
+	// took until all its rows were read, it failed, or its rows were closed.
+	Start    time.Time
+	Duration time.Duration
+	// Rows is the number of rows read by the client, and RowsAffected the
+	// number of rows affected by the statement, as reported by the server.
+	Rows         int64
+	RowsAffected int64
+	// ProcessedRows and ProcessedBytes are the rows and bytes read by the
+	// query, as last reported by the server.
+	ProcessedRows  int64
+	ProcessedBytes int64
+	// Cached is set if the results were read from the ResultCache.
+	Cached bool
+	// Err is the error of the statement, ErrQueryCancelled if its rows were
+	// closed before reading all of them, or nil.
+	Err error
+}
+
+// NewSlogStatementLogger returns a StatementLogger logging the summary of
+// every statement to logger, with the attributes of the StatementSummary, at
+// the info level, or at the error level if the statement failed.
+func NewSlogStatementLogger(logger *slog.Logger) StatementLogger {
+	return StatementLoggerFunc(func(s StatementSummary) {
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String("query_id", s.QueryID),
+			slog.String("user", s.User),
+			slog.String("query", s.Query),
+			slog.Time("start", s.Start),
+			slog.Duration("duration", s.Duration),
+			slog.Int64("rows", s.Rows),
+			slog.Int64("rows_affected", s.RowsAffected),
+			slog.Int64("processed_rows", s.ProcessedRows),
+			slog.Int64("processed_bytes", s.ProcessedBytes),
+			slog.Bool("cached", s.Cached),
+		}
+		if s.Err != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.String("error", s.Err.Error()))
+		}
+		logger.LogAttrs(context.Background(), level, "trino statement", attrs...)
+	})
+}
+
+// logStatement passes the summary of the last statement to the StatementLogger
+// of the connection, if any, with its user, query and duration.
+func (st *driverStmt) logStatement(s StatementSummary) {
+	if st.conn.statementLogger == nil {
+		return
+	}
+	s.User = st.user
+	if s.User == "" {
+		s.User = st.conn.httpHeaders.Get(trinoUserHeader)
+	}
+	s.Query = st.statement
+	s.Start = st.started
+	s.Duration = time.Since(st.started)
+	st.conn.statementLogger.LogStatement(s)
+}
+
+// logFailed logs a statement which failed before returning rows, with the
+// response of the server, if it created the query.
+func (st *driverStmt) logFailed(sr *stmtResponse, err error) {
+	s := StatementSummary{Err: err}
+	if sr != nil {
+		s.QueryID = sr.ID
+		s.ProcessedRows = sr.Stats.ProcessedRows
+		s.ProcessedBytes = sr.Stats.ProcessedBytes
+	}
+	st.logStatement(s)
+}
+
+// logStatement logs the summary of the statement of the rows, once.
+func (qr *driverRows) logStatement(err error) {
+	if qr.statementLogged {
+		return
+	}
+	qr.statementLogged = true
+	qr.stmt.logStatement(StatementSummary{
+		QueryID:        qr.queryID,
+		Rows:           qr.rowsRead,
+		RowsAffected:   qr.rowsAffected,
+		ProcessedRows:  qr.progressInfo.QueryStats.ProcessedRows,
+		ProcessedBytes: qr.progressInfo.QueryStats.ProcessedBytes,
+		Cached:         qr.cached != nil,
+		Err:            err,
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementLogger(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodPost:
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			id := strings.Fields(string(b))[0]
+			if id == "MISSING" {
+				json.NewEncoder(w).Encode(&stmtResponse{ID: id, Error: ErrTrino{ErrorName: "TABLE_NOT_FOUND", ErrorType: "USER_ERROR"}})
+				return
+			}
+			json.NewEncoder(w).Encode(&stmtResponse{ID: id, NextURI: ts.URL + "/v1/statement/" + id + "/1"})
+			return
+		}
+		id := strings.Split(r.URL.Path, "/")[3]
+		if id == "INSERT" {
+			w.Write([]byte(`{"id":"INSERT","updateType":"INSERT","updateCount":5,"stats":{"state":"FINISHED","processedRows":10,"processedBytes":100}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"` + id + `","columns":[{"name":"id","type":"integer","typeSignature":{"rawType":"integer","arguments":[]}}],"data":[[1],[2],[3]],"stats":{"state":"FINISHED","processedRows":3,"processedBytes":30}}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	var summaries []StatementSummary
+	connector, err := NewConnector(&Config{
+		ServerURI: "http://alice@" + strings.TrimPrefix(ts.URL, "http://"),
+		StatementLogger: StatementLoggerFunc(func(s StatementSummary) {
+			summaries = append(summaries, s)
+		}),
+	})
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM t")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+
+	_, err = db.ExecContext(WithUser(ctx, "bob"), "INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+
+	rows, err = db.QueryContext(ctx, "CLOSED")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+
+	_, err = db.ExecContext(ctx, "MISSING")
+	require.Error(t, err)
+
+	require.Len(t, summaries, 4)
+	for _, s := range summaries {
+		assert.False(t, s.Start.IsZero())
+		assert.Greater(t, s.Duration, time.Duration(0))
+	}
+	assert.Equal(t, StatementSummary{
+		QueryID:        "SELECT",
+		User:           "alice",
+		Query:          "SELECT id FROM t",
+		Rows:           3,
+		ProcessedRows:  3,
+		ProcessedBytes: 30,
+		Start:          summaries[0].Start,
+		Duration:       summaries[0].Duration,
+	}, summaries[0])
+	assert.Equal(t, StatementSummary{
+		QueryID:        "INSERT",
+		User:           "bob",
+		Query:          "INSERT INTO t VALUES (1)",
+		RowsAffected:   5,
+		ProcessedRows:  10,
+		ProcessedBytes: 100,
+		Start:          summaries[1].Start,
+		Duration:       summaries[1].Duration,
+	}, summaries[1])
+	assert.Equal(t, "CLOSED", summaries[2].QueryID)
+	assert.Equal(t, int64(1), summaries[2].Rows)
+	assert.Equal(t, ErrQueryCancelled, summaries[2].Err)
+	assert.Equal(t, "MISSING", summaries[3].QueryID)
+	var trinoErr *ErrTrino
+	assert.ErrorAs(t, summaries[3].Err, &trinoErr)
+}
+
+func TestSlogStatementLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogStatementLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.LogStatement(StatementSummary{QueryID: "20240101_000000_00000_aaaaa", User: "alice", Query: "SELECT 1", Rows: 1})
+	logger.LogStatement(StatementSummary{Query: "SELECT", Err: ErrQueryCancelled})
+
+	d := json.NewDecoder(&buf)
+	var record map[string]interface{}
+	require.NoError(t, d.Decode(&record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "20240101_000000_00000_aaaaa", record["query_id"])
+	assert.Equal(t, "alice", record["user"])
+	assert.Equal(t, float64(1), record["rows"])
+	assert.NotContains(t, record, "error")
+	record = nil
+	require.NoError(t, d.Decode(&record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, ErrQueryCancelled.Error(), record["error"])
+}
@@ -0,0 +1,206 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const (
+	trinoClientInfoHeader = trinoHeaderPrefix + `Client-Info`
+	trinoClientTagsHeader = trinoHeaderPrefix + `Client-Tags`
+
+	// DefaultClientInfoTemplate is the template of the X-Trino-Client-Info header used by QueryTags.
+	DefaultClientInfoTemplate = `{{.ServiceName}}@{{.Hostname}}{{with .CorrelationID}} correlation_id={{.}}{{end}}{{with .Caller}} caller={{.}}{{end}}`
+)
+
+// QueryTags adds information about the code running a query to the X-Trino-Client-Info
+// and X-Trino-Client-Tags headers of every query, so queries listed in
+// system.runtime.queries, or in the Trino UI, can be traced back to it.
+//
+// Templates use the text/template syntax, and are executed with a QueryTagsData.
+type QueryTags struct {
+	// ServiceName is the name of the service running queries.
+	// It defaults to the name of the executable.
+	ServiceName string
+	// ClientInfo is the template of the X-Trino-Client-Info header,
+	// which defaults to DefaultClientInfoTemplate. It's not set if the
+	// query already has the header, passed as a NamedArg.
+	ClientInfo string
+	// ClientTags are templates of tags added to the X-Trino-Client-Tags header,
+	// in addition to the ones passed as a NamedArg. Tags are often used to select
+	// resource groups, so none are added by default. Commas are replaced with
+	// underscores, and empty tags are skipped.
+	ClientTags []string
+}
+
+// QueryTagsData is the data QueryTags templates are executed with.
+type QueryTagsData struct {
+	// ServiceName is the name of the service running the query.
+	ServiceName string
+	// Hostname is the host name reported by the kernel.
+	Hostname string
+	// CorrelationID is the ID set in the context of the query with WithCorrelationID, if any.
+	CorrelationID string
+	// Caller is the function, file and line of the code running the query,
+	// outside of the database/sql package and this driver.
+	Caller string
+}
+
+// WithCorrelationID returns a context adding a correlation ID, like the ID of the request
+// being served, to the QueryTags of queries.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDContextKey, id)
+}
+
+// WithPriority returns a context adding the client tag priority=<priority> to
+// queries, like priority=interactive or priority=batch, which the selectors of
+// resource groups can match, to route interactive and batch queries to
+// different groups. If the DSN has the priorities parameter, queries with
+// another priority fail with ErrInvalidPriority. An empty priority adds no tag.
+func WithPriority(ctx context.Context, priority string) context.Context {
+	return context.WithValue(ctx, priorityContextKey, priority)
+}
+
+// setPriorityTag adds the client tag of a priority set with WithPriority to
+// the headers of a query, after the tags passed as a NamedArg.
+func (c *Conn) setPriorityTag(hs http.Header, priority string) error {
+	if strings.ContainsAny(priority, ",=") || (c.priorities != nil && !slices.Contains(c.priorities, priority)) {
+		return fmt.Errorf("%w: %q", ErrInvalidPriority, priority)
+	}
+	tags := "priority=" + priority
+	if v := hs.Get(trinoClientTagsHeader); v != "" {
+		tags = v + "," + tags
+	}
+	hs.Set(trinoClientTagsHeader, tags)
+	return nil
+}
+
+// queryTagger renders QueryTags for every query.
+type queryTagger struct {
+	serviceName string
+	hostname    string
+	clientInfo  *template.Template
+	clientTags  []*template.Template
+}
+
+func newQueryTagger(tags *QueryTags) (*queryTagger, error) {
+	t := &queryTagger{serviceName: tags.ServiceName}
+	if t.serviceName == "" {
+		t.serviceName = filepath.Base(os.Args[0])
+	}
+	t.hostname, _ = os.Hostname()
+	clientInfo := tags.ClientInfo
+	if clientInfo == "" {
+		clientInfo = DefaultClientInfoTemplate
+	}
+	var err error
+	t.clientInfo, err = template.New("client info").Parse(clientInfo)
+	if err != nil {
+		return nil, fmt.Errorf("trino: invalid client info template: %w", err)
+	}
+	for _, tag := range tags.ClientTags {
+		tmpl, err := template.New("client tag").Parse(tag)
+		if err != nil {
+			return nil, fmt.Errorf("trino: invalid client tag template %q: %w", tag, err)
+		}
+		t.clientTags = append(t.clientTags, tmpl)
+	}
+	return t, nil
+}
+
+// setHeaders sets the client info and tags headers of a query.
+func (t *queryTagger) setHeaders(ctx context.Context, hs http.Header) error {
+	data := QueryTagsData{
+		ServiceName: t.serviceName,
+		Hostname:    t.hostname,
+		Caller:      callerOutsideDriver(),
+	}
+	data.CorrelationID, _ = ctx.Value(correlationIDContextKey).(string)
+
+	if hs.Get(trinoClientInfoHeader) == "" {
+		info, err := executeTagTemplate(t.clientInfo, data)
+		if err != nil {
+			return err
+		}
+		hs.Set(trinoClientInfoHeader, info)
+	}
+	var tags []string
+	if v := hs.Get(trinoClientTagsHeader); v != "" {
+		tags = append(tags, v)
+	}
+	for _, tmpl := range t.clientTags {
+		tag, err := executeTagTemplate(tmpl, data)
+		if err != nil {
+			return err
+		}
+		if tag = strings.ReplaceAll(tag, ",", "_"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) != 0 {
+		hs.Set(trinoClientTagsHeader, strings.Join(tags, ","))
+	}
+	return nil
+}
+
+func executeTagTemplate(tmpl *template.Template, data QueryTagsData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("trino: error executing %s template: %w", tmpl.Name(), err)
+	}
+	// header values can't contain line breaks
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, b.String())), nil
+}
+
+// driverDir is the directory of the source files of this package.
+var driverDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callerOutsideDriver returns the first function on the stack that's not in
+// the runtime, the database/sql package or this driver, as "function (file:line)".
+func callerOutsideDriver() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "runtime.") ||
+			strings.HasPrefix(frame.Function, "database/sql.") ||
+			(filepath.Dir(frame.File) == driverDir && !strings.HasSuffix(frame.File, "_test.go"))
+		if !internal && frame.Function != "" {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
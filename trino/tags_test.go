@@ -0,0 +1,110 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+func TestQueryTags(t *testing.T) {
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{})
+
+	connector, err := NewConnector(&Config{
+		ServerURI: server.URL,
+		QueryTags: &QueryTags{
+			ServiceName: "billing",
+			ClientTags:  []string{"service={{.ServiceName}}", "{{.CorrelationID}}"},
+		},
+	})
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(WithCorrelationID(context.Background(), "req-1,2"), "SELECT 1")
+	require.NoError(t, err)
+	requests := submitted(server)
+	require.Len(t, requests, 1)
+	info := requests[0].Header.Get(trinoClientInfoHeader)
+	assert.Contains(t, info, "billing@"+hostname+" correlation_id=req-1,2 caller=github.com/trinodb/trino-go-client/trino.TestQueryTags (tags_test.go:")
+	assert.Equal(t, "service=billing,req-1_2", requests[0].Header.Get(trinoClientTagsHeader))
+
+	_, err = db.Exec("SELECT 1",
+		sql.Named(trinoClientInfoHeader, "custom"),
+		sql.Named(trinoClientTagsHeader, "a,b"),
+	)
+	require.NoError(t, err)
+	requests = submitted(server)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "custom", requests[1].Header.Get(trinoClientInfoHeader))
+	assert.Equal(t, "a,b,service=billing", requests[1].Header.Get(trinoClientTagsHeader))
+}
+
+func TestQueryTagsInvalidTemplate(t *testing.T) {
+	_, err := NewConnector(&Config{ServerURI: "http://localhost", QueryTags: &QueryTags{ClientInfo: "{{.Missing"}})
+	assert.Error(t, err)
+	_, err = NewConnector(&Config{ServerURI: "http://localhost", QueryTags: &QueryTags{ClientTags: []string{"{{"}}})
+	assert.Error(t, err)
+}
+
+func TestWithPriority(t *testing.T) {
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{})
+	tags := func() []string {
+		var tags []string
+		for _, r := range submitted(server) {
+			tags = append(tags, r.Header.Get(trinoClientTagsHeader))
+		}
+		return tags
+	}
+
+	dsn, err := (&Config{ServerURI: server.URL, Priorities: []string{"interactive", "batch"}}).FormatDSN()
+	require.NoError(t, err)
+	db := openTestDB(t, dsn)
+	ctx := context.Background()
+
+	_, err = db.ExecContext(WithPriority(ctx, "interactive"), "SELECT 1")
+	require.NoError(t, err)
+	_, err = db.ExecContext(WithPriority(ctx, "batch"), "SELECT 1", sql.Named(trinoClientTagsHeader, "etl"))
+	require.NoError(t, err)
+	_, err = db.ExecContext(WithPriority(ctx, ""), "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"priority=interactive", "etl,priority=batch", ""}, tags())
+
+	for _, priority := range []string{"high", "batch,priority=high"} {
+		_, err = db.ExecContext(WithPriority(ctx, priority), "SELECT 1")
+		assert.ErrorIs(t, err, ErrInvalidPriority, priority)
+	}
+	assert.Len(t, tags(), 3, "queries with invalid priorities must not be submitted")
+
+	// any priority is allowed without the priorities parameter
+	db = openTestDB(t, server.URL)
+	_, err = db.ExecContext(WithPriority(ctx, "high"), "SELECT 1")
+	require.NoError(t, err)
+	_, err = db.ExecContext(WithPriority(ctx, "a=b"), "SELECT 1")
+	assert.ErrorIs(t, err, ErrInvalidPriority)
+	assert.Equal(t, "priority=high", tags()[3])
+}
@@ -0,0 +1,25 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import "github.com/trinodb/trino-go-client/trino/internal/auth"
+
+// TokenSource provides access tokens sent in the Authorization header of every request.
+type TokenSource = auth.TokenSource
+
+// ClientCredentials is a TokenSource obtaining access tokens using the OAuth 2.0
+// client credentials grant, supported by identity providers like Azure AD (Microsoft Entra ID).
+// Tokens are cached, and refreshed shortly before they expire.
+type ClientCredentials = auth.ClientCredentials
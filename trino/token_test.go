@@ -0,0 +1,178 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+func newTokenServer(t *testing.T, expiresIn string) (*httptest.Server, *int) {
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "api://trino/.default", r.PostForm.Get("scope"))
+		count++
+		fmt.Fprintf(w, `{"access_token":"token%d","token_type":"Bearer","expires_in":%s}`, count, expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &count
+}
+
+func TestClientCredentials(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		expiresIn  string
+		wantTokens []string
+	}{
+		{name: "cached", expiresIn: "3600", wantTokens: []string{"token1", "token1"}},
+		{name: "cached string expiry", expiresIn: `"3600"`, wantTokens: []string{"token1", "token1"}},
+		{name: "expired", expiresIn: "1", wantTokens: []string{"token1", "token2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, _ := newTokenServer(t, tc.expiresIn)
+			source := &ClientCredentials{
+				TokenURL:     ts.URL,
+				ClientID:     "client",
+				ClientSecret: "secret",
+				Scopes:       []string{"api://trino/.default"},
+			}
+			var tokens []string
+			for range tc.wantTokens {
+				token, err := source.Token(context.Background())
+				require.NoError(t, err)
+				tokens = append(tokens, token)
+			}
+			assert.Equal(t, tc.wantTokens, tokens)
+		})
+	}
+}
+
+func TestClientCredentialsFailure(t *testing.T) {
+	ts, _ := newTokenServer(t, "3600")
+	source := &ClientCredentials{
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "invalid",
+	}
+	_, err := source.Token(context.Background())
+	var qf *ErrQueryFailed
+	require.ErrorAs(t, err, &qf)
+	assert.Equal(t, http.StatusUnauthorized, qf.StatusCode)
+}
+
+func TestTokenSourceAuth(t *testing.T) {
+	tokenServer, count := newTokenServer(t, "3600")
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{})
+
+	connector, err := NewConnector(&Config{
+		ServerURI: server.URL,
+		TokenSource: &ClientCredentials{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			Scopes:       []string{"api://trino/.default"},
+		},
+	})
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	_, err = db.Exec("SELECT 1")
+	require.NoError(t, err)
+	var authorizations []string
+	for _, r := range server.Requests() {
+		authorizations = append(authorizations, r.Header.Get(authorizationHeader))
+	}
+	assert.Equal(t, []string{"Bearer token1", "Bearer token1"}, authorizations)
+	assert.Equal(t, 1, *count)
+}
+
+func newTestJWT(t *testing.T, expiresAt time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return token
+}
+
+func TestExpiredAccessToken(t *testing.T) {
+	server := newMockServer(t)
+	server.Handle("SELECT 1", trinomock.Response{})
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "opaque", token: "token"},
+		{name: "valid", token: newTestJWT(t, time.Now().Add(time.Hour))},
+		{name: "expired", token: newTestJWT(t, time.Now().Add(-time.Hour)), wantErr: ErrTokenExpired},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sent := len(submitted(server))
+			dsn, err := (&Config{ServerURI: server.URL, AccessToken: tc.token}).FormatDSN()
+			require.NoError(t, err)
+			db := openTestDB(t, dsn)
+
+			_, err = db.Exec("SELECT 1")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Len(t, submitted(server), sent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, submitted(server), sent+1)
+		})
+	}
+}
+
+func TestClientCredentialsJWTExpiry(t *testing.T) {
+	token := newTestJWT(t, time.Now().Add(time.Hour))
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		fmt.Fprintf(w, `{"access_token":%q,"token_type":"Bearer"}`, token)
+	}))
+	t.Cleanup(ts.Close)
+
+	source := &ClientCredentials{TokenURL: ts.URL}
+	for i := 0; i < 2; i++ {
+		got, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, token, got)
+	}
+	assert.Equal(t, 1, count)
+}
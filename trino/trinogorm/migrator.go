@@ -0,0 +1,171 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trinogorm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// Migrator is a GORM migrator limited to the DDL supported by Trino. Trino has
+// no indexes or constraints, so creating them does nothing, and they're never found.
+// Tables are created with the table options set with gorm:table_options, like:
+//
+//	db.Set("gorm:table_options", "WITH (format = 'PARQUET')").Migrator().CreateTable(&User{})
+type Migrator struct {
+	migrator.Migrator
+}
+
+// CurrentDatabase returns the schema of the connections.
+func (m Migrator) CurrentDatabase() (name string) {
+	m.DB.Raw("SELECT current_schema").Row().Scan(&name)
+	return name
+}
+
+// FullDataTypeOf returns the type of a column with its NOT NULL and COMMENT
+// clauses, which Trino supports, unlike defaults and unique columns.
+func (m Migrator) FullDataTypeOf(field *schema.Field) clause.Expr {
+	expr := clause.Expr{SQL: m.DataTypeOf(field)}
+	if field.NotNull {
+		expr.SQL += " NOT NULL"
+	}
+	if field.Comment != "" {
+		// DDL statements don't accept parameters
+		expr.SQL += " COMMENT '" + strings.ReplaceAll(field.Comment, "'", "''") + "'"
+	}
+	return expr
+}
+
+// CreateTable creates tables with their columns, without primary keys and indexes.
+func (m Migrator) CreateTable(values ...interface{}) error {
+	for _, value := range m.ReorderModels(values, false) {
+		tx := m.DB.Session(&gorm.Session{})
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			if stmt.Schema == nil {
+				return fmt.Errorf("trinogorm: cannot create a table for %T without a schema", value)
+			}
+			sql := "CREATE TABLE ? ("
+			vars := []interface{}{m.CurrentTable(stmt)}
+			columns := 0
+			for _, dbName := range stmt.Schema.DBNames {
+				field := stmt.Schema.FieldsByDBName[dbName]
+				if field.IgnoreMigration {
+					continue
+				}
+				if columns > 0 {
+					sql += ", "
+				}
+				sql += "? ?"
+				vars = append(vars, clause.Column{Name: dbName}, m.DB.Migrator().FullDataTypeOf(field))
+				columns++
+			}
+			sql += ")"
+			if options, ok := m.DB.Get("gorm:table_options"); ok {
+				sql += " " + fmt.Sprint(options)
+			}
+			return tx.Exec(sql, vars...).Error
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddColumn adds a column to a table.
+func (m Migrator) AddColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return fmt.Errorf("trinogorm: failed to look up field with name: %s", name)
+		}
+		if field.IgnoreMigration {
+			return nil
+		}
+		return m.DB.Exec("ALTER TABLE ? ADD COLUMN ? ?",
+			m.CurrentTable(stmt), clause.Column{Name: field.DBName}, m.DB.Migrator().FullDataTypeOf(field)).Error
+	})
+}
+
+// AlterColumn changes the type of a column.
+func (m Migrator) AlterColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return fmt.Errorf("trinogorm: failed to look up field with name: %s", name)
+		}
+		return m.DB.Exec("ALTER TABLE ? ALTER COLUMN ? SET DATA TYPE ?",
+			m.CurrentTable(stmt), clause.Column{Name: field.DBName}, clause.Expr{SQL: m.DataTypeOf(field)}).Error
+	})
+}
+
+// typeParameters matches the parameters of types, like the precision of DECIMAL(10,2).
+var typeParameters = regexp.MustCompile(`\([0-9, ]*\)`)
+
+// MigrateColumn alters columns which type differs from the type of their field,
+// ignoring the parameters of the types, which the driver doesn't return by default.
+func (m Migrator) MigrateColumn(value interface{}, field *schema.Field, columnType gorm.ColumnType) error {
+	want := strings.ToLower(typeParameters.ReplaceAllString(m.DataTypeOf(field), ""))
+	got := strings.ToLower(typeParameters.ReplaceAllString(columnType.DatabaseTypeName(), ""))
+	if want == got {
+		return nil
+	}
+	return m.DB.Migrator().AlterColumn(value, field.DBName)
+}
+
+// MigrateColumnUnique does nothing, since Trino has no unique columns.
+func (m Migrator) MigrateColumnUnique(value interface{}, field *schema.Field, columnType gorm.ColumnType) error {
+	return nil
+}
+
+// CreateConstraint does nothing, since Trino has no constraints.
+func (m Migrator) CreateConstraint(value interface{}, name string) error {
+	return nil
+}
+
+// DropConstraint does nothing, since Trino has no constraints.
+func (m Migrator) DropConstraint(value interface{}, name string) error {
+	return nil
+}
+
+// HasConstraint returns false, since Trino has no constraints.
+func (m Migrator) HasConstraint(value interface{}, name string) bool {
+	return false
+}
+
+// CreateIndex does nothing, since Trino has no indexes.
+func (m Migrator) CreateIndex(value interface{}, name string) error {
+	return nil
+}
+
+// DropIndex does nothing, since Trino has no indexes.
+func (m Migrator) DropIndex(value interface{}, name string) error {
+	return nil
+}
+
+// HasIndex returns false, since Trino has no indexes.
+func (m Migrator) HasIndex(value interface{}, name string) bool {
+	return false
+}
+
+// RenameIndex does nothing, since Trino has no indexes.
+func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error {
+	return nil
+}
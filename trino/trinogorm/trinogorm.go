@@ -0,0 +1,230 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trinogorm provides a GORM dialector for Trino, using this driver:
+//
+//	db, err := gorm.Open(trinogorm.Open("http://user@localhost:8080?catalog=memory&schema=default"), &gorm.Config{})
+//
+// Trino has no transactions, so the dialector disables the default transactions
+// of GORM, which would fail otherwise. It has no auto-increment columns either,
+// so models must set their primary keys, declared with autoIncrement:false:
+//
+//	type User struct {
+//		ID   int64  `gorm:"primaryKey;autoIncrement:false"`
+//		Name string `gorm:"size:100"`
+//	}
+//
+// The driver doesn't accept float32 and float64 arguments, which could lose
+// precision, so use trino.Numeric fields for numbers with fractional digits,
+// with a type like `gorm:"type:DECIMAL(10,2)"`.
+//
+// Pagination with Limit and Offset is supported. The migrator only creates,
+// renames and drops tables, and adds, alters, renames and drops columns, since
+// Trino has no indexes or constraints.
+//
+// It's a separate module, so that the driver doesn't depend on GORM.
+package trinogorm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+
+	"github.com/trinodb/trino-go-client/trino"
+)
+
+// DefaultTimePrecision is the precision of the timestamp columns of time.Time
+// fields without one, like Trino's default precision.
+const DefaultTimePrecision = 3
+
+// Config is the configuration of a Dialector.
+type Config struct {
+	// DSN is the data source name of the driver, used to open connections.
+	DSN string
+	// Conn is used instead of opening connections with DSN, if set, like a
+	// *sql.DB opened with a trino.Connector (optional).
+	Conn gorm.ConnPool
+}
+
+// Dialector is a GORM dialector for Trino.
+type Dialector struct {
+	*Config
+}
+
+var _ gorm.Dialector = Dialector{}
+
+// Open returns a Dialector opening connections with the given DSN.
+func Open(dsn string) gorm.Dialector {
+	return Dialector{Config: &Config{DSN: dsn}}
+}
+
+// New returns a Dialector with the given configuration.
+func New(config Config) gorm.Dialector {
+	return Dialector{Config: &config}
+}
+
+// Name implements the gorm.Dialector interface.
+func (d Dialector) Name() string {
+	return "trino"
+}
+
+// Initialize implements the gorm.Dialector interface.
+func (d Dialector) Initialize(db *gorm.DB) error {
+	// the driver returns an error when beginning transactions
+	db.SkipDefaultTransaction = true
+	config := &callbacks.Config{}
+	callbacks.RegisterDefaultCallbacks(db, config)
+	if err := db.Callback().Create().Replace("gorm:create", create(config)); err != nil {
+		return err
+	}
+	db.ClauseBuilders["LIMIT"] = buildLimit
+	if d.Conn != nil {
+		db.ConnPool = d.Conn
+		return nil
+	}
+	var err error
+	db.ConnPool, err = sql.Open("trino", d.DSN)
+	return err
+}
+
+// create returns the create callback of GORM, ignoring the error of getting
+// the ID of the inserted rows, which the driver doesn't support.
+func create(config *callbacks.Config) func(db *gorm.DB) {
+	createFn := callbacks.Create(config)
+	return func(db *gorm.DB) {
+		failed := db.Error != nil
+		createFn(db)
+		var unsupported *trino.UnsupportedOperationError
+		if !failed && errors.As(db.Error, &unsupported) && unsupported.Operation == "LastInsertId" {
+			db.Error = nil
+		}
+	}
+}
+
+// buildLimit builds the LIMIT clause, which Trino only accepts after OFFSET.
+func buildLimit(c clause.Clause, builder clause.Builder) {
+	limit, ok := c.Expression.(clause.Limit)
+	if !ok {
+		c.Build(builder)
+		return
+	}
+	if limit.Offset > 0 {
+		builder.WriteString("OFFSET ")
+		builder.WriteString(strconv.Itoa(limit.Offset))
+	}
+	if limit.Limit != nil && *limit.Limit >= 0 {
+		if limit.Offset > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString("LIMIT ")
+		builder.WriteString(strconv.Itoa(*limit.Limit))
+	}
+}
+
+// Migrator implements the gorm.Dialector interface.
+func (d Dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return Migrator{migrator.Migrator{Config: migrator.Config{
+		DB:        db,
+		Dialector: d,
+	}}}
+}
+
+// DataTypeOf implements the gorm.Dialector interface.
+func (d Dialector) DataTypeOf(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "BOOLEAN"
+	case schema.Int:
+		switch {
+		case field.Size > 0 && field.Size <= 8:
+			return "TINYINT"
+		case field.Size > 0 && field.Size <= 16:
+			return "SMALLINT"
+		case field.Size > 0 && field.Size <= 32:
+			return "INTEGER"
+		}
+		return "BIGINT"
+	case schema.Uint:
+		switch {
+		case field.Size > 0 && field.Size <= 8:
+			return "SMALLINT"
+		case field.Size > 0 && field.Size <= 16:
+			return "INTEGER"
+		case field.Size > 0 && field.Size <= 32:
+			return "BIGINT"
+		}
+		return "DECIMAL(20,0)"
+	case schema.Float:
+		if field.Precision > 0 {
+			return fmt.Sprintf("DECIMAL(%d,%d)", field.Precision, field.Scale)
+		}
+		if field.Size > 0 && field.Size <= 32 {
+			return "REAL"
+		}
+		return "DOUBLE"
+	case schema.String:
+		if field.Size > 0 {
+			return fmt.Sprintf("VARCHAR(%d)", field.Size)
+		}
+		return "VARCHAR"
+	case schema.Time:
+		precision := field.Precision
+		if precision == 0 {
+			precision = DefaultTimePrecision
+		}
+		// the driver passes time.Time arguments with their time zone
+		return fmt.Sprintf("TIMESTAMP(%d) WITH TIME ZONE", precision)
+	case schema.Bytes:
+		return "VARBINARY"
+	}
+	return string(field.DataType)
+}
+
+// DefaultValueOf implements the gorm.Dialector interface. Trino has no column
+// defaults, so missing values are inserted as NULL.
+func (d Dialector) DefaultValueOf(field *schema.Field) clause.Expression {
+	return clause.Expr{SQL: "NULL"}
+}
+
+// BindVarTo implements the gorm.Dialector interface.
+func (d Dialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('?')
+}
+
+// QuoteTo implements the gorm.Dialector interface, quoting each part of
+// qualified names, like catalog.schema.table, with double quotes.
+func (d Dialector) QuoteTo(writer clause.Writer, str string) {
+	for i, part := range strings.Split(str, ".") {
+		if i > 0 {
+			writer.WriteByte('.')
+		}
+		writer.WriteByte('"')
+		writer.WriteString(strings.ReplaceAll(part, `"`, `""`))
+		writer.WriteByte('"')
+	}
+}
+
+// Explain implements the gorm.Dialector interface.
+func (d Dialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trinogorm
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/trinodb/trino-go-client/trino"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+type user struct {
+	ID      int64         `gorm:"primaryKey;autoIncrement:false"`
+	Name    string        `gorm:"size:100;not null;comment:the user's name"`
+	Age     int32         `gorm:"index"`
+	Balance trino.Numeric `gorm:"type:DECIMAL(10,2)"`
+	Active  bool
+	Created time.Time
+}
+
+func openDB(t *testing.T, server *trinomock.Server) *gorm.DB {
+	db, err := gorm.Open(Open(server.DSN()), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, sqlDB.Close())
+	})
+	return db
+}
+
+func TestDataTypeOf(t *testing.T) {
+	type model struct {
+		I8      int8
+		I64     int64
+		U32     uint32
+		U64     uint64
+		F32     float32
+		F64     float64
+		Amount  float64 `gorm:"precision:10;scale:2"`
+		Text    string
+		Name    string `gorm:"size:100"`
+		Flag    bool
+		Created time.Time
+		Updated time.Time `gorm:"precision:6"`
+		Data    []byte
+	}
+	s, err := schema.Parse(&model{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	d := Dialector{Config: &Config{}}
+	var types []string
+	for _, dbName := range s.DBNames {
+		types = append(types, d.DataTypeOf(s.FieldsByDBName[dbName]))
+	}
+	assert.Equal(t, []string{
+		"TINYINT", "BIGINT", "BIGINT", "DECIMAL(20,0)", "REAL", "DOUBLE", "DECIMAL(10,2)", "VARCHAR", "VARCHAR(100)",
+		"BOOLEAN", "TIMESTAMP(3) WITH TIME ZONE", "TIMESTAMP(6) WITH TIME ZONE", "VARBINARY",
+	}, types)
+}
+
+func TestPagination(t *testing.T) {
+	server := trinomock.NewServer()
+	t.Cleanup(server.Close)
+	db := openDB(t, server).Session(&gorm.Session{DryRun: true})
+
+	var users []user
+	stmt := db.Where("age > ?", 18).Order("id").Offset(20).Limit(10).Find(&users).Statement
+	assert.Equal(t, `SELECT * FROM "users" WHERE age > ? ORDER BY id OFFSET 20 LIMIT 10`, stmt.SQL.String())
+	stmt = db.Limit(10).Find(&users).Statement
+	assert.Equal(t, `SELECT * FROM "users" LIMIT 10`, stmt.SQL.String())
+}
+
+func TestCreateTable(t *testing.T) {
+	server := trinomock.NewServer()
+	t.Cleanup(server.Close)
+	server.HandleMatch(func(statement string) bool {
+		return strings.HasPrefix(statement, "CREATE TABLE")
+	}, trinomock.Response{UpdateType: "CREATE TABLE"})
+	db := openDB(t, server)
+
+	require.NoError(t, db.Set("gorm:table_options", "WITH (format = 'PARQUET')").Migrator().CreateTable(&user{}))
+	assert.Equal(t, []string{`CREATE TABLE "users" (` +
+		`"id" BIGINT, "name" VARCHAR(100) NOT NULL COMMENT 'the user''s name', "age" INTEGER, "balance" DECIMAL(10,2), ` +
+		`"active" BOOLEAN, "created" TIMESTAMP(3) WITH TIME ZONE) WITH (format = 'PARQUET')`,
+	}, server.Statements())
+}
+
+func TestCreateAndFind(t *testing.T) {
+	server := trinomock.NewServer()
+	t.Cleanup(server.Close)
+	server.HandleMatch(func(statement string) bool {
+		return strings.HasPrefix(statement, `INSERT INTO "users"`)
+	}, trinomock.Response{UpdateType: "INSERT", UpdateCount: 1})
+	server.HandleMatch(func(statement string) bool {
+		return strings.HasPrefix(statement, `SELECT * FROM "users"`)
+	}, trinomock.Response{
+		Columns: []trinomock.Column{
+			{Name: "id", Type: "bigint"},
+			{Name: "name", Type: "varchar(100)"},
+			{Name: "age", Type: "integer"},
+			{Name: "balance", Type: "decimal(10,2)"},
+			{Name: "active", Type: "boolean"},
+			{Name: "created", Type: "timestamp(3) with time zone"},
+		},
+		Rows: [][]interface{}{{1, "alice", 30, "12.50", true, "2024-01-02 03:04:05.000 UTC"}},
+	})
+	db := openDB(t, server)
+
+	result := db.Create(&user{ID: 1, Name: "alice", Age: 30, Balance: "12.5", Active: true, Created: time.Now()})
+	require.NoError(t, result.Error, "creating doesn't begin a transaction")
+	assert.Equal(t, int64(1), result.RowsAffected)
+
+	var users []user
+	require.NoError(t, db.Where("name = ?", "alice").Limit(1).Find(&users).Error)
+	assert.Equal(t, []user{{
+		ID:      1,
+		Name:    "alice",
+		Age:     30,
+		Balance: "12.50",
+		Active:  true,
+		Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}}, users)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trinomock
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	useRegexp                = regexp.MustCompile(`(?is)^USE\s+(?:([^\s.]+)\.)?([^\s.]+)$`)
+	setAuthorizationRegexp   = regexp.MustCompile(`(?is)^SET\s+SESSION\s+AUTHORIZATION\s+(\S+)$`)
+	resetAuthorizationRegexp = regexp.MustCompile(`(?is)^RESET\s+SESSION\s+AUTHORIZATION$`)
+	setSessionRegexp         = regexp.MustCompile(`(?is)^SET\s+SESSION\s+([\w.]+)\s*=\s*(.+)$`)
+	resetSessionRegexp       = regexp.MustCompile(`(?is)^RESET\s+SESSION\s+([\w.]+)$`)
+	prepareRegexp            = regexp.MustCompile(`(?is)^PREPARE\s+(\w+)\s+FROM\s+(.+)$`)
+	deallocatePrepareRegexp  = regexp.MustCompile(`(?is)^DEALLOCATE\s+PREPARE\s+(\w+)$`)
+)
+
+// sessionHeaders returns the headers of the response to a statement changing
+// the session of the client, like USE or SET SESSION, as Trino sets them, or
+// nil for the other statements.
+func sessionHeaders(statement string) http.Header {
+	h := make(http.Header)
+	if m := useRegexp.FindStringSubmatch(statement); m != nil {
+		if m[1] != "" {
+			h.Set("X-Trino-Set-Catalog", unquote(m[1]))
+		}
+		h.Set("X-Trino-Set-Schema", unquote(m[2]))
+	} else if m := setAuthorizationRegexp.FindStringSubmatch(statement); m != nil {
+		h.Set("X-Trino-Set-Authorization-User", unquote(m[1]))
+	} else if resetAuthorizationRegexp.MatchString(statement) {
+		h.Set("X-Trino-Reset-Authorization-User", "true")
+	} else if m := setSessionRegexp.FindStringSubmatch(statement); m != nil {
+		h.Set("X-Trino-Set-Session", m[1]+"="+url.QueryEscape(unquote(strings.TrimSpace(m[2]))))
+	} else if m := resetSessionRegexp.FindStringSubmatch(statement); m != nil {
+		h.Set("X-Trino-Clear-Session", m[1])
+	} else if m := prepareRegexp.FindStringSubmatch(statement); m != nil {
+		h.Set("X-Trino-Added-Prepare", m[1]+"="+url.QueryEscape(strings.TrimSpace(m[2])))
+	} else if m := deallocatePrepareRegexp.FindStringSubmatch(statement); m != nil {
+		h.Set("X-Trino-Deallocated-Prepare", m[1])
+	} else {
+		return nil
+	}
+	return h
+}
+
+// unquote returns the value of a quoted identifier or string literal, or s
+// if it's not quoted.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		q := string(s[0])
+		return strings.ReplaceAll(s[1:len(s)-1], q+q, q)
+	}
+	return s
+}
@@ -0,0 +1,536 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trinomock provides an in-memory Trino server implementing enough of
+// the client REST protocol to unit test code running queries, like its retries
+// and error handling, deterministically and without Docker:
+//
+//	server := trinomock.NewServer()
+//	defer server.Close()
+//	server.Handle("SELECT name FROM users WHERE id = ?", trinomock.Response{
+//		Columns: []trinomock.Column{{Name: "name", Type: "varchar"}},
+//		Rows:    [][]interface{}{{"alice"}},
+//	})
+//	server.Handle("SELECT broken", trinomock.Response{
+//		Error: &trinomock.Error{Message: "line 1:8: Column 'broken' cannot be resolved", ErrorName: "COLUMN_NOT_FOUND"},
+//	})
+//	db, err := sql.Open("trino", server.DSN())
+//
+// Statements are matched without their arguments, which the driver folds into
+// EXECUTE statements. Values of rows are encoded in JSON as they are, so they
+// must be encoded like the server does, like decimals and timestamps as strings.
+//
+// Like Trino, the server answers the statements changing the session of the
+// client, like USE, SET SESSION, PREPARE or SET SESSION AUTHORIZATION, with the
+// headers applying the change, and they succeed without a handler.
+package trinomock
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const preparedStatementHeader = "X-Trino-Prepared-Statement"
+
+// Response is the response of the server to the statements matching a handler.
+type Response struct {
+	// Columns are the columns of the results.
+	Columns []Column
+	// Rows are the rows of the results, with a value for each column.
+	Rows [][]interface{}
+	// PageSize is the number of rows returned in each response. All the rows
+	// are returned in a single response if it's zero.
+	PageSize int
+	// QueuedPages is the number of responses without results returned before
+	// the first page of rows, while the query is queued.
+	QueuedPages int
+	// PageDelay delays every response, to test timeouts and cancellation.
+	PageDelay time.Duration
+	// UpdateType and UpdateCount are the type of the statement and the number of
+	// rows it modified, like INSERT and 10. Like Trino, the type is returned with
+	// every page of rows, and the count with the last response.
+	UpdateType  string
+	UpdateCount int64
+	// Error fails the query after returning all its pages of rows.
+	Error *Error
+	// Faults are HTTP errors returned instead of responses of the query.
+	Faults []Fault
+	// Header are headers of the response to the statement, like X-Trino-Set-Session,
+	// overriding those set for the statements changing the session.
+	Header http.Header
+}
+
+// Column is a column of the results of a query.
+type Column struct {
+	// Name is the name of the column.
+	Name string
+	// Type is the Trino type of the column, like bigint or decimal(10,2).
+	Type string
+}
+
+// Error is the error of a failed query.
+type Error struct {
+	Message string
+	// ErrorName is the name of the error, like SYNTAX_ERROR. It defaults to GENERIC_USER_ERROR.
+	ErrorName string
+	// ErrorType is the type of the error, like USER_ERROR. It defaults to USER_ERROR.
+	ErrorType string
+	ErrorCode int
+	SqlState  string
+	// LineNumber and ColumnNumber are the location of the error in the
+	// statement, starting at 1, if set.
+	LineNumber   int
+	ColumnNumber int
+}
+
+// Fault is an HTTP error returned instead of a response of a query, like
+// 503 Service Unavailable or 429 Too Many Requests, which the driver retries.
+type Fault struct {
+	// Request is the request of the query failing, 0 for the request submitting
+	// it, 1 for the request of its first nextUri, and so on. Faults of the
+	// request submitting the query apply to the first statements matching the
+	// handler, since there's no query yet.
+	Request int
+	// StatusCode is the status of the response.
+	StatusCode int
+	// Header are headers of the response, like Retry-After.
+	Header http.Header
+	// Count is the number of times the request fails, which defaults to 1.
+	Count int
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	// Body is the body of the request, decompressed, with the text of the
+	// statement submitted.
+	Body string
+}
+
+// Server is an in-memory Trino server.
+type Server struct {
+	// URL is the base URL of the server.
+	URL string
+
+	ts *httptest.Server
+
+	mu         sync.Mutex
+	handlers   []*handler
+	queries    map[string]*query
+	nextID     int
+	requests   []Request
+	statements []string
+}
+
+type handler struct {
+	match func(statement string) bool
+	resp  Response
+	// faults is the number of remaining failures of each fault of the submitting request.
+	faults []int
+}
+
+type query struct {
+	id        string
+	resp      *Response
+	pages     []page
+	faults    []int
+	cancelled bool
+}
+
+// NewServer starts a Server. Close it once it's no longer used.
+func NewServer() *Server {
+	s := &Server{queries: make(map[string]*query)}
+	s.ts = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.ts.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+// DSN returns a data source name connecting to the server as the test user.
+func (s *Server) DSN() string {
+	return strings.Replace(s.URL, "http://", "http://test@", 1)
+}
+
+// Handle returns resp for statements equal to statement, ignoring leading and
+// trailing whitespace. Handlers are matched in the order they were added.
+func (s *Server) Handle(statement string, resp Response) {
+	statement = strings.TrimSpace(statement)
+	s.HandleMatch(func(s string) bool {
+		return s == statement
+	}, resp)
+}
+
+// HandleMatch returns resp for statements for which match returns true.
+// Handlers are matched in the order they were added.
+func (s *Server) HandleMatch(match func(statement string) bool, resp Response) {
+	h := &handler{match: match, resp: resp}
+	for _, f := range resp.Faults {
+		if f.Request == 0 {
+			h.faults = append(h.faults, faultCount(f))
+		} else {
+			h.faults = append(h.faults, 0)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, h)
+}
+
+// Requests returns the requests received by the server.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Statements returns the statements submitted to the server, without their arguments.
+func (s *Server) Statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.statements...)
+}
+
+// Cancelled reports whether the query with the given ID was cancelled by the client.
+func (s *Server) Cancelled(queryID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[queryID]
+	return ok && q.cancelled
+}
+
+func faultCount(f Fault) int {
+	if f.Count == 0 {
+		return 1
+	}
+	return f.Count
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	reader := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reader = zr
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: string(body)})
+	s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/statement":
+		s.submit(w, r, string(body))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/statement/executing/"):
+		s.next(w, r)
+	case r.Method == http.MethodDelete:
+		s.cancel(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, body string) {
+	statement := statementText(body, r.Header)
+	session := sessionHeaders(statement)
+	s.mu.Lock()
+	var h *handler
+	for _, candidate := range s.handlers {
+		if candidate.match(statement) {
+			h = candidate
+			break
+		}
+	}
+	if h == nil && session != nil {
+		h = &handler{}
+	}
+	if h == nil {
+		h = &handler{resp: Response{Error: &Error{
+			Message:   "trinomock: no response for statement: " + statement,
+			ErrorName: "NOT_SUPPORTED",
+		}}}
+	}
+	for i, remaining := range h.faults {
+		if remaining > 0 {
+			h.faults[i]--
+			s.mu.Unlock()
+			writeFault(w, h.resp.Faults[i])
+			return
+		}
+	}
+	s.nextID++
+	q := &query{
+		id:   fmt.Sprintf("trinomock_%d", s.nextID),
+		resp: &h.resp,
+	}
+	for _, f := range h.resp.Faults {
+		if f.Request == 0 {
+			q.faults = append(q.faults, 0)
+		} else {
+			q.faults = append(q.faults, faultCount(f))
+		}
+	}
+	q.pages = newPages(h.resp)
+	s.queries[q.id] = q
+	s.statements = append(s.statements, statement)
+	s.mu.Unlock()
+
+	for k, v := range session {
+		w.Header()[k] = v
+	}
+	for k, v := range h.resp.Header {
+		w.Header()[k] = v
+	}
+	s.writePage(w, r, q, 0)
+}
+
+func (s *Server) next(w http.ResponseWriter, r *http.Request) {
+	// the path is /v1/statement/executing/{queryId}/{token}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/statement/executing/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	token, err := strconv.Atoi(parts[1])
+	s.mu.Lock()
+	q, ok := s.queries[parts[0]]
+	if !ok || err != nil || token < 1 || token >= len(q.pages) {
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	for i, f := range q.resp.Faults {
+		if f.Request == token && q.faults[i] > 0 {
+			q.faults[i]--
+			s.mu.Unlock()
+			writeFault(w, f)
+			return
+		}
+	}
+	s.mu.Unlock()
+	s.writePage(w, r, q, token)
+}
+
+func (s *Server) cancel(w http.ResponseWriter, r *http.Request) {
+	// queries are cancelled with either /v1/query/{queryId} or their nextUri
+	id := strings.TrimPrefix(r.URL.Path, "/v1/query/")
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/v1/statement/executing/"); ok {
+		id, _, _ = strings.Cut(rest, "/")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	q.cancelled = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeFault(w http.ResponseWriter, f Fault) {
+	for k, v := range f.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(f.StatusCode)
+}
+
+// page is a response of a query, without its URIs.
+type page struct {
+	State       string          `json:"-"`
+	Columns     []column        `json:"columns,omitempty"`
+	Data        [][]interface{} `json:"data,omitempty"`
+	UpdateType  string          `json:"updateType,omitempty"`
+	UpdateCount int64           `json:"updateCount,omitempty"`
+	Error       *queryError     `json:"error,omitempty"`
+	rows        int64
+}
+
+type queryResponse struct {
+	ID      string `json:"id"`
+	InfoURI string `json:"infoUri"`
+	NextURI string `json:"nextUri,omitempty"`
+	page
+	Stats stats `json:"stats"`
+}
+
+type stats struct {
+	State         string `json:"state"`
+	Scheduled     bool   `json:"scheduled"`
+	ProcessedRows int64  `json:"processedRows"`
+}
+
+type queryError struct {
+	Message       string         `json:"message"`
+	ErrorCode     int            `json:"errorCode"`
+	ErrorName     string         `json:"errorName"`
+	ErrorType     string         `json:"errorType"`
+	SqlState      string         `json:"sqlState,omitempty"`
+	ErrorLocation *errorLocation `json:"errorLocation,omitempty"`
+}
+
+type errorLocation struct {
+	LineNumber   int `json:"lineNumber"`
+	ColumnNumber int `json:"columnNumber"`
+}
+
+type column struct {
+	Name          string        `json:"name"`
+	Type          string        `json:"type"`
+	TypeSignature typeSignature `json:"typeSignature"`
+}
+
+// newPages returns the responses of a query: the one submitting it, the ones
+// of queued pages, of pages of rows, and the last one, finishing or failing it.
+func newPages(resp Response) []page {
+	columns := make([]column, len(resp.Columns))
+	for i, c := range resp.Columns {
+		columns[i] = column{Name: c.Name, Type: strings.ToLower(c.Type), TypeSignature: parseType(c.Type)}
+	}
+	pages := []page{{State: "QUEUED"}}
+	for i := 0; i < resp.QueuedPages; i++ {
+		pages = append(pages, page{State: "QUEUED"})
+	}
+	pageSize := resp.PageSize
+	if pageSize <= 0 {
+		pageSize = len(resp.Rows)
+	}
+	var processed int64
+	for start := 0; start < len(resp.Rows); start += pageSize {
+		data := resp.Rows[start:min(start+pageSize, len(resp.Rows))]
+		processed += int64(len(data))
+		pages = append(pages, page{State: "RUNNING", Columns: columns, Data: data, UpdateType: resp.UpdateType, rows: processed})
+	}
+	last := page{State: "FINISHED", Columns: columns, UpdateType: resp.UpdateType, UpdateCount: resp.UpdateCount, rows: processed}
+	if resp.Error != nil {
+		last.State = "FAILED"
+		last.Error = newQueryError(*resp.Error)
+	}
+	return append(pages, last)
+}
+
+func newQueryError(e Error) *queryError {
+	qe := &queryError{
+		Message:   e.Message,
+		ErrorCode: e.ErrorCode,
+		ErrorName: e.ErrorName,
+		ErrorType: e.ErrorType,
+		SqlState:  e.SqlState,
+	}
+	if qe.ErrorName == "" {
+		qe.ErrorName = "GENERIC_USER_ERROR"
+	}
+	if qe.ErrorType == "" {
+		qe.ErrorType = "USER_ERROR"
+	}
+	if e.LineNumber > 0 {
+		qe.ErrorLocation = &errorLocation{LineNumber: e.LineNumber, ColumnNumber: e.ColumnNumber}
+	}
+	return qe
+}
+
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, q *query, token int) {
+	if q.resp.PageDelay > 0 {
+		select {
+		case <-time.After(q.resp.PageDelay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	s.mu.Lock()
+	p := q.pages[token]
+	if q.cancelled {
+		p = page{State: "FAILED", Error: &queryError{Message: "Query was canceled", ErrorCode: 3, ErrorName: "USER_CANCELLED", ErrorType: "USER_ERROR"}}
+	}
+	s.mu.Unlock()
+
+	resp := queryResponse{
+		ID:      q.id,
+		InfoURI: s.URL + "/ui/query.html?" + q.id,
+		page:    p,
+		Stats:   stats{State: p.State, Scheduled: p.State != "QUEUED", ProcessedRows: p.rows},
+	}
+	if p.State != "FINISHED" && p.State != "FAILED" {
+		resp.NextURI = fmt.Sprintf("%s/v1/statement/executing/%s/%d", s.URL, q.id, token+1)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&resp)
+}
+
+var executeRegexp = regexp.MustCompile(`(?is)^EXECUTE\s+(\w+)(?:\s+USING\b.*)?$`)
+
+// statementText returns the text of a statement, without the EXECUTE statements
+// the driver uses to pass arguments.
+func statementText(body string, header http.Header) string {
+	body = strings.TrimSpace(body)
+	if rest, ok := cutPrefixFold(body, "EXECUTE IMMEDIATE '"); ok {
+		var b strings.Builder
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == '\'' {
+				if i+1 < len(rest) && rest[i+1] == '\'' {
+					i++
+				} else {
+					return strings.TrimSpace(b.String())
+				}
+			}
+			b.WriteByte(rest[i])
+		}
+		return body
+	}
+	m := executeRegexp.FindStringSubmatch(body)
+	if m == nil {
+		return body
+	}
+	for _, v := range header.Values(preparedStatementHeader) {
+		for _, prepared := range strings.Split(v, ",") {
+			name, statement, ok := strings.Cut(strings.TrimSpace(prepared), "=")
+			if !ok || name != m[1] {
+				continue
+			}
+			if text, err := url.QueryUnescape(statement); err == nil {
+				return strings.TrimSpace(text)
+			}
+		}
+	}
+	return body
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,222 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trinomock
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trinodb/trino-go-client/trino"
+)
+
+func openDB(t *testing.T, dsn string) *sql.DB {
+	db, err := sql.Open("trino", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db
+}
+
+func TestServerRows(t *testing.T) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("SELECT id, tags FROM t WHERE name = ?", Response{
+		Columns: []Column{
+			{Name: "id", Type: "bigint"},
+			{Name: "tags", Type: "array(varchar(10))"},
+			{Name: "price", Type: "decimal(10,2)"},
+		},
+		Rows:        [][]interface{}{{1, []string{"a"}, "1.50"}, {2, nil, nil}, {3, []string{}, "0.00"}},
+		PageSize:    2,
+		QueuedPages: 2,
+	})
+
+	for _, dsn := range []string{server.DSN(), server.DSN() + "?explicitPrepare=false"} {
+		db := openDB(t, dsn)
+		rows, err := db.Query("SELECT id, tags FROM t WHERE name = ?", "it's")
+		require.NoError(t, err)
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			var tags trino.NullSliceString
+			var price sql.NullString
+			require.NoError(t, rows.Scan(&id, &tags, &price))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		require.NoError(t, rows.Close())
+		assert.Equal(t, []int64{1, 2, 3}, ids)
+	}
+	assert.Equal(t, []string{"SELECT id, tags FROM t WHERE name = ?", "SELECT id, tags FROM t WHERE name = ?"}, server.Statements())
+}
+
+func TestServerUpdate(t *testing.T) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+	server.HandleMatch(func(statement string) bool {
+		return strings.HasPrefix(statement, "INSERT ")
+	}, Response{UpdateType: "INSERT", UpdateCount: 3})
+
+	result, err := openDB(t, server.DSN()).Exec("INSERT INTO t VALUES (1), (2), (3)")
+	require.NoError(t, err)
+	n, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestServerErrors(t *testing.T) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("SELECT broken", Response{
+		Columns: []Column{{Name: "x", Type: "bigint"}},
+		Rows:    [][]interface{}{{1}},
+		Error:   &Error{Message: "Division by zero", ErrorName: "DIVISION_BY_ZERO", ErrorCode: 8, LineNumber: 1, ColumnNumber: 8},
+	})
+	db := openDB(t, server.DSN())
+
+	rows, err := db.Query("SELECT broken")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.False(t, rows.Next())
+	var trinoErr *trino.ErrTrino
+	require.True(t, errors.As(rows.Err(), &trinoErr), rows.Err())
+	assert.Equal(t, "DIVISION_BY_ZERO", trinoErr.ErrorName)
+	assert.Equal(t, "USER_ERROR", trinoErr.ErrorType)
+	assert.Equal(t, trino.ErrorLocation{LineNumber: 1, ColumnNumber: 8}, trinoErr.ErrorLocation)
+	require.NoError(t, rows.Close())
+
+	_, err = db.Exec("SELECT unknown")
+	require.True(t, errors.As(err, &trinoErr), err)
+	assert.Equal(t, "NOT_SUPPORTED", trinoErr.ErrorName)
+}
+
+func TestServerFaults(t *testing.T) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("SELECT 1", Response{
+		Columns: []Column{{Name: "_col0", Type: "integer"}},
+		Rows:    [][]interface{}{{1}},
+		Faults: []Fault{
+			{Request: 0, StatusCode: http.StatusServiceUnavailable, Count: 2},
+			{Request: 1, StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"0"}}},
+		},
+	})
+	server.Handle("SELECT 2", Response{
+		Faults: []Fault{{Request: 1, StatusCode: http.StatusInternalServerError}},
+	})
+	db := openDB(t, server.DSN())
+
+	var v int
+	require.NoError(t, db.QueryRow("SELECT 1").Scan(&v))
+	assert.Equal(t, 1, v)
+	var methods []string
+	for _, r := range server.Requests() {
+		methods = append(methods, r.Method)
+	}
+	assert.Equal(t, []string{"POST", "POST", "POST", "GET", "GET"}, methods[:5])
+
+	_, err := db.Exec("SELECT 2")
+	var queryErr *trino.ErrQueryFailed
+	require.True(t, errors.As(err, &queryErr), err)
+	assert.Equal(t, http.StatusInternalServerError, queryErr.StatusCode)
+}
+
+func TestServerCancel(t *testing.T) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("SELECT x FROM large", Response{
+		Columns:   []Column{{Name: "x", Type: "bigint"}},
+		Rows:      [][]interface{}{{1}, {2}, {3}},
+		PageSize:  1,
+		PageDelay: 10 * time.Millisecond,
+	})
+
+	rows, err := openDB(t, server.DSN()).Query("SELECT x FROM large")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Close())
+	assert.True(t, server.Cancelled("trinomock_1"))
+	assert.False(t, server.Cancelled("trinomock_2"))
+}
+
+func TestServerSession(t *testing.T) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("SELECT 1", Response{})
+	server.Handle("SET SESSION query_priority = 0", Response{Error: &Error{Message: "Invalid query_priority"}})
+	db := openDB(t, server.DSN()+"?compress_request_body=true")
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, conn.Close())
+	})
+
+	for _, statement := range []string{
+		"USE tpch.tiny",
+		"SET SESSION query_max_run_time = '1h 30m'",
+		"SET SESSION AUTHORIZATION alice",
+		"PREPARE a FROM SELECT 1",
+		"SELECT 1",
+	} {
+		_, err = conn.ExecContext(context.Background(), statement)
+		require.NoError(t, err, statement)
+	}
+	_, err = conn.ExecContext(context.Background(), "SET SESSION query_priority = 0")
+	assert.Error(t, err, "handlers override the statements changing the session")
+
+	requests := server.Requests()
+	var last Request
+	for _, r := range requests {
+		if r.Method == http.MethodPost {
+			last = r
+		}
+	}
+	assert.Equal(t, "SET SESSION query_priority = 0", last.Body, "compressed bodies are decompressed")
+	for _, r := range requests {
+		if r.Method == http.MethodPost && r.Body == "SELECT 1" {
+			assert.Equal(t, "tpch", r.Header.Get("X-Trino-Catalog"))
+			assert.Equal(t, "tiny", r.Header.Get("X-Trino-Schema"))
+			assert.Equal(t, "query_max_run_time=1h+30m", r.Header.Get("X-Trino-Session"))
+			assert.Equal(t, "alice", r.Header.Get("X-Trino-User"))
+			assert.Equal(t, "a=SELECT+1", r.Header.Get(preparedStatementHeader))
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for typ, want := range map[string]string{
+		"bigint":                      `{"rawType":"bigint","arguments":[]}`,
+		"VARCHAR(10)":                 `{"rawType":"varchar","arguments":[{"kind":"LONG","value":10}]}`,
+		"timestamp(3) with time zone": `{"rawType":"timestamp with time zone","arguments":[{"kind":"LONG","value":3}]}`,
+		"map(varchar, array(bigint))": `{"rawType":"map","arguments":[{"kind":"TYPE","value":{"rawType":"varchar","arguments":[]}},{"kind":"TYPE","value":{"rawType":"array","arguments":[{"kind":"TYPE","value":{"rawType":"bigint","arguments":[]}}]}}]}`,
+		"row(x double, time(3) with time zone)": `{"rawType":"row","arguments":[` +
+			`{"kind":"NAMED_TYPE","value":{"fieldName":{"name":"x"},"typeSignature":{"rawType":"double","arguments":[]}}},` +
+			`{"kind":"NAMED_TYPE","value":{"fieldName":null,"typeSignature":{"rawType":"time with time zone","arguments":[{"kind":"LONG","value":3}]}}}]}`,
+	} {
+		b, err := json.Marshal(parseType(typ))
+		require.NoError(t, err)
+		assert.JSONEq(t, want, string(b), typ)
+	}
+}
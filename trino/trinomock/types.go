@@ -0,0 +1,125 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trinomock
+
+import (
+	"strconv"
+	"strings"
+)
+
+type typeSignature struct {
+	RawType   string         `json:"rawType"`
+	Arguments []typeArgument `json:"arguments"`
+}
+
+type typeArgument struct {
+	Kind  string      `json:"kind"`
+	Value interface{} `json:"value"`
+}
+
+type namedTypeSignature struct {
+	FieldName     *rowFieldName `json:"fieldName"`
+	TypeSignature typeSignature `json:"typeSignature"`
+}
+
+type rowFieldName struct {
+	Name string `json:"name"`
+}
+
+// parseType returns the signature of a type, like array(decimal(10,2)) or
+// timestamp(3) with time zone, as returned by the server.
+func parseType(t string) typeSignature {
+	t = strings.ToLower(strings.TrimSpace(t))
+	open := strings.IndexByte(t, '(')
+	if open < 0 {
+		return typeSignature{RawType: t, Arguments: []typeArgument{}}
+	}
+	end := closingParen(t, open)
+	sig := typeSignature{
+		RawType:   strings.TrimSpace(t[:open] + t[end+1:]),
+		Arguments: []typeArgument{},
+	}
+	for _, arg := range splitArguments(t[open+1 : end]) {
+		switch {
+		case sig.RawType == "row":
+			named := namedTypeSignature{TypeSignature: parseType(arg)}
+			if name, typ, ok := cutFieldName(arg); ok {
+				named = namedTypeSignature{FieldName: &rowFieldName{Name: name}, TypeSignature: parseType(typ)}
+			}
+			sig.Arguments = append(sig.Arguments, typeArgument{Kind: "NAMED_TYPE", Value: named})
+		case sig.RawType == "array" || sig.RawType == "map":
+			sig.Arguments = append(sig.Arguments, typeArgument{Kind: "TYPE", Value: parseType(arg)})
+		default:
+			n, _ := strconv.ParseInt(arg, 10, 64)
+			sig.Arguments = append(sig.Arguments, typeArgument{Kind: "LONG", Value: n})
+		}
+	}
+	return sig
+}
+
+// closingParen returns the index of the parenthesis closing the one at open.
+func closingParen(t string, open int) int {
+	depth := 0
+	for i := open; i < len(t); i++ {
+		switch t[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(t) - 1
+}
+
+// splitArguments splits the arguments of a type on commas outside of parentheses.
+func splitArguments(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
+// multiWordTypes are the types which names contain spaces, so they're not
+// mistaken for the names of row fields.
+var multiWordTypes = []string{"timestamp", "time", "interval", "double"}
+
+// cutFieldName splits a field of a row type into its name and type, if it's named.
+func cutFieldName(field string) (string, string, bool) {
+	name, typ, ok := strings.Cut(field, " ")
+	if !ok || strings.ContainsRune(name, '(') {
+		return "", "", false
+	}
+	for _, t := range multiWordTypes {
+		if name == t {
+			return "", "", false
+		}
+	}
+	return strings.Trim(name, `"`), strings.TrimSpace(typ), true
+}
@@ -0,0 +1,340 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trinotest runs a Trino server in a Docker container, to test queries
+// against a real Trino in integration tests:
+//
+//	import (
+//		"context"
+//		"database/sql"
+//		"testing"
+//
+//		_ "github.com/trinodb/trino-go-client/trino"
+//		"github.com/trinodb/trino-go-client/trino/trinotest"
+//	)
+//
+//	func TestOrders(t *testing.T) {
+//		server := trinotest.StartContainer(t, nil)
+//		db, err := sql.Open("trino", server.DSN)
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		defer db.Close()
+//		err = trinotest.LoadTables(context.Background(), db, trinotest.Table{
+//			Name:    "memory.default.orders",
+//			Columns: []trinotest.Column{{"id", "BIGINT"}, {"status", "VARCHAR"}},
+//			Rows:    [][]interface{}{{1, "open"}, {2, "closed"}},
+//		})
+//		...
+//	}
+//
+// Starting a server takes a while, so tests sharing one should start it in
+// TestMain, with Start, and close it once they finished.
+//
+// This package doesn't import the driver, so it can be used by its own tests.
+// Import it to open connections to the server.
+package trinotest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmetb/dlog"
+	dt "github.com/ory/dockertest/v3"
+	docker "github.com/ory/dockertest/v3/docker"
+)
+
+const (
+	// DefaultRepository is the Docker image repository of the Trino server.
+	DefaultRepository = "trinodb/trino"
+	// DefaultTag is the tag of the Docker image of the Trino server.
+	DefaultTag = "latest"
+	// DefaultUser is the user of the DSN of the server.
+	DefaultUser = "test"
+	// DefaultMaxWait is how long to wait for the server to be ready.
+	DefaultMaxWait = time.Minute
+
+	httpPort = "8080/tcp"
+)
+
+// Options are the options of the Trino server.
+type Options struct {
+	// Repository and Tag are the Docker image of the server,
+	// which default to DefaultRepository and DefaultTag.
+	Repository string
+	Tag        string
+	// Name is the name of the container. If a container with this name already
+	// exists, it's reused, and started if it isn't running. Unnamed containers
+	// get a random name, and are never reused.
+	Name string
+	// ConfigDir is a directory mounted as /etc/trino, replacing the configuration
+	// of the image, like its catalogs. The image provides the memory and tpch
+	// catalogs, among others, by default.
+	ConfigDir string
+	// ExposedPorts are ports of the container to expose, like 8443/tcp, in
+	// addition to the 8080/tcp HTTP port. Get their ports on the host with HostPort.
+	ExposedPorts []string
+	// User is the user of the DSN of the server, which defaults to DefaultUser.
+	User string
+	// MaxWait is how long to wait for the server to be ready, which defaults to DefaultMaxWait.
+	MaxWait time.Duration
+	// KeepContainer keeps the container when closing the server, to reuse it in the next
+	// test run, with the same Name.
+	KeepContainer bool
+	// BeforeCreate is called before creating a new container, but not when reusing
+	// one, like to generate files in ConfigDir.
+	BeforeCreate func() error
+}
+
+// Server is a Trino server running in a Docker container.
+type Server struct {
+	// DSN is the data source name of the server, to open connections with
+	// sql.Open("trino", server.DSN).
+	DSN string
+
+	pool     *dt.Pool
+	resource *dt.Resource
+	keep     bool
+}
+
+// StartContainer starts a Trino server for a test, and removes it when the test
+// and its subtests complete. It skips the test in short mode, and fails it if
+// the server can't be started. A nil opts uses the default options.
+func StartContainer(t testing.TB, opts *Options) *Server {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping test in short mode.")
+	}
+	s, err := Start(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return s
+}
+
+// Start starts a Trino server and waits until it's ready. Close it once it's no
+// longer used. A nil opts uses the default options.
+func Start(opts *Options) (*Server, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	pool, err := dt.NewPool("")
+	if err != nil {
+		return nil, fmt.Errorf("trinotest: could not connect to docker: %w", err)
+	}
+	pool.MaxWait = opts.MaxWait
+	if pool.MaxWait == 0 {
+		pool.MaxWait = DefaultMaxWait
+	}
+	s := &Server{pool: pool, keep: opts.KeepContainer}
+
+	var ok bool
+	if opts.Name != "" {
+		s.resource, ok = pool.ContainerByName(opts.Name)
+	}
+	if !ok {
+		if opts.BeforeCreate != nil {
+			if err := opts.BeforeCreate(); err != nil {
+				return nil, fmt.Errorf("trinotest: %w", err)
+			}
+		}
+		runOptions := &dt.RunOptions{
+			Name:         opts.Name,
+			Repository:   opts.Repository,
+			Tag:          opts.Tag,
+			ExposedPorts: append([]string{httpPort}, opts.ExposedPorts...),
+		}
+		if runOptions.Repository == "" {
+			runOptions.Repository = DefaultRepository
+		}
+		if runOptions.Tag == "" {
+			runOptions.Tag = DefaultTag
+		}
+		if opts.ConfigDir != "" {
+			runOptions.Mounts = []string{opts.ConfigDir + ":/etc/trino"}
+		}
+		s.resource, err = pool.RunWithOptions(runOptions)
+		if err != nil {
+			return nil, fmt.Errorf("trinotest: could not start container: %w", err)
+		}
+	} else if !s.resource.Container.State.Running {
+		if err := pool.Client.StartContainer(s.resource.Container.ID, nil); err != nil {
+			return nil, fmt.Errorf("trinotest: could not start container %s: %w", s.resource.Container.ID, err)
+		}
+	}
+
+	if err := s.waitUntilHealthy(); err != nil {
+		if !s.keep {
+			pool.Purge(s.resource)
+		}
+		return nil, err
+	}
+	user := opts.User
+	if user == "" {
+		user = DefaultUser
+	}
+	s.DSN = "http://" + user + "@localhost:" + s.HostPort(httpPort)
+	return s, nil
+}
+
+func (s *Server) waitUntilHealthy() error {
+	// exited stops waiting for a container that will never be ready
+	var exited error
+	err := s.pool.Retry(func() error {
+		c, err := s.pool.Client.InspectContainer(s.resource.Container.ID)
+		if err != nil {
+			exited = fmt.Errorf("trinotest: failed to inspect container %s: %w", s.resource.Container.ID, err)
+			return nil
+		}
+		if !c.State.Running {
+			exited = fmt.Errorf("trinotest: container %s is not running: %s\nContainer logs:\n%s", s.resource.Container.ID, c.State.String(), s.Logs())
+			return nil
+		}
+		if c.State.Health.Status != "healthy" {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	if exited != nil {
+		return exited
+	}
+	if err != nil {
+		return fmt.Errorf("trinotest: timed out waiting for container to get ready: %w\nContainer logs:\n%s", err, s.Logs())
+	}
+	return nil
+}
+
+// HostPort returns the port of the host a port of the container, like 8443/tcp, is bound to.
+func (s *Server) HostPort(port string) string {
+	return s.resource.GetPort(port)
+}
+
+// Logs returns the logs of the container, to report why a test failed.
+func (s *Server) Logs() []byte {
+	var buf bytes.Buffer
+	s.pool.Client.Logs(docker.LogsOptions{
+		Container:    s.resource.Container.ID,
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Stdout:       true,
+		Stderr:       true,
+		RawTerminal:  true,
+	})
+	logs, _ := io.ReadAll(dlog.NewReader(&buf))
+	return logs
+}
+
+// Close removes the container, unless KeepContainer was set.
+func (s *Server) Close() error {
+	if s.keep {
+		return nil
+	}
+	if err := s.pool.Purge(s.resource); err != nil {
+		return fmt.Errorf("trinotest: could not remove container: %w", err)
+	}
+	return nil
+}
+
+// Table is a fixture table created by LoadTables.
+type Table struct {
+	// Name is the qualified name of the table, like memory.default.orders.
+	// Its catalog must support creating tables, like the memory catalog.
+	Name string
+	// Columns are the columns of the table.
+	Columns []Column
+	// Rows are the rows of the table, with a value for each column.
+	// Values are passed to the driver as query arguments, and cast to the type
+	// of their column.
+	Rows [][]interface{}
+}
+
+// Column is a column of a fixture Table.
+type Column struct {
+	// Name is the name of the column.
+	Name string
+	// Type is the Trino type of the column, like BIGINT or DECIMAL(10,2).
+	Type string
+}
+
+// insertBatchSize is the maximum number of rows inserted by a single statement.
+const insertBatchSize = 100
+
+// LoadTables creates fixture tables, replacing existing tables with the same
+// names, and inserts their rows. The schemas of the tables are created if they
+// don't exist. The driver must be registered to use db.
+func LoadTables(ctx context.Context, db *sql.DB, tables ...Table) error {
+	for _, table := range tables {
+		if err := loadTable(ctx, db, table); err != nil {
+			return fmt.Errorf("trinotest: failed to load table %s: %w", table.Name, err)
+		}
+	}
+	return nil
+}
+
+func loadTable(ctx context.Context, db *sql.DB, table Table) error {
+	if len(table.Columns) == 0 {
+		return errors.New("no columns")
+	}
+	if parts := strings.Split(table.Name, "."); len(parts) == 3 {
+		if _, err := db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+parts[0]+"."+parts[1]); err != nil {
+			return err
+		}
+	}
+	if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+table.Name); err != nil {
+		return err
+	}
+	columns := make([]string, len(table.Columns))
+	values := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		columns[i] = quoteIdentifier(column.Name) + " " + column.Type
+		values[i] = "CAST(? AS " + column.Type + ")"
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE "+table.Name+" ("+strings.Join(columns, ", ")+")"); err != nil {
+		return err
+	}
+
+	row := "(" + strings.Join(values, ", ") + ")"
+	for start := 0; start < len(table.Rows); start += insertBatchSize {
+		batch := table.Rows[start:min(start+insertBatchSize, len(table.Rows))]
+		rows := make([]string, len(batch))
+		var args []interface{}
+		for i, values := range batch {
+			if len(values) != len(table.Columns) {
+				return fmt.Errorf("row %d has %d values, expected %d", start+i, len(values), len(table.Columns))
+			}
+			rows[i] = row
+			args = append(args, values...)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO "+table.Name+" VALUES "+strings.Join(rows, ", "), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
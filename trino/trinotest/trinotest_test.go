@@ -0,0 +1,70 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trinotest
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/trinodb/trino-go-client/trino"
+)
+
+func TestLoadTables(t *testing.T) {
+	var statements []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		statements = append(statements, string(b))
+		io.WriteString(w, `{"id":"fake-query"}`)
+	}))
+	t.Cleanup(ts.Close)
+
+	db, err := sql.Open("trino", ts.URL+"?explicitPrepare=false")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+
+	rows := make([][]interface{}, insertBatchSize+1)
+	for i := range rows {
+		rows[i] = []interface{}{i, "open"}
+	}
+	err = LoadTables(context.Background(), db, Table{
+		Name:    "memory.test.orders",
+		Columns: []Column{{"id", "BIGINT"}, {"status", "VARCHAR(10)"}},
+		Rows:    rows,
+	})
+	require.NoError(t, err)
+	require.Len(t, statements, 5)
+	assert.Equal(t, "CREATE SCHEMA IF NOT EXISTS memory.test", statements[0])
+	assert.Equal(t, "DROP TABLE IF EXISTS memory.test.orders", statements[1])
+	assert.Equal(t, `CREATE TABLE memory.test.orders ("id" BIGINT, "status" VARCHAR(10))`, statements[2])
+	assert.Contains(t, statements[3], "USING 0, 'open', 1, 'open'")
+	assert.Equal(t, `EXECUTE IMMEDIATE 'INSERT INTO memory.test.orders VALUES (CAST(? AS BIGINT), CAST(? AS VARCHAR(10)))' USING 100, 'open'`, statements[4])
+
+	err = LoadTables(context.Background(), db, Table{
+		Name:    "orders",
+		Columns: []Column{{"id", "BIGINT"}},
+		Rows:    [][]interface{}{{1, 2}},
+	})
+	assert.EqualError(t, err, "trinotest: failed to load table orders: row 0 has 2 values, expected 1")
+}
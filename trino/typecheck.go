@@ -0,0 +1,233 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// TypeCase is a value of a Trino type checked by ValidateTypes.
+type TypeCase struct {
+	// Type is the type of the value, like decimal(38,0).
+	Type string
+	// Literal is an SQL expression of the value, like DECIMAL '1.5'.
+	Literal string
+	// Argument is an SQL expression converting the value returned by the
+	// driver, passed as the argument ?, back to Type, like json_parse(?).
+	// It defaults to CAST(? AS Type).
+	Argument string
+	// Expected is the value the driver must return, compared instead of
+	// passing it back to the server, for values the driver can't pass as
+	// arguments, like arrays, maps and NaN.
+	Expected interface{}
+}
+
+// DefaultTypeCases are the values checked by ValidateTypes by default, with
+// the extremes of the types supported by the driver, and of their parameters.
+var DefaultTypeCases = []TypeCase{
+	{Type: "boolean", Literal: "true"},
+	{Type: "boolean", Literal: "false"},
+	{Type: "tinyint", Literal: "TINYINT '-128'"},
+	{Type: "tinyint", Literal: "TINYINT '127'"},
+	{Type: "smallint", Literal: "SMALLINT '-32768'"},
+	{Type: "smallint", Literal: "SMALLINT '32767'"},
+	{Type: "integer", Literal: "INTEGER '-2147483648'"},
+	{Type: "integer", Literal: "INTEGER '2147483647'"},
+	{Type: "bigint", Literal: "BIGINT '-9223372036854775808'"},
+	{Type: "bigint", Literal: "BIGINT '9223372036854775807'"},
+	{Type: "real", Literal: "REAL '3.4028235E38'"},
+	{Type: "real", Literal: "REAL '1.4E-45'"},
+	{Type: "real", Literal: "REAL '-0.1'"},
+	{Type: "double", Literal: "DOUBLE '1.7976931348623157E308'"},
+	{Type: "double", Literal: "DOUBLE '4.9E-324'"},
+	{Type: "double", Literal: "DOUBLE '-0.1'"},
+	{Type: "double", Literal: "nan()", Expected: math.NaN()},
+	{Type: "double", Literal: "infinity()", Expected: math.Inf(1)},
+	{Type: "double", Literal: "-infinity()", Expected: math.Inf(-1)},
+	{Type: "decimal(38,0)", Literal: "DECIMAL '99999999999999999999999999999999999999'"},
+	{Type: "decimal(38,0)", Literal: "DECIMAL '-99999999999999999999999999999999999999'"},
+	{Type: "decimal(38,38)", Literal: "DECIMAL '0.00000000000000000000000000000000000001'"},
+	{Type: "decimal(10,2)", Literal: "DECIMAL '-12345678.90'"},
+	{Type: "varchar", Literal: "''"},
+	{Type: "varchar", Literal: "'it''s Grüße, 世界 😀'"},
+	{Type: "varchar(3)", Literal: "CAST('abc' AS VARCHAR(3))"},
+	{Type: "char(5)", Literal: "CAST('ab' AS CHAR(5))"},
+	{Type: "varbinary", Literal: "X''"},
+	{Type: "varbinary", Literal: "X'00FF7F80'"},
+	{Type: "json", Literal: `JSON '{"a":[1,2.5,null],"b":"x"}'`, Argument: "json_parse(?)"},
+	{Type: "date", Literal: "DATE '1970-01-01'"},
+	{Type: "date", Literal: "DATE '0001-01-01'"},
+	{Type: "date", Literal: "DATE '1582-10-04'"},
+	{Type: "date", Literal: "DATE '9999-12-31'"},
+	{Type: "time(0)", Literal: "TIME '00:00:00'"},
+	{Type: "time(3)", Literal: "TIME '23:59:59.999'"},
+	{Type: "time(9)", Literal: "TIME '23:59:59.999999999'"},
+	{Type: "time(3) with time zone", Literal: "TIME '01:02:03.456 +05:30'"},
+	{Type: "timestamp(0)", Literal: "TIMESTAMP '2001-08-22 03:04:05'"},
+	{Type: "timestamp(3)", Literal: "TIMESTAMP '2001-08-22 03:04:05.321'"},
+	{Type: "timestamp(6)", Literal: "TIMESTAMP '2001-08-22 03:04:05.321987'"},
+	{Type: "timestamp(9)", Literal: "TIMESTAMP '2001-08-22 03:04:05.321987654'"},
+	{Type: "timestamp(0)", Literal: "TIMESTAMP '0001-01-01 00:00:00'"},
+	{Type: "timestamp(9)", Literal: "TIMESTAMP '9999-12-31 23:59:59.999999999'"},
+	{Type: "timestamp(3) with time zone", Literal: "TIMESTAMP '2001-08-22 03:04:05.321 America/Los_Angeles'"},
+	{Type: "timestamp(9) with time zone", Literal: "TIMESTAMP '2001-08-22 03:04:05.321987654 +05:30'"},
+	{Type: "interval year to month", Literal: "INTERVAL '14' MONTH", Expected: "1-2"},
+	{Type: "interval day to second", Literal: "INTERVAL '1 02:03:04.567' DAY TO SECOND", Expected: "1 02:03:04.567"},
+	{Type: "uuid", Literal: "UUID '12151fd2-7586-11e9-8f9e-2a86e4085a59'"},
+	{Type: "ipaddress", Literal: "IPADDRESS '2001:db8::1'"},
+	{Type: "array(integer)", Literal: "ARRAY[1, NULL, 3]", Expected: []interface{}{json.Number("1"), nil, json.Number("3")}},
+	{Type: "map(varchar,integer)", Literal: "MAP(ARRAY['a'], ARRAY[1])", Expected: map[string]interface{}{"a": json.Number("1")}},
+	{Type: "row(a integer,b varchar)", Literal: "CAST(ROW(1, 'x') AS ROW(a INTEGER, b VARCHAR))", Expected: []interface{}{json.Number("1"), "x"}},
+}
+
+// TypeReport is the result of ValidateTypes.
+type TypeReport struct {
+	// ServerVersion is the version of the server.
+	ServerVersion string
+	// Results are the results of the type cases, in the same order.
+	Results []TypeResult
+}
+
+// TypeResult is the result of a type case checked by ValidateTypes.
+type TypeResult struct {
+	TypeCase
+	// Value is the value returned by the driver.
+	Value interface{}
+	// Err is the reason the value isn't converted losslessly, like an error of
+	// the server for types it doesn't support, an error of the driver decoding
+	// or encoding the value, or a value changed by the round trip. It's nil
+	// if the value is converted losslessly.
+	Err error
+}
+
+// Failures returns the results of the type cases which aren't converted losslessly.
+func (r *TypeReport) Failures() []TypeResult {
+	var failures []TypeResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// String returns the report as a table, with a line per type case.
+func (r *TypeReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Trino %s: %d of %d values converted losslessly\n\n", r.ServerVersion, len(r.Results)-len(r.Failures()), len(r.Results))
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tLITERAL\tVALUE\tRESULT")
+	for _, result := range r.Results {
+		status := "ok"
+		if result.Err != nil {
+			status = strings.ReplaceAll(result.Err.Error(), "\n", " ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Type, result.Literal, formatTypeValue(result.Value), status)
+	}
+	w.Flush()
+	return b.String()
+}
+
+func formatTypeValue(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "NULL"
+	case string, []byte:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// ValidateTypes checks that the driver converts values of every Trino type
+// losslessly, with the server db is connected to, and returns a report, for
+// example to validate a new version of Trino before upgrading to it. It checks
+// cases, or DefaultTypeCases if there are none.
+//
+// Every value is queried, and the value returned by the driver is passed back
+// to the server as an argument, which compares it to the original value, or
+// it's compared to the expected value of the case. That's two queries for
+// every case, so it takes a few seconds.
+//
+// It returns an error only if the version of the server can't be queried, or
+// ctx is done. Values which aren't converted losslessly are reported by the
+// Failures of the report.
+func ValidateTypes(ctx context.Context, db *sql.DB, cases ...TypeCase) (*TypeReport, error) {
+	if len(cases) == 0 {
+		cases = DefaultTypeCases
+	}
+	report := &TypeReport{}
+	if err := db.QueryRowContext(ctx, "SELECT version()").Scan(&report.ServerVersion); err != nil {
+		return nil, err
+	}
+	for _, c := range cases {
+		result := TypeResult{TypeCase: c}
+		result.Value, result.Err = checkTypeCase(ctx, db, c)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// checkTypeCase returns the value of a type case returned by the driver, and
+// an error if it's not converted losslessly.
+func checkTypeCase(ctx context.Context, db *sql.DB, c TypeCase) (interface{}, error) {
+	var value interface{}
+	if err := db.QueryRowContext(ctx, "SELECT "+c.Literal).Scan(&value); err != nil {
+		return nil, err
+	}
+	if c.Expected != nil {
+		if !equalTypeValues(value, c.Expected) {
+			return value, fmt.Errorf("trino: expected %s, got %s", formatTypeValue(c.Expected), formatTypeValue(value))
+		}
+		return value, nil
+	}
+	argument := c.Argument
+	if argument == "" {
+		argument = "CAST(? AS " + c.Type + ")"
+	}
+	arg := value
+	if f, ok := value.(float64); ok {
+		// floats are passed as numbers, without losing precision
+		arg = Numeric(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	var same bool
+	if err := db.QueryRowContext(ctx, "SELECT "+c.Literal+" IS NOT DISTINCT FROM "+argument, arg).Scan(&same); err != nil {
+		return value, err
+	}
+	if !same {
+		return value, fmt.Errorf("trino: value changed to %s by a round trip", formatTypeValue(value))
+	}
+	return value, nil
+}
+
+// equalTypeValues reports whether a value returned by the driver is the
+// expected one, with NaN equal to itself.
+func equalTypeValues(value, expected interface{}) bool {
+	if f, ok := value.(float64); ok && math.IsNaN(f) {
+		e, ok := expected.(float64)
+		return ok && math.IsNaN(e)
+	}
+	return reflect.DeepEqual(value, expected)
+}
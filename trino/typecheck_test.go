@@ -0,0 +1,86 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+func TestValidateTypes(t *testing.T) {
+	result := func(typ string, value interface{}) trinomock.Response {
+		return trinomock.Response{
+			Columns: []trinomock.Column{{Name: "_col0", Type: typ}},
+			Rows:    [][]interface{}{{value}},
+		}
+	}
+	// the results of the queries sent by the driver, and no result for the
+	// interval, failing its query
+	server := newMockServer(t)
+	server.Handle("SELECT version()", result("varchar", "449"))
+	server.Handle("SELECT INTEGER '42'", result("integer", 42))
+	server.Handle("SELECT INTEGER '42' IS NOT DISTINCT FROM CAST(? AS integer)", result("boolean", true))
+	server.Handle("SELECT REAL '3.4028235E38'", result("real", json.Number("3.4028235E38")))
+	server.Handle("SELECT REAL '3.4028235E38' IS NOT DISTINCT FROM CAST(? AS real)", result("boolean", true))
+	server.Handle("SELECT CAST('ab' AS CHAR(5))", result("char", "ab"))
+	server.Handle("SELECT CAST('ab' AS CHAR(5)) IS NOT DISTINCT FROM CAST(? AS char(5))", result("boolean", false))
+	server.Handle("SELECT nan()", result("double", "NaN"))
+	server.Handle("SELECT ARRAY[1]", result("array(integer)", []int{2}))
+
+	db := openTestDB(t, server.DSN()+"?explicitPrepare=false")
+
+	report, err := ValidateTypes(context.Background(), db,
+		TypeCase{Type: "integer", Literal: "INTEGER '42'"},
+		TypeCase{Type: "real", Literal: "REAL '3.4028235E38'"},
+		TypeCase{Type: "char(5)", Literal: "CAST('ab' AS CHAR(5))"},
+		TypeCase{Type: "double", Literal: "nan()", Expected: math.NaN()},
+		TypeCase{Type: "array(integer)", Literal: "ARRAY[1]", Expected: []interface{}{json.Number("1")}},
+		TypeCase{Type: "interval year to month", Literal: "INTERVAL '1' MONTH", Expected: "0-1"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "449", report.ServerVersion)
+	require.Len(t, report.Results, 6)
+	assert.Equal(t, int64(42), report.Results[0].Value)
+	assert.NoError(t, report.Results[0].Err)
+	assert.NoError(t, report.Results[1].Err)
+	assert.EqualError(t, report.Results[2].Err, `trino: value changed to "ab" by a round trip`)
+	assert.NoError(t, report.Results[3].Err)
+	assert.EqualError(t, report.Results[4].Err, "trino: expected [1], got [2]")
+	var trinoErr *ErrTrino
+	assert.ErrorAs(t, report.Results[5].Err, &trinoErr)
+	assert.Subset(t, submittedBodies(server), []string{
+		"EXECUTE IMMEDIATE 'SELECT INTEGER ''42'' IS NOT DISTINCT FROM CAST(? AS integer)' USING 42",
+		"EXECUTE IMMEDIATE 'SELECT REAL ''3.4028235E38'' IS NOT DISTINCT FROM CAST(? AS real)' USING 3.4028235e+38",
+		"EXECUTE IMMEDIATE 'SELECT CAST(''ab'' AS CHAR(5)) IS NOT DISTINCT FROM CAST(? AS char(5))' USING 'ab'",
+	})
+
+	failures := report.Failures()
+	require.Len(t, failures, 3)
+	assert.Equal(t, "char(5)", failures[0].Type)
+	text := report.String()
+	assert.Contains(t, text, "Trino 449: 3 of 6 values converted losslessly")
+	assert.Regexp(t, `integer\s+INTEGER '42'\s+42\s+ok`, text)
+
+	for _, c := range DefaultTypeCases {
+		assert.NotEmpty(t, c.Type)
+		assert.NotEmpty(t, c.Literal)
+	}
+}
@@ -0,0 +1,1201 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains code that was borrowed from prestgo, mainly some
+// data type definitions.
+//
+// See https://github.com/avct/prestgo for copyright information.
+//
+// The MIT License (MIT)
+//
+// Copyright (c) 2015 Avocet Systems Ltd.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trino
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode/utf8"
+
+	"github.com/trinodb/trino-go-client/trino/internal/protocol"
+	"github.com/trinodb/trino-go-client/trino/internal/types"
+)
+
+// The types of the columns in responses are defined in the protocol package.
+type (
+	queryColumn        = protocol.QueryColumn
+	queryData          = protocol.QueryData
+	namedTypeSignature = protocol.NamedTypeSignature
+	rowFieldName       = protocol.RowFieldName
+	typeSignature      = protocol.TypeSignature
+	typeKind           = protocol.TypeKind
+	typeArgument       = protocol.TypeArgument
+)
+
+// Kinds of type arguments.
+const (
+	KIND_TYPE       = protocol.KindType
+	KIND_NAMED_TYPE = protocol.KindNamedType
+	KIND_LONG       = protocol.KindLong
+	KIND_VARIABLE   = protocol.KindVariable
+)
+
+// TypeSignature is the parsed type of a column, as returned by the server.
+type TypeSignature struct {
+	// RawType is the name of the type without its parameters, like decimal or row.
+	RawType string
+	// Arguments are the parameters of the type, like the precision and scale of a decimal,
+	// the element type of an array, or the fields of a row.
+	Arguments []TypeArgument
+}
+
+// TypeArgument is a parameter of a type.
+type TypeArgument struct {
+	// Kind is TYPE, NAMED_TYPE or LONG.
+	Kind string
+	// Type is set for TYPE and NAMED_TYPE arguments.
+	Type *TypeSignature
+	// FieldName is the name of a row field for NAMED_TYPE arguments,
+	// or an empty string for anonymous row fields.
+	FieldName string
+	// Long is set for LONG arguments, like the length of a varchar.
+	Long int64
+}
+
+func newTypeSignature(signature typeSignature) TypeSignature {
+	result := TypeSignature{RawType: signature.RawType}
+	for _, argument := range signature.Arguments {
+		arg := TypeArgument{Kind: string(argument.Kind)}
+		switch argument.Kind {
+		case KIND_TYPE:
+			t := newTypeSignature(argument.TypeSignature)
+			arg.Type = &t
+		case KIND_NAMED_TYPE:
+			t := newTypeSignature(argument.NamedTypeSignature.TypeSignature)
+			arg.Type = &t
+			arg.FieldName = argument.NamedTypeSignature.FieldName.Name
+		case KIND_LONG:
+			arg.Long = argument.Long
+		}
+		result.Arguments = append(result.Arguments, arg)
+	}
+	return result
+}
+
+type typeConverter struct {
+	typeName   string
+	signature  TypeSignature
+	parsedType []string
+	scanType   reflect.Type
+	precision  optionalInt64
+	scale      optionalInt64
+	size       optionalInt64
+	// subNanosecondTime controls how time and timestamp values with a precision
+	// above nanoseconds are converted, it defaults to truncating them.
+	subNanosecondTime string
+	// strictTime rejects time and timestamp values with leap seconds or 24:00:00.
+	strictTime bool
+	// realAsFloat32 converts real values to float32 instead of float64.
+	realAsFloat32 bool
+	// strictStringLength validates the length of char(N) and varchar(N) values.
+	strictStringLength bool
+	// convert converts the values instead of the driver, when set by a ColumnConverter.
+	convert func(v interface{}) (driver.Value, error)
+}
+
+type optionalInt64 struct {
+	value    int64
+	hasValue bool
+}
+
+func newOptionalInt64(value int64) optionalInt64 {
+	return optionalInt64{value: value, hasValue: true}
+}
+
+func newTypeConverter(typeName string, signature typeSignature) (*typeConverter, error) {
+	result := &typeConverter{
+		typeName:   typeName,
+		signature:  newTypeSignature(signature),
+		parsedType: getNestedTypes([]string{}, signature),
+	}
+	var err error
+	result.scanType, err = getScanType(result.parsedType)
+	if err != nil {
+		return nil, err
+	}
+	switch signature.RawType {
+	case "char", "varchar":
+		if len(signature.Arguments) > 0 {
+			if signature.Arguments[0].Kind != KIND_LONG {
+				return nil, ErrInvalidResponseType
+			}
+			result.size = newOptionalInt64(signature.Arguments[0].Long)
+		}
+	case "decimal":
+		if len(signature.Arguments) > 0 {
+			if signature.Arguments[0].Kind != KIND_LONG {
+				return nil, ErrInvalidResponseType
+			}
+			result.precision = newOptionalInt64(signature.Arguments[0].Long)
+		}
+		if len(signature.Arguments) > 1 {
+			if signature.Arguments[1].Kind != KIND_LONG {
+				return nil, ErrInvalidResponseType
+			}
+			result.scale = newOptionalInt64(signature.Arguments[1].Long)
+		}
+	case "time", "time with time zone", "timestamp", "timestamp with time zone":
+		if len(signature.Arguments) > 0 {
+			if signature.Arguments[0].Kind != KIND_LONG {
+				return nil, ErrInvalidResponseType
+			}
+			result.precision = newOptionalInt64(signature.Arguments[0].Long)
+		}
+	}
+
+	return result, nil
+}
+
+// checkStringLength returns an error if a char(N) value doesn't have exactly N characters,
+// or a varchar(N) value has more than N characters.
+func (c *typeConverter) checkStringLength(s string) error {
+	if !c.size.hasValue {
+		return nil
+	}
+	n := int64(utf8.RuneCountInString(s))
+	switch c.parsedType[0] {
+	case "char":
+		if n != c.size.value {
+			return fmt.Errorf("trino: %s value has %d characters instead of %d", c.typeName, n, c.size.value)
+		}
+	case "varchar":
+		if n > c.size.value {
+			return fmt.Errorf("trino: %s value has %d characters, more than %d", c.typeName, n, c.size.value)
+		}
+	}
+	return nil
+}
+
+// setSubNanosecondTime sets how to convert time and timestamp values with a precision above nanoseconds.
+func (c *typeConverter) setSubNanosecondTime(mode string) {
+	c.subNanosecondTime = mode
+	if mode == subNanosecondTimePreserve && c.hasSubNanosecondPrecision() {
+		c.scanType = reflect.TypeOf(NullPreciseTime{})
+	}
+}
+
+// hasSubNanosecondPrecision returns true for time and timestamp types with more than 9 fractional digits.
+func (c *typeConverter) hasSubNanosecondPrecision() bool {
+	switch c.parsedType[0] {
+	case "time", "time with time zone", "timestamp", "timestamp with time zone":
+		return c.precision.hasValue && c.precision.value > 9
+	}
+	return false
+}
+
+func getNestedTypes(types []string, signature typeSignature) []string {
+	types = append(types, signature.RawType)
+	if len(signature.Arguments) == 1 {
+		switch signature.Arguments[0].Kind {
+		case KIND_TYPE:
+			types = getNestedTypes(types, signature.Arguments[0].TypeSignature)
+		case KIND_NAMED_TYPE:
+			types = getNestedTypes(types, signature.Arguments[0].NamedTypeSignature.TypeSignature)
+		}
+	}
+	return types
+}
+
+func getScanType(typeNames []string) (reflect.Type, error) {
+	var v interface{}
+	switch typeNames[0] {
+	case "boolean":
+		v = sql.NullBool{}
+	case "json", "char", "varchar", "varbinary", "interval year to month", "interval day to second", "decimal", "ipaddress", "uuid", "unknown":
+		v = sql.NullString{}
+	case "tinyint", "smallint":
+		v = sql.NullInt32{}
+	case "integer":
+		v = sql.NullInt32{}
+	case "bigint":
+		v = sql.NullInt64{}
+	case "real", "double":
+		v = sql.NullFloat64{}
+	case "date", "time", "time with time zone", "timestamp", "timestamp with time zone":
+		v = sql.NullTime{}
+	case "map":
+		v = NullMap{}
+	case "array":
+		dimensions := 1
+		for dimensions < len(typeNames) && typeNames[dimensions] == "array" {
+			dimensions++
+		}
+		if dimensions == len(typeNames) {
+			return nil, ErrInvalidResponseType
+		}
+		v = arrayScanTypes(typeNames[dimensions])[min(dimensions, 4)-1]
+	}
+	if v == nil {
+		return reflect.TypeOf(new(interface{})).Elem(), nil
+	}
+	return reflect.TypeOf(v), nil
+}
+
+// arrayScanTypes returns the scan types of arrays of one, two, three, and four
+// or more dimensions, with elements of the given type, or nils if the arrays are
+// scanned into an empty interface, like arrays of rows.
+func arrayScanTypes(elementType string) [4]interface{} {
+	switch elementType {
+	case "boolean":
+		return [4]interface{}{NullSliceBool{}, NullSlice2Bool{}, NullSlice3Bool{}, NullArray[sql.NullBool]{}}
+	case "json", "char", "varchar", "varbinary", "interval year to month", "interval day to second", "decimal", "ipaddress", "uuid", "unknown":
+		return [4]interface{}{NullSliceString{}, NullSlice2String{}, NullSlice3String{}, NullArray[sql.NullString]{}}
+	case "tinyint", "smallint", "integer", "bigint":
+		return [4]interface{}{NullSliceInt64{}, NullSlice2Int64{}, NullSlice3Int64{}, NullArray[sql.NullInt64]{}}
+	case "real", "double":
+		return [4]interface{}{NullSliceFloat64{}, NullSlice2Float64{}, NullSlice3Float64{}, NullArray[sql.NullFloat64]{}}
+	case "date", "time", "time with time zone", "timestamp", "timestamp with time zone":
+		return [4]interface{}{NullSliceTime{}, NullSlice2Time{}, NullSlice3Time{}, NullArray[NullTime]{}}
+	case "map":
+		return [4]interface{}{NullSliceMap{}, NullSlice2Map{}, NullSlice3Map{}, NullArray[NullMap]{}}
+	}
+	return [4]interface{}{}
+}
+
+// ConvertValue implements the driver.ValueConverter interface.
+func (c *typeConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if c.convert != nil {
+		return c.convert(v)
+	}
+	switch c.parsedType[0] {
+	case "boolean":
+		vv, err := types.ScanNullBool(v)
+		if !vv.Valid {
+			return nil, err
+		}
+		return vv.Bool, err
+	case "json", "char", "varchar", "varbinary", "interval year to month", "interval day to second", "decimal", "ipaddress", "uuid", "Geometry", "SphericalGeography", "unknown":
+		vv, err := types.ScanNullString(v)
+		if !vv.Valid {
+			return nil, err
+		}
+		if c.strictStringLength {
+			if err := c.checkStringLength(vv.String); err != nil {
+				return nil, err
+			}
+		}
+		// returning the decoded value as is, instead of vv.String, avoids allocating
+		// a new interface value per row, so scanning into sql.RawBytes, which
+		// database/sql copies into the buffer of the destination, doesn't allocate
+		return v, err
+	case "tinyint", "smallint", "integer", "bigint":
+		vv, err := types.ScanNullInt64(v)
+		if !vv.Valid {
+			return nil, err
+		}
+		return vv.Int64, err
+	case "real", "double":
+		if c.realAsFloat32 && c.parsedType[0] == "real" {
+			return types.ScanFloat32(v)
+		}
+		vv, err := types.ScanNullFloat64(v)
+		if !vv.Valid {
+			return nil, err
+		}
+		return vv.Float64, err
+	case "date", "time", "time with time zone", "timestamp", "timestamp with time zone":
+		if c.strictTime {
+			if err := types.CheckStrictTime(v); err != nil {
+				return nil, err
+			}
+		}
+		if c.hasSubNanosecondPrecision() && c.subNanosecondTime != "" && c.subNanosecondTime != subNanosecondTimeTruncate {
+			vv, err := scanNullPreciseTime(v)
+			if err != nil {
+				return c.timeFallback(v, err)
+			}
+			if !vv.Valid {
+				return nil, nil
+			}
+			if c.subNanosecondTime == subNanosecondTimePreserve {
+				return PreciseTime{Time: vv.Time, Picoseconds: vv.Picoseconds}, nil
+			}
+			if vv.Picoseconds != 0 {
+				return nil, fmt.Errorf("trino: %s value %v cannot be represented with nanosecond precision", c.typeName, v)
+			}
+			return vv.Time, nil
+		}
+		vv, err := scanNullTime(v)
+		if err != nil {
+			return c.timeFallback(v, err)
+		}
+		if !vv.Valid {
+			return nil, nil
+		}
+		return vv.Time, nil
+	case "map":
+		if err := types.ValidateMap(v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "array":
+		if err := types.ValidateSlice(v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "row":
+		if err := types.ValidateSlice(v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("type not supported: %q", c.typeName)
+	}
+}
+
+// timeFallback returns a date, time or timestamp value the driver failed to parse,
+// like one with a time zone unknown to Go, as a string, so it doesn't fail reading
+// all the results, or the error when using strict_time=true.
+func (c *typeConverter) timeFallback(v interface{}, err error) (driver.Value, error) {
+	if s, ok := v.(string); ok && !c.strictTime {
+		return s, nil
+	}
+	return nil, err
+}
+
+// NullSliceBool represents a slice of bool that may be null.
+//
+// Like the other NullSlice types, its AsSlice method converts it to plain Go
+// slices, with nil for null elements, or returns nil if the slice is null. The
+// bool, string, int64 and time slices are also passed as query arguments, with
+// their Value method.
+type NullSliceBool struct {
+	SliceBool []sql.NullBool
+	Valid     bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSliceBool) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[]bool", types.ScanNullBool)
+	if err != nil {
+		return err
+	}
+	s.SliceBool, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as []*bool.
+func (s NullSliceBool) AsSlice() []*bool {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.SliceBool, func(v sql.NullBool) *bool {
+		if !v.Valid {
+			return nil
+		}
+		return &v.Bool
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSliceBool) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.SliceBool, func(v sql.NullBool) interface{} {
+		if !v.Valid {
+			return nil
+		}
+		return v.Bool
+	}), nil
+}
+
+// NullSlice2Bool represents a two-dimensional slice of bool that may be null.
+type NullSlice2Bool struct {
+	Slice2Bool [][]sql.NullBool
+	Valid      bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice2Bool) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][]bool", func(v interface{}) ([]sql.NullBool, error) {
+		var ss NullSliceBool
+		err := ss.Scan(v)
+		return ss.SliceBool, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice2Bool, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][]*bool.
+func (s NullSlice2Bool) AsSlice() [][]*bool {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice2Bool, func(v []sql.NullBool) []*bool {
+		return NullSliceBool{SliceBool: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice2Bool) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice2Bool, func(v []sql.NullBool) interface{} {
+		slice, _ := NullSliceBool{SliceBool: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullSlice3Bool implements a three-dimensional slice of bool that may be null.
+type NullSlice3Bool struct {
+	Slice3Bool [][][]sql.NullBool
+	Valid      bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice3Bool) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][][]bool", func(v interface{}) ([][]sql.NullBool, error) {
+		var ss NullSlice2Bool
+		err := ss.Scan(v)
+		return ss.Slice2Bool, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice3Bool, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][][]*bool.
+func (s NullSlice3Bool) AsSlice() [][][]*bool {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice3Bool, func(v [][]sql.NullBool) [][]*bool {
+		return NullSlice2Bool{Slice2Bool: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice3Bool) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice3Bool, func(v [][]sql.NullBool) interface{} {
+		slice, _ := NullSlice2Bool{Slice2Bool: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullSliceString represents a slice of string that may be null.
+type NullSliceString struct {
+	SliceString []sql.NullString
+	Valid       bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSliceString) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[]string", types.ScanNullString)
+	if err != nil {
+		return err
+	}
+	s.SliceString, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as []*string.
+func (s NullSliceString) AsSlice() []*string {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.SliceString, func(v sql.NullString) *string {
+		if !v.Valid {
+			return nil
+		}
+		return &v.String
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSliceString) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.SliceString, func(v sql.NullString) interface{} {
+		if !v.Valid {
+			return nil
+		}
+		return v.String
+	}), nil
+}
+
+// NullSlice2String represents a two-dimensional slice of string that may be null.
+type NullSlice2String struct {
+	Slice2String [][]sql.NullString
+	Valid        bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice2String) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][]string", func(v interface{}) ([]sql.NullString, error) {
+		var ss NullSliceString
+		err := ss.Scan(v)
+		return ss.SliceString, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice2String, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][]*string.
+func (s NullSlice2String) AsSlice() [][]*string {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice2String, func(v []sql.NullString) []*string {
+		return NullSliceString{SliceString: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice2String) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice2String, func(v []sql.NullString) interface{} {
+		slice, _ := NullSliceString{SliceString: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullSlice3String implements a three-dimensional slice of string that may be null.
+type NullSlice3String struct {
+	Slice3String [][][]sql.NullString
+	Valid        bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice3String) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][][]string", func(v interface{}) ([][]sql.NullString, error) {
+		var ss NullSlice2String
+		err := ss.Scan(v)
+		return ss.Slice2String, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice3String, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][][]*string.
+func (s NullSlice3String) AsSlice() [][][]*string {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice3String, func(v [][]sql.NullString) [][]*string {
+		return NullSlice2String{Slice2String: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice3String) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice3String, func(v [][]sql.NullString) interface{} {
+		slice, _ := NullSlice2String{Slice2String: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullSliceInt64 represents a slice of int64 that may be null.
+type NullSliceInt64 struct {
+	SliceInt64 []sql.NullInt64
+	Valid      bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSliceInt64) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[]int64", types.ScanNullInt64)
+	if err != nil {
+		return err
+	}
+	s.SliceInt64, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as []*int64.
+func (s NullSliceInt64) AsSlice() []*int64 {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.SliceInt64, func(v sql.NullInt64) *int64 {
+		if !v.Valid {
+			return nil
+		}
+		return &v.Int64
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSliceInt64) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.SliceInt64, func(v sql.NullInt64) interface{} {
+		if !v.Valid {
+			return nil
+		}
+		return v.Int64
+	}), nil
+}
+
+// NullSlice2Int64 represents a two-dimensional slice of int64 that may be null.
+type NullSlice2Int64 struct {
+	Slice2Int64 [][]sql.NullInt64
+	Valid       bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice2Int64) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][]int64", func(v interface{}) ([]sql.NullInt64, error) {
+		var ss NullSliceInt64
+		err := ss.Scan(v)
+		return ss.SliceInt64, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice2Int64, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][]*int64.
+func (s NullSlice2Int64) AsSlice() [][]*int64 {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice2Int64, func(v []sql.NullInt64) []*int64 {
+		return NullSliceInt64{SliceInt64: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice2Int64) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice2Int64, func(v []sql.NullInt64) interface{} {
+		slice, _ := NullSliceInt64{SliceInt64: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullSlice3Int64 implements a three-dimensional slice of int64 that may be null.
+type NullSlice3Int64 struct {
+	Slice3Int64 [][][]sql.NullInt64
+	Valid       bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice3Int64) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][][]int64", func(v interface{}) ([][]sql.NullInt64, error) {
+		var ss NullSlice2Int64
+		err := ss.Scan(v)
+		return ss.Slice2Int64, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice3Int64, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][][]*int64.
+func (s NullSlice3Int64) AsSlice() [][][]*int64 {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice3Int64, func(v [][]sql.NullInt64) [][]*int64 {
+		return NullSlice2Int64{Slice2Int64: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice3Int64) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice3Int64, func(v [][]sql.NullInt64) interface{} {
+		slice, _ := NullSlice2Int64{Slice2Int64: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullSliceFloat64 represents a slice of float64 that may be null.
+type NullSliceFloat64 struct {
+	SliceFloat64 []sql.NullFloat64
+	Valid        bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSliceFloat64) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[]float64", types.ScanNullFloat64)
+	if err != nil {
+		return err
+	}
+	s.SliceFloat64, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as []*float64.
+func (s NullSliceFloat64) AsSlice() []*float64 {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.SliceFloat64, func(v sql.NullFloat64) *float64 {
+		if !v.Valid {
+			return nil
+		}
+		return &v.Float64
+	})
+}
+
+// NullSlice2Float64 represents a two-dimensional slice of float64 that may be null.
+type NullSlice2Float64 struct {
+	Slice2Float64 [][]sql.NullFloat64
+	Valid         bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice2Float64) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][]float64", func(v interface{}) ([]sql.NullFloat64, error) {
+		var ss NullSliceFloat64
+		err := ss.Scan(v)
+		return ss.SliceFloat64, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice2Float64, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][]*float64.
+func (s NullSlice2Float64) AsSlice() [][]*float64 {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice2Float64, func(v []sql.NullFloat64) []*float64 {
+		return NullSliceFloat64{SliceFloat64: v, Valid: true}.AsSlice()
+	})
+}
+
+// NullSlice3Float64 represents a three-dimensional slice of float64 that may be null.
+type NullSlice3Float64 struct {
+	Slice3Float64 [][][]sql.NullFloat64
+	Valid         bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice3Float64) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][][]float64", func(v interface{}) ([][]sql.NullFloat64, error) {
+		var ss NullSlice2Float64
+		err := ss.Scan(v)
+		return ss.Slice2Float64, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice3Float64, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][][]*float64.
+func (s NullSlice3Float64) AsSlice() [][][]*float64 {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice3Float64, func(v [][]sql.NullFloat64) [][]*float64 {
+		return NullSlice2Float64{Slice2Float64: v, Valid: true}.AsSlice()
+	})
+}
+
+// scanNullTime converts a date, time or timestamp value to a NullTime.
+func scanNullTime(v interface{}) (NullTime, error) {
+	t, err := types.ScanNullTime(v)
+	return NullTime(t), err
+}
+
+// NullTime represents a time.Time value that can be null.
+// The NullTime supports Trino's Date, Time and Timestamp data types,
+// with or without time zone.
+type NullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullTime) Scan(value interface{}) error {
+	if value == nil {
+		s.Time, s.Valid = time.Time{}, false
+		return nil
+	}
+	switch t := value.(type) {
+	case time.Time:
+		s.Time, s.Valid = t, true
+	case NullTime:
+		*s = t
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullTime) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return s.Time, nil
+}
+
+// PreciseTime represents a time.Time with picosecond precision, for Trino's
+// time and timestamp types with a precision of up to 12 digits.
+type PreciseTime struct {
+	Time time.Time
+	// Picoseconds are added to the nanoseconds of Time, from 0 to 999.
+	Picoseconds int
+}
+
+// NullPreciseTime represents a PreciseTime value that can be null.
+// Columns are returned as PreciseTime values when using sub_nanosecond_time=preserve
+// in the DSN, otherwise the picoseconds are always 0.
+type NullPreciseTime struct {
+	Time        time.Time
+	Picoseconds int
+	Valid       bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullPreciseTime) Scan(value interface{}) error {
+	switch t := value.(type) {
+	case nil:
+		*s = NullPreciseTime{}
+	case PreciseTime:
+		s.Time, s.Picoseconds, s.Valid = t.Time, t.Picoseconds, true
+	case time.Time:
+		s.Time, s.Picoseconds, s.Valid = t, 0, true
+	default:
+		vv, err := scanNullPreciseTime(value)
+		if err != nil {
+			return err
+		}
+		*s = vv
+	}
+	return nil
+}
+
+func scanNullPreciseTime(v interface{}) (NullPreciseTime, error) {
+	if v == nil {
+		return NullPreciseTime{}, nil
+	}
+	vv, ok := v.(string)
+	if !ok {
+		return NullPreciseTime{}, fmt.Errorf("cannot convert %v (%T) to time string", v, v)
+	}
+	vv, picos, err := types.SplitPicoseconds(vv)
+	if err != nil {
+		return NullPreciseTime{}, err
+	}
+	t, err := scanNullTime(vv)
+	if err != nil {
+		return NullPreciseTime{}, err
+	}
+	return NullPreciseTime{Time: t.Time, Picoseconds: picos, Valid: true}, nil
+}
+
+// NullSliceTime represents a slice of time.Time that may be null.
+type NullSliceTime struct {
+	SliceTime []NullTime
+	Valid     bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSliceTime) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[]time.Time", scanNullTime)
+	if err != nil {
+		return err
+	}
+	s.SliceTime, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as []*time.Time.
+func (s NullSliceTime) AsSlice() []*time.Time {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.SliceTime, func(v NullTime) *time.Time {
+		if !v.Valid {
+			return nil
+		}
+		return &v.Time
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSliceTime) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.SliceTime, func(v NullTime) interface{} {
+		if !v.Valid {
+			return nil
+		}
+		return v.Time
+	}), nil
+}
+
+// NullSlice2Time represents a two-dimensional slice of time.Time that may be null.
+type NullSlice2Time struct {
+	Slice2Time [][]NullTime
+	Valid      bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice2Time) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][]time.Time", func(v interface{}) ([]NullTime, error) {
+		var ss NullSliceTime
+		err := ss.Scan(v)
+		return ss.SliceTime, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice2Time, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][]*time.Time.
+func (s NullSlice2Time) AsSlice() [][]*time.Time {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice2Time, func(v []NullTime) []*time.Time {
+		return NullSliceTime{SliceTime: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice2Time) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice2Time, func(v []NullTime) interface{} {
+		slice, _ := NullSliceTime{SliceTime: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullSlice3Time represents a three-dimensional slice of time.Time that may be null.
+type NullSlice3Time struct {
+	Slice3Time [][][]NullTime
+	Valid      bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice3Time) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][][]time.Time", func(v interface{}) ([][]NullTime, error) {
+		var ss NullSlice2Time
+		err := ss.Scan(v)
+		return ss.Slice2Time, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice3Time, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][][]*time.Time.
+func (s NullSlice3Time) AsSlice() [][][]*time.Time {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice3Time, func(v [][]NullTime) [][]*time.Time {
+		return NullSlice2Time{Slice2Time: v, Valid: true}.AsSlice()
+	})
+}
+
+// Value implements the driver.Valuer interface.
+func (s NullSlice3Time) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return types.MapSlice(s.Slice3Time, func(v [][]NullTime) interface{} {
+		slice, _ := NullSlice2Time{Slice2Time: v, Valid: true}.Value()
+		return slice
+	}), nil
+}
+
+// NullMap represents a map type that may be null.
+type NullMap struct {
+	Map   map[string]interface{}
+	Valid bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (m *NullMap) Scan(v interface{}) error {
+	if v == nil {
+		m.Map, m.Valid = map[string]interface{}{}, false
+		return nil
+	}
+	m.Map, m.Valid = v.(map[string]interface{})
+	return nil
+}
+
+// AsMap returns the map, or nil if it's null.
+func (m NullMap) AsMap() map[string]interface{} {
+	if !m.Valid {
+		return nil
+	}
+	return m.Map
+}
+
+// NullSliceMap represents a slice of NullMap that may be null.
+type NullSliceMap struct {
+	SliceMap []NullMap
+	Valid    bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSliceMap) Scan(value interface{}) error {
+	if value == nil {
+		s.SliceMap, s.Valid = []NullMap{}, false
+		return nil
+	}
+	vs, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("trino: cannot convert %v (%T) to []NullMap", value, value)
+	}
+	slice := make([]NullMap, len(vs))
+	for i := range vs {
+		if err := types.ValidateMap(vs[i]); err != nil {
+			return fmt.Errorf("cannot convert %v (%T) to []NullMap", value, value)
+		}
+		m := NullMap{}
+		// this scan can never fail
+		_ = m.Scan(vs[i])
+		slice[i] = m
+	}
+	s.SliceMap = slice
+	s.Valid = true
+	return nil
+}
+
+// AsSlice returns the elements as []map[string]interface{}.
+func (s NullSliceMap) AsSlice() []map[string]interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.SliceMap, NullMap.AsMap)
+}
+
+// NullSlice2Map represents a two-dimensional slice of NullMap that may be null.
+type NullSlice2Map struct {
+	Slice2Map [][]NullMap
+	Valid     bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice2Map) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][]NullMap", func(v interface{}) ([]NullMap, error) {
+		var ss NullSliceMap
+		err := ss.Scan(v)
+		return ss.SliceMap, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice2Map, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][]map[string]interface{}.
+func (s NullSlice2Map) AsSlice() [][]map[string]interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice2Map, func(v []NullMap) []map[string]interface{} {
+		return NullSliceMap{SliceMap: v, Valid: true}.AsSlice()
+	})
+}
+
+// NullSlice3Map represents a three-dimensional slice of NullMap that may be null.
+type NullSlice3Map struct {
+	Slice3Map [][][]NullMap
+	Valid     bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *NullSlice3Map) Scan(value interface{}) error {
+	slice, valid, err := types.ScanSlice(value, "[][][]NullMap", func(v interface{}) ([][]NullMap, error) {
+		var ss NullSlice2Map
+		err := ss.Scan(v)
+		return ss.Slice2Map, err
+	})
+	if err != nil {
+		return err
+	}
+	s.Slice3Map, s.Valid = slice, valid
+	return nil
+}
+
+// AsSlice returns the elements as [][][]map[string]interface{}.
+func (s NullSlice3Map) AsSlice() [][][]map[string]interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return types.MapSlice(s.Slice3Map, func(v [][]NullMap) [][]map[string]interface{} {
+		return NullSlice2Map{Slice2Map: v, Valid: true}.AsSlice()
+	})
+}
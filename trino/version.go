@@ -0,0 +1,65 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// modulePath is the path of the module of the driver.
+const modulePath = "github.com/trinodb/trino-go-client"
+
+var version = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				if dep.Replace.Version == "" {
+					return "(devel)"
+				}
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "(devel)"
+})
+
+// Version returns the version of the driver, like v0.300.0, read from the
+// build information of the binary, or (devel) when it's unknown, like in its
+// own tests or when it's replaced by a local copy.
+func Version() string {
+	return version()
+}
+
+// userAgent returns the User-Agent header of requests, with the versions of the
+// driver and Go, and the platform, like trino-go-client/v0.300.0 Go/1.22.1 linux/amd64,
+// followed by the suffix, if any.
+func userAgent(suffix string) string {
+	ua := "trino-go-client/" + Version() + " Go/" + strings.TrimPrefix(runtime.Version(), "go") + " " + runtime.GOOS + "/" + runtime.GOARCH
+	if suffix != "" {
+		ua += " " + suffix
+	}
+	return ua
+}
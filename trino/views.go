@@ -0,0 +1,211 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// View is a view of a catalog.
+type View struct {
+	Catalog string
+	Schema  string
+	Name    string
+	// Definition is the query of the view.
+	Definition string
+}
+
+// MaterializedView is a materialized view of a catalog, with its freshness.
+type MaterializedView struct {
+	Catalog string
+	Schema  string
+	Name    string
+	// StorageCatalog, StorageSchema and StorageTable are the table storing the
+	// data of the materialized view, if the connector reports it.
+	StorageCatalog string
+	StorageSchema  string
+	StorageTable   string
+	// Freshness is FRESH if the data of the materialized view is up to date with
+	// its tables, STALE if they changed since it was last refreshed, or UNKNOWN.
+	Freshness string
+	// LastFreshTime is the last time the materialized view was known to be
+	// fresh, or zero if it's unknown.
+	LastFreshTime time.Time
+	Comment       string
+	// Definition is the query of the materialized view.
+	Definition string
+}
+
+// ListViews returns the views of a schema of a catalog, or of all its schemas
+// if schema is empty, sorted by schema and name, from the information_schema
+// of the catalog.
+func ListViews(ctx context.Context, db *sql.DB, catalog, schema string) ([]View, error) {
+	return queryViews(ctx, db, catalog, schema, "")
+}
+
+// GetView returns a view, or sql.ErrNoRows if it doesn't exist.
+func GetView(ctx context.Context, db *sql.DB, catalog, schema, name string) (*View, error) {
+	views, err := queryViews(ctx, db, catalog, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(views) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &views[0], nil
+}
+
+func queryViews(ctx context.Context, db *sql.DB, catalog, schema, name string) ([]View, error) {
+	query := "SELECT table_schema, table_name, view_definition FROM " + quoteIdentifier(catalog) + ".information_schema.views"
+	conditions, args := metadataConditions("table_schema", schema, "table_name", name)
+	if schema == "" {
+		conditions = append(conditions, "table_schema <> 'information_schema'")
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY table_schema, table_name"
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var views []View
+	for rows.Next() {
+		view := View{Catalog: catalog}
+		var definition sql.NullString
+		if err := rows.Scan(&view.Schema, &view.Name, &definition); err != nil {
+			return nil, err
+		}
+		view.Definition = definition.String
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+// ListMaterializedViews returns the materialized views of a schema of a
+// catalog, or of all its schemas if schema is empty, or of all the catalogs if
+// catalog is also empty, sorted by catalog, schema and name, with their freshness, from the system.metadata.materialized_views
+// table. Connectors checking the freshness against the tables of the
+// materialized views, like Iceberg, make it slower than listing views.
+func ListMaterializedViews(ctx context.Context, db *sql.DB, catalog, schema string) ([]MaterializedView, error) {
+	return queryMaterializedViews(ctx, db, catalog, schema, "")
+}
+
+// GetMaterializedView returns a materialized view, with its freshness, or
+// sql.ErrNoRows if it doesn't exist.
+func GetMaterializedView(ctx context.Context, db *sql.DB, catalog, schema, name string) (*MaterializedView, error) {
+	views, err := queryMaterializedViews(ctx, db, catalog, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(views) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &views[0], nil
+}
+
+func queryMaterializedViews(ctx context.Context, db *sql.DB, catalog, schema, name string) ([]MaterializedView, error) {
+	query := "SELECT catalog_name, schema_name, name, storage_catalog, storage_schema, storage_table, " +
+		"freshness, last_fresh_time, comment, definition FROM system.metadata.materialized_views"
+	conditions, args := metadataConditions("catalog_name", catalog, "schema_name", schema, "name", name)
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY catalog_name, schema_name, name"
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var views []MaterializedView
+	for rows.Next() {
+		var (
+			view                                        MaterializedView
+			storageCatalog, storageSchema, storageTable sql.NullString
+			comment, definition                         sql.NullString
+			lastFreshTime                               sql.NullTime
+		)
+		if err := rows.Scan(&view.Catalog, &view.Schema, &view.Name, &storageCatalog, &storageSchema, &storageTable,
+			&view.Freshness, &lastFreshTime, &comment, &definition); err != nil {
+			return nil, err
+		}
+		view.StorageCatalog = storageCatalog.String
+		view.StorageSchema = storageSchema.String
+		view.StorageTable = storageTable.String
+		view.LastFreshTime = lastFreshTime.Time
+		view.Comment = comment.String
+		view.Definition = definition.String
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+// metadataConditions returns the conditions of a query of metadata tables,
+// and their arguments, for pairs of columns and values, skipping empty values.
+func metadataConditions(columnValues ...string) ([]string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for i := 0; i < len(columnValues); i += 2 {
+		if value := columnValues[i+1]; value != "" {
+			conditions = append(conditions, columnValues[i]+" = ?")
+			args = append(args, value)
+		}
+	}
+	return conditions, args
+}
+
+// RefreshStats are the statistics of the refresh of a materialized view.
+type RefreshStats struct {
+	// QueryID is the ID of the query refreshing the materialized view.
+	QueryID string
+	// Rows is the number of rows written to the storage table.
+	Rows int64
+	// ProcessedRows and ProcessedBytes are the rows and bytes read from the
+	// tables of the materialized view, and PhysicalWrittenBytes the bytes written.
+	ProcessedRows        int64
+	ProcessedBytes       int64
+	PhysicalWrittenBytes int64
+	// Elapsed is how long the refresh ran, as reported by the server.
+	Elapsed time.Duration
+}
+
+// RefreshMaterializedView runs REFRESH MATERIALIZED VIEW for a materialized
+// view, waits for it to finish, and returns its statistics.
+func RefreshMaterializedView(ctx context.Context, db *sql.DB, catalog, schema, name string) (*RefreshStats, error) {
+	var progress ExecProgress
+	ctx = WithExecProgress(ctx, func(p ExecProgress) {
+		progress = p
+	})
+	statement := "REFRESH MATERIALIZED VIEW " + quoteIdentifier(catalog) + "." + quoteIdentifier(schema) + "." + quoteIdentifier(name)
+	if _, err := db.ExecContext(ctx, statement); err != nil {
+		return nil, err
+	}
+	return &RefreshStats{
+		QueryID:              progress.QueryID,
+		Rows:                 progress.RowsAffected,
+		ProcessedRows:        progress.ProcessedRows,
+		ProcessedBytes:       progress.ProcessedBytes,
+		PhysicalWrittenBytes: progress.PhysicalWrittenBytes,
+		Elapsed:              progress.ElapsedTime,
+	}, nil
+}
+
+// quoteIdentifier quotes an identifier, like the name of a catalog, schema or table.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
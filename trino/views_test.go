@@ -0,0 +1,148 @@
+// Copyright (c) Facebook, Inc. and its affiliates. All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trino
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trinodb/trino-go-client/trino/trinomock"
+)
+
+func TestViews(t *testing.T) {
+	varchar := func(names ...string) []trinomock.Column {
+		columns := make([]trinomock.Column, len(names))
+		for i, name := range names {
+			columns[i] = trinomock.Column{Name: name, Type: "varchar"}
+		}
+		return columns
+	}
+	server := newMockServer(t)
+	server.HandleMatch(func(statement string) bool {
+		return strings.Contains(statement, `"hive".information_schema.views WHERE table_schema = ?`)
+	}, trinomock.Response{Columns: varchar("table_schema", "table_name", "view_definition")})
+	server.HandleMatch(func(statement string) bool {
+		return strings.Contains(statement, "information_schema.views")
+	}, trinomock.Response{
+		Columns: varchar("table_schema", "table_name", "view_definition"),
+		Rows: [][]interface{}{
+			{"web", "daily_visits", "SELECT day, count(*) visits FROM visits GROUP BY day"},
+			{"web", "hidden", nil},
+		},
+	})
+	server.HandleMatch(func(statement string) bool {
+		return strings.Contains(statement, "system.metadata.materialized_views")
+	}, trinomock.Response{
+		Columns: append(varchar("catalog_name", "schema_name", "name", "storage_catalog", "storage_schema", "storage_table", "freshness"),
+			trinomock.Column{Name: "last_fresh_time", Type: "timestamp(3) with time zone"},
+			trinomock.Column{Name: "comment", Type: "varchar"},
+			trinomock.Column{Name: "definition", Type: "varchar"},
+		),
+		Rows: [][]interface{}{
+			{"iceberg", "web", "daily_visits_mv", "iceberg", "web", "st_daily_visits_mv", "STALE", "2024-01-02 03:04:05.000 UTC", "daily", "SELECT 1"},
+			{"iceberg", "web", "new_mv", nil, nil, nil, "UNKNOWN", nil, nil, "SELECT 2"},
+		},
+	})
+
+	db := openTestDB(t, server.DSN()+"?explicitPrepare=false")
+	ctx := context.Background()
+
+	views, err := ListViews(ctx, db, "hive", "")
+	require.NoError(t, err)
+	assert.Equal(t, []View{
+		{Catalog: "hive", Schema: "web", Name: "daily_visits", Definition: "SELECT day, count(*) visits FROM visits GROUP BY day"},
+		{Catalog: "hive", Schema: "web", Name: "hidden"},
+	}, views)
+	view, err := GetView(ctx, db, `my"catalog`, "web", "daily_visits")
+	require.NoError(t, err)
+	assert.Equal(t, "daily_visits", view.Name)
+	_, err = GetView(ctx, db, "hive", "web", "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	mvs, err := ListMaterializedViews(ctx, db, "iceberg", "web")
+	require.NoError(t, err)
+	assert.Equal(t, []MaterializedView{
+		{
+			Catalog:        "iceberg",
+			Schema:         "web",
+			Name:           "daily_visits_mv",
+			StorageCatalog: "iceberg",
+			StorageSchema:  "web",
+			StorageTable:   "st_daily_visits_mv",
+			Freshness:      "STALE",
+			LastFreshTime:  mvs[0].LastFreshTime,
+			Comment:        "daily",
+			Definition:     "SELECT 1",
+		},
+		{Catalog: "iceberg", Schema: "web", Name: "new_mv", Freshness: "UNKNOWN", Definition: "SELECT 2"},
+	}, mvs)
+	assert.True(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Equal(mvs[0].LastFreshTime))
+	_, err = ListMaterializedViews(ctx, db, "", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"SELECT table_schema, table_name, view_definition FROM \"hive\".information_schema.views WHERE table_schema <> 'information_schema' ORDER BY table_schema, table_name",
+		"EXECUTE IMMEDIATE 'SELECT table_schema, table_name, view_definition FROM \"my\"\"catalog\".information_schema.views WHERE table_schema = ? AND table_name = ? ORDER BY table_schema, table_name' USING 'web', 'daily_visits'",
+		"EXECUTE IMMEDIATE 'SELECT table_schema, table_name, view_definition FROM \"hive\".information_schema.views WHERE table_schema = ? AND table_name = ? ORDER BY table_schema, table_name' USING 'web', 'missing'",
+		"EXECUTE IMMEDIATE 'SELECT catalog_name, schema_name, name, storage_catalog, storage_schema, storage_table, freshness, last_fresh_time, comment, definition FROM system.metadata.materialized_views WHERE catalog_name = ? AND schema_name = ? ORDER BY catalog_name, schema_name, name' USING 'iceberg', 'web'",
+		"SELECT catalog_name, schema_name, name, storage_catalog, storage_schema, storage_table, freshness, last_fresh_time, comment, definition FROM system.metadata.materialized_views ORDER BY catalog_name, schema_name, name",
+	}, submittedBodies(server))
+}
+
+func TestRefreshMaterializedView(t *testing.T) {
+	var statement string
+	handler := fakeQueryHandler(queryResponse{}, queryResponse{
+		UpdateType:  "REFRESH MATERIALIZED VIEW",
+		UpdateCount: 42,
+		Stats: stmtStats{
+			State:                "FINISHED",
+			ElapsedTimeMillis:    1500,
+			ProcessedRows:        100,
+			ProcessedBytes:       2048,
+			PhysicalWrittenBytes: 512,
+		},
+	})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			statement = string(b)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	db := openTestDB(t, ts.URL)
+
+	stats, err := RefreshMaterializedView(context.Background(), db, "iceberg", "web", "daily_visits_mv")
+	require.NoError(t, err)
+	assert.Equal(t, &RefreshStats{
+		QueryID:              "fake-query",
+		Rows:                 42,
+		ProcessedRows:        100,
+		ProcessedBytes:       2048,
+		PhysicalWrittenBytes: 512,
+		Elapsed:              1500 * time.Millisecond,
+	}, stats)
+
+	assert.Equal(t, `REFRESH MATERIALIZED VIEW "iceberg"."web"."daily_visits_mv"`, statement)
+}